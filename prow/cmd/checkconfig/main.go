@@ -0,0 +1,56 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// checkconfig loads the prow config file and prints every problem with it,
+// one per line. It exits nonzero if there are any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"k8s.io/test-infra/prow/config"
+	_ "k8s.io/test-infra/prow/plugins/all"
+)
+
+var configPath = flag.String("config-path", "config.yaml", "Path to the prow config file.")
+
+func main() {
+	flag.Parse()
+
+	b, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	c, err := config.Parse(b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	errs := append(c.Validate(), c.UnknownPlugins()...)
+	if len(errs) == 0 {
+		return
+	}
+	sort.Sort(config.Errors(errs))
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(1)
+}
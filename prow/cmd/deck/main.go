@@ -18,9 +18,13 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"regexp"
+	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/Sirupsen/logrus"
@@ -32,10 +36,13 @@ const (
 	namespace = "default"
 )
 
+var hookURL = flag.String("hook-url", "http://hook:8888", "Hook's address, for the help for its plugins.")
+
 // Matches letters, numbers, hyphens, and underscores.
 var podReg = regexp.MustCompile(`^[\w-]+$`)
 
 func main() {
+	flag.Parse()
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 
 	kc, err := kube.NewClientInCluster(namespace)
@@ -51,6 +58,7 @@ func main() {
 	http.Handle("/", gziphandler.GzipHandler(http.FileServer(http.Dir("/static"))))
 	http.Handle("/data.js", gziphandler.GzipHandler(handleData(ja)))
 	http.Handle("/log", gziphandler.GzipHandler(handleLog(kc)))
+	http.Handle("/plugin-help", gziphandler.GzipHandler(handlePluginHelp(&http.Client{Timeout: 30 * time.Second}, *hookURL)))
 
 	logrus.WithError(http.ListenAndServe(":http", nil)).Fatal("ListenAndServe returned.")
 }
@@ -98,3 +106,27 @@ func handleLog(kc logClient) http.HandlerFunc {
 		}
 	}
 }
+
+// handlePluginHelp passes requests for the help for plugins on to hook,
+// which knows which plugins each repo has.
+func handlePluginHelp(c *http.Client, hook string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		u := hook + "/plugin-help"
+		if repo := r.URL.Query().Get("repo"); repo != "" {
+			u += "?" + url.Values{"repo": {repo}}.Encode()
+		}
+		resp, err := c.Get(u)
+		if err != nil {
+			http.Error(w, "Error getting plugin help from hook", http.StatusBadGateway)
+			logrus.WithError(err).Warning("Error getting plugin help.")
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			logrus.WithError(err).Warning("Error writing plugin help.")
+		}
+	}
+}
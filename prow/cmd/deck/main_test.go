@@ -98,3 +98,64 @@ func TestHandleLog(t *testing.T) {
 		}
 	}
 }
+
+func TestHandlePluginHelp(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plugin-help" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		switch repo := r.URL.Query().Get("repo"); repo {
+		case "org/repo":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"repos":["org/repo"]}`))
+		default:
+			http.Error(w, "bad repo", http.StatusBadRequest)
+		}
+	}))
+	defer hook.Close()
+
+	var testcases = []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{
+			name: "good repo",
+			path: "?repo=org/repo",
+			code: http.StatusOK,
+			body: `{"repos":["org/repo"]}`,
+		},
+		{
+			name: "bad repo",
+			path: "?repo=org",
+			code: http.StatusBadRequest,
+		},
+	}
+	handler := handlePluginHelp(&http.Client{}, hook.URL)
+	for _, tc := range testcases {
+		req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.code {
+			t.Errorf("For case %s, expected code %d, got %d.", tc.name, tc.code, rr.Code)
+		} else if tc.body != "" && rr.Body.String() != tc.body {
+			t.Errorf("For case %s, expected body %s, got %s.", tc.name, tc.body, rr.Body.String())
+		}
+	}
+
+	// Hook being down is a bad gateway.
+	hook.Close()
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "", nil)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected code %d with hook down, got %d.", http.StatusBadGateway, rr.Code)
+	}
+}
@@ -0,0 +1,116 @@
+function getParameterByName(name) {  // http://stackoverflow.com/a/5158301/3694
+    var match = RegExp('[?&]' + name + '=([^&/]*)').exec(window.location.search);
+    return match && decodeURIComponent(match[1].replace(/\+/g, ' '));
+}
+
+window.onload = function() {
+    var repo = getParameterByName("repo") || "";
+    var req = new XMLHttpRequest();
+    req.onload = function() {
+        if (req.status !== 200) {
+            showError("Could not load plugin help: " + req.responseText);
+            return;
+        }
+        var help = JSON.parse(req.responseText);
+        drawRepos(help.repos || [], repo);
+        drawPlugins(help.plugins || {});
+    };
+    req.onerror = function() {
+        showError("Could not load plugin help.");
+    };
+    req.open("GET", "plugin-help?repo=" + encodeURIComponent(repo));
+    req.send();
+};
+
+function selectRepo(repo) {
+    window.location.search = repo ? "?repo=" + encodeURIComponent(repo) : "";
+}
+
+function showError(msg) {
+    var d = document.createElement("div");
+    d.appendChild(document.createTextNode(msg));
+    document.getElementById("plugins").appendChild(d);
+}
+
+function drawRepos(repos, selected) {
+    var select = document.getElementById("repo");
+    for (var i = 0; i < repos.length; i++) {
+        var o = document.createElement("option");
+        o.value = repos[i];
+        o.text = repos[i];
+        o.selected = repos[i] === selected;
+        select.appendChild(o);
+    }
+}
+
+function drawPlugins(plugins) {
+    var article = document.getElementById("plugins");
+    var names = Object.keys(plugins).sort();
+    for (var i = 0; i < names.length; i++) {
+        var help = plugins[names[i]];
+        var d = document.createElement("div");
+        var h = document.createElement("h2");
+        h.appendChild(document.createTextNode(names[i]));
+        d.appendChild(h);
+        var p = document.createElement("p");
+        p.appendChild(document.createTextNode(help.description || "No help is available for this plugin."));
+        d.appendChild(p);
+        if (help.commands) {
+            d.appendChild(commandTable(help.commands));
+        }
+        if (help.config) {
+            d.appendChild(configList(help.config));
+        }
+        article.appendChild(d);
+    }
+}
+
+function commandTable(commands) {
+    var t = document.createElement("table");
+    var head = document.createElement("tr");
+    var cols = ["Command", "Description", "Who can use it", "Example", "Regexp"];
+    for (var i = 0; i < cols.length; i++) {
+        var th = document.createElement("th");
+        th.appendChild(document.createTextNode(cols[i]));
+        head.appendChild(th);
+    }
+    t.appendChild(head);
+    for (var i = 0; i < commands.length; i++) {
+        var c = commands[i];
+        var r = document.createElement("tr");
+        r.appendChild(textCell(c.usage, true));
+        r.appendChild(textCell(c.description, false));
+        r.appendChild(textCell(c.who_can_use, false));
+        r.appendChild(textCell(c.example, true));
+        r.appendChild(textCell(c.regex, true));
+        t.appendChild(r);
+    }
+    return t;
+}
+
+function configList(config) {
+    var ul = document.createElement("ul");
+    var keys = Object.keys(config).sort();
+    for (var i = 0; i < keys.length; i++) {
+        var li = document.createElement("li");
+        var code = document.createElement("code");
+        code.appendChild(document.createTextNode(keys[i]));
+        li.appendChild(code);
+        li.appendChild(document.createTextNode(": " + (config[keys[i]] || "(not set)")));
+        ul.appendChild(li);
+    }
+    return ul;
+}
+
+function textCell(text, isCode) {
+    var c = document.createElement("td");
+    var t = document.createTextNode(text || "");
+    if (isCode) {
+        var code = document.createElement("code");
+        code.appendChild(t);
+        c.appendChild(code);
+    } else {
+        c.appendChild(t);
+    }
+    return c;
+}
@@ -199,12 +199,22 @@ function redraw() {
         } else {
             r.appendChild(createTextCell(""));
         }
-        r.appendChild(createLinkCell(build.repo, "https://github.com/" + build.repo));
+        if (build.type == "periodic") {
+            r.appendChild(createTextCell(""));
+        } else {
+            r.appendChild(createLinkCell(build.repo, "https://github.com/" + build.repo));
+        }
         if (build.type == "batch") {
             var batchText = createTextCell("Batch");
             batchText.setAttribute("title", build.refs.replace(/,/g, ' '));
             r.appendChild(batchText);
             r.appendChild(createTextCell(''));
+        } else if (build.type == "postsubmit") {
+            r.appendChild(createLinkCell(build.base_ref, "https://github.com/" + build.repo + "/commit/" + build.base_sha));
+            r.appendChild(createTextCell(''));
+        } else if (build.type == "periodic") {
+            r.appendChild(createTextCell("Periodic"));
+            r.appendChild(createTextCell(''));
         } else {
             r.appendChild(createLinkCell(build.number, "https://github.com/" + build.repo + "/pull/" + build.number));
             r.appendChild(createLinkCell(build.author, "https://github.com/" + build.author));
@@ -17,40 +17,316 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
 	"github.com/Sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins"
 )
 
-// EventAgent pulls events off of the queues and dispatches to the relevant
-// plugins.
+// EventAgent pulls events off of the queue, dispatches them to the relevant
+// plugins, and then removes them from the journal. Events for the same PR or
+// issue are handled one at a time in the order that they arrived, so that a
+// "synchronize" can't race a "closed". Events for different ones are handled
+// in parallel by a fixed number of workers.
 type EventAgent struct {
 	Plugins *plugins.PluginAgent
+	Journal *Journal
+
+	// How many events to handle at once. Defaults to one.
+	Workers int
+	// How often to look in the journal for events that didn't fit in their
+	// worker's queue. Defaults to ten seconds.
+	ReplayInterval time.Duration
+
+	events chan Event
 
-	PullRequestEvents  <-chan github.PullRequestEvent
-	IssueCommentEvents <-chan github.IssueCommentEvent
-	StatusEvents       <-chan github.StatusEvent
+	mut sync.Mutex
+	// The GUIDs of the events that are queued or being handled.
+	inFlight map[string]bool
+	// Whether Dispatch dropped an event since the last replay.
+	dropping bool
+	stopped  bool
+
+	wg sync.WaitGroup
 }
 
-// Start starts listening for events. It does not block.
+// Each worker queues this many events. Once its queue is full, its events
+// wait in the journal so that a slow PR can't hold up the others.
+const workerQueueSize = 100
+
+// Dispatch takes up to this many events before the dispatching goroutine
+// catches up.
+const eventQueueSize = 1000
+
+const defaultReplayInterval = 10 * time.Second
+
+// Start starts taking events and replays any that were left in the journal
+// when we last stopped. It does not block. Call Stop to stop taking events,
+// and then Wait to let the agent finish the events it has.
 func (ea *EventAgent) Start() {
+	n := ea.Workers
+	if n < 1 {
+		n = 1
+	}
+	interval := ea.ReplayInterval
+	if interval <= 0 {
+		interval = defaultReplayInterval
+	}
+	ea.events = make(chan Event, eventQueueSize)
+	ea.inFlight = map[string]bool{}
+	d := &dispatcher{
+		ea:     ea,
+		queues: make([]chan Event, n),
+		full:   make([]bool, n),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan Event, workerQueueSize)
+		ea.wg.Add(1)
+		go func(q <-chan Event) {
+			defer ea.wg.Done()
+			for e := range q {
+				ea.handleEvent(e)
+				ea.mut.Lock()
+				delete(ea.inFlight, e.GUID)
+				ea.mut.Unlock()
+				workerQueueDepth.Add(-1)
+			}
+		}(d.queues[i])
+	}
 	go func() {
-		for pr := range ea.PullRequestEvents {
-			go ea.handlePullRequestEvent(pr)
+		d.replay()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case e, ok := <-ea.events:
+				if !ok {
+					for _, q := range d.queues {
+						close(q)
+					}
+					return
+				}
+				d.dispatch(e)
+			case <-t.C:
+				if d.overflowed() {
+					d.replay()
+				}
+			}
 		}
 	}()
-	go func() {
-		for ic := range ea.IssueCommentEvents {
-			go ea.handleIssueCommentEvent(ic)
+}
+
+// Dispatch hands an event that is in the journal to the agent without
+// blocking. If the agent is too busy to take it, then it and the events after
+// it wait in the journal for the next replay, so that they stay in order.
+func (ea *EventAgent) Dispatch(e Event) {
+	ea.mut.Lock()
+	defer ea.mut.Unlock()
+	if ea.stopped || ea.dropping {
+		return
+	}
+	select {
+	case ea.events <- e:
+	default:
+		logrus.WithField("guid", e.GUID).Warning("Event queue is full, leaving the event in the journal.")
+		ea.dropping = true
+	}
+}
+
+// Stop stops taking events. Events that Dispatch took before then are still
+// handled.
+func (ea *EventAgent) Stop() {
+	ea.mut.Lock()
+	defer ea.mut.Unlock()
+	if !ea.stopped {
+		ea.stopped = true
+		close(ea.events)
+	}
+}
+
+// dispatcher hands events to workers. Only the agent's dispatching goroutine
+// uses it.
+type dispatcher struct {
+	ea     *EventAgent
+	queues []chan Event
+	// Whether each worker's queue filled up since the last replay.
+	full []bool
+}
+
+// dispatch queues the event for its worker without blocking. Once a worker's
+// queue fills up, it drops the worker's events until the next replay, which
+// queues them from the journal in the order that they arrived. It returns
+// true if it queued the event.
+func (d *dispatcher) dispatch(e Event) bool {
+	w := worker(eventKey(e), len(d.queues))
+	if d.full[w] {
+		return false
+	}
+	d.ea.mut.Lock()
+	defer d.ea.mut.Unlock()
+	// A replay may have queued it already, or even handled it.
+	if d.ea.inFlight[e.GUID] || !d.ea.Journal.Has(e.GUID) {
+		return false
+	}
+	select {
+	case d.queues[w] <- e:
+		d.ea.inFlight[e.GUID] = true
+		workerQueueDepth.Add(1)
+		return true
+	default:
+		logrus.WithField("guid", e.GUID).Warning("Worker queue is full, leaving the event in the journal.")
+		d.full[w] = true
+		return false
+	}
+}
+
+func (d *dispatcher) overflowed() bool {
+	for _, f := range d.full {
+		if f {
+			return true
 		}
-	}()
-	go func() {
-		for se := range ea.StatusEvents {
-			go ea.handleStatusEvents(se)
+	}
+	d.ea.mut.Lock()
+	defer d.ea.mut.Unlock()
+	return d.ea.dropping
+}
+
+// replay queues every event in the journal that isn't already queued, oldest
+// first.
+func (d *dispatcher) replay() {
+	// Anything that Dispatch drops from now on is after what we read.
+	d.ea.mut.Lock()
+	d.ea.dropping = false
+	d.ea.mut.Unlock()
+	pending, err := d.ea.Journal.Pending()
+	if err != nil {
+		logrus.WithError(err).Error("Error reading journal.")
+		return
+	}
+	for i := range d.full {
+		d.full[i] = false
+	}
+	queued := 0
+	for _, e := range pending {
+		if d.dispatch(e) {
+			queued++
 		}
-	}()
+	}
+	if queued > 0 {
+		logrus.Infof("Replayed %d events from the journal.", queued)
+	}
+}
 
+// Wait blocks until Events is closed and every event has been handled.
+func (ea *EventAgent) Wait() {
+	ea.wg.Wait()
+}
+
+// eventKey returns a key that is the same for every event that must be
+// handled in order, such as "org/repo#123" for a PR or issue.
+func eventKey(e Event) string {
+	var p struct {
+		Number int `json:"number"`
+		Issue  struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Repo struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+	if err := json.Unmarshal(e.Payload, &p); err != nil || p.Repo.FullName == "" {
+		// We don't know what it is about, so it doesn't need ordering.
+		return e.GUID
+	}
+	repo := p.Repo.FullName
+	switch {
+	case p.PullRequest.Number != 0:
+		return fmt.Sprintf("%s#%d", repo, p.PullRequest.Number)
+	case p.Issue.Number != 0:
+		return fmt.Sprintf("%s#%d", repo, p.Issue.Number)
+	case p.Number != 0:
+		return fmt.Sprintf("%s#%d", repo, p.Number)
+	case p.Ref != "":
+		return repo + "@" + p.Ref
+	case p.SHA != "":
+		return repo + "@" + p.SHA
+	}
+	return repo
+}
+
+// worker picks one of n workers for the key. It always picks the same one.
+func worker(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// handleEvent acknowledges the event even if a plugin fails since plugins are
+// not necessarily idempotent.
+func (ea *EventAgent) handleEvent(e Event) {
+	l := logrus.WithFields(logrus.Fields{
+		"event-type": e.Type,
+		"guid":       e.GUID,
+	})
+	if err := ea.demuxEvent(e.Type, e.Payload); err != nil {
+		l.WithError(err).Error("Error parsing event.")
+	}
+	if err := ea.Journal.Ack(e.GUID); err != nil {
+		l.WithError(err).Error("Error removing event from journal.")
+	}
+}
+
+func (ea *EventAgent) demuxEvent(eventType string, payload []byte) error {
+	switch eventType {
+	case "pull_request":
+		var pr github.PullRequestEvent
+		if err := json.Unmarshal(payload, &pr); err != nil {
+			return err
+		}
+		ea.handlePullRequestEvent(pr)
+	case "issue_comment":
+		var ic github.IssueCommentEvent
+		if err := json.Unmarshal(payload, &ic); err != nil {
+			return err
+		}
+		ea.handleIssueCommentEvent(ic)
+	case "status":
+		var se github.StatusEvent
+		if err := json.Unmarshal(payload, &se); err != nil {
+			return err
+		}
+		ea.handleStatusEvents(se)
+	case "pull_request_review":
+		var re github.ReviewEvent
+		if err := json.Unmarshal(payload, &re); err != nil {
+			return err
+		}
+		ea.handleReviewEvent(re)
+	case "pull_request_review_comment":
+		var rce github.ReviewCommentEvent
+		if err := json.Unmarshal(payload, &rce); err != nil {
+			return err
+		}
+		ea.handleReviewCommentEvent(rce)
+	case "push":
+		var pe github.PushEvent
+		if err := json.Unmarshal(payload, &pe); err != nil {
+			return err
+		}
+		ea.handlePushEvent(pe)
+	}
+	return nil
 }
 
 func (ea *EventAgent) handlePullRequestEvent(pr github.PullRequestEvent) {
@@ -65,7 +341,11 @@ func (ea *EventAgent) handlePullRequestEvent(pr github.PullRequestEvent) {
 	for p, h := range ea.Plugins.PullRequestHandlers(pr.PullRequest.Base.Repo.Owner.Login, pr.PullRequest.Base.Repo.Name) {
 		pc := ea.Plugins.PluginClient
 		pc.Logger = l.WithField("plugin", p)
-		if err := h(pc, pr); err != nil {
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, pr)
+		observeHandler("pull_request", p, start, err)
+		if err != nil {
 			pc.Logger.WithError(err).Error("Error handling PullRequestEvent.")
 		}
 	}
@@ -83,7 +363,11 @@ func (ea *EventAgent) handleIssueCommentEvent(ic github.IssueCommentEvent) {
 	for p, h := range ea.Plugins.IssueCommentHandlers(ic.Repo.Owner.Login, ic.Repo.Name) {
 		pc := ea.Plugins.PluginClient
 		pc.Logger = l.WithField("plugin", p)
-		if err := h(pc, ic); err != nil {
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, ic)
+		observeHandler("issue_comment", p, start, err)
+		if err != nil {
 			pc.Logger.WithError(err).Error("Error handling IssueCommentEvent.")
 		}
 	}
@@ -102,8 +386,77 @@ func (ea *EventAgent) handleStatusEvents(se github.StatusEvent) {
 	for p, h := range ea.Plugins.StatusEventHandlers(se.Repo.Owner.Login, se.Repo.Name) {
 		pc := ea.Plugins.PluginClient
 		pc.Logger = l.WithField("plugin", p)
-		if err := h(pc, se); err != nil {
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, se)
+		observeHandler("status", p, start, err)
+		if err != nil {
 			pc.Logger.WithError(err).Error("Error handling StatusEvent.")
 		}
 	}
 }
+
+func (ea *EventAgent) handleReviewEvent(re github.ReviewEvent) {
+	l := logrus.WithFields(logrus.Fields{
+		"org":      re.Repo.Owner.Login,
+		"repo":     re.Repo.Name,
+		"pr":       re.PullRequest.Number,
+		"reviewer": re.Review.User.Login,
+		"url":      re.Review.HTMLURL,
+	})
+	l.Infof("Review %s.", re.Action)
+	for p, h := range ea.Plugins.ReviewEventHandlers(re.Repo.Owner.Login, re.Repo.Name) {
+		pc := ea.Plugins.PluginClient
+		pc.Logger = l.WithField("plugin", p)
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, re)
+		observeHandler("pull_request_review", p, start, err)
+		if err != nil {
+			pc.Logger.WithError(err).Error("Error handling ReviewEvent.")
+		}
+	}
+}
+
+func (ea *EventAgent) handleReviewCommentEvent(rce github.ReviewCommentEvent) {
+	l := logrus.WithFields(logrus.Fields{
+		"org":    rce.Repo.Owner.Login,
+		"repo":   rce.Repo.Name,
+		"pr":     rce.PullRequest.Number,
+		"author": rce.Comment.User.Login,
+		"url":    rce.Comment.HTMLURL,
+	})
+	l.Infof("Review comment %s.", rce.Action)
+	for p, h := range ea.Plugins.ReviewCommentEventHandlers(rce.Repo.Owner.Login, rce.Repo.Name) {
+		pc := ea.Plugins.PluginClient
+		pc.Logger = l.WithField("plugin", p)
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, rce)
+		observeHandler("pull_request_review_comment", p, start, err)
+		if err != nil {
+			pc.Logger.WithError(err).Error("Error handling ReviewCommentEvent.")
+		}
+	}
+}
+
+func (ea *EventAgent) handlePushEvent(pe github.PushEvent) {
+	l := logrus.WithFields(logrus.Fields{
+		"org":   pe.Repo.Owner.Login,
+		"repo":  pe.Repo.Name,
+		"ref":   pe.Ref,
+		"after": pe.After,
+	})
+	l.Info("Push.")
+	for p, h := range ea.Plugins.PushEventHandlers(pe.Repo.Owner.Login, pe.Repo.Name) {
+		pc := ea.Plugins.PluginClient
+		pc.Logger = l.WithField("plugin", p)
+		pc.PluginConfig = ea.Plugins.Config()
+		start := time.Now()
+		err := h(pc, pe)
+		observeHandler("push", p, start, err)
+		if err != nil {
+			pc.Logger.WithError(err).Error("Error handling PushEvent.")
+		}
+	}
+}
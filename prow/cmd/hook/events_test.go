@@ -0,0 +1,287 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestEventKey(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{
+			name:     "pull request",
+			payload:  `{"number":3,"pull_request":{"number":3},"repository":{"full_name":"org/repo"}}`,
+			expected: "org/repo#3",
+		},
+		{
+			name:     "issue comment",
+			payload:  `{"issue":{"number":3},"repository":{"full_name":"org/repo"}}`,
+			expected: "org/repo#3",
+		},
+		{
+			name:     "push",
+			payload:  `{"ref":"refs/heads/master","repository":{"full_name":"org/repo"}}`,
+			expected: "org/repo@refs/heads/master",
+		},
+		{
+			name:     "status",
+			payload:  `{"sha":"abc","repository":{"full_name":"org/repo"}}`,
+			expected: "org/repo@abc",
+		},
+		{
+			name:     "no repo",
+			payload:  `{"zen":"Keep it logically awesome."}`,
+			expected: "guid",
+		},
+		{
+			name:     "bad payload",
+			payload:  `not json`,
+			expected: "guid",
+		},
+	}
+	for _, tc := range testcases {
+		if key := eventKey(Event{GUID: "guid", Payload: []byte(tc.payload)}); key != tc.expected {
+			t.Errorf("For case %s, expected key %s, got %s", tc.name, tc.expected, key)
+		}
+	}
+}
+
+// Events for the same PR must be handled one at a time and in order, even
+// with many workers.
+func TestEventAgentOrdering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mut sync.Mutex
+	handled := map[int][]int{}
+	running := map[int]bool{}
+	plugins.RegisterIssueCommentHandler("ordering-test", func(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+		n := ic.Issue.Number
+		mut.Lock()
+		if running[n] {
+			t.Errorf("Handling two events for #%d at once.", n)
+		}
+		running[n] = true
+		mut.Unlock()
+		time.Sleep(time.Millisecond)
+		i, _ := strconv.Atoi(ic.Comment.Body)
+		mut.Lock()
+		running[n] = false
+		handled[n] = append(handled[n], i)
+		mut.Unlock()
+		return nil
+	}, nil)
+	pa := &plugins.PluginAgent{
+		PluginClient: plugins.PluginClient{Logger: logrus.NewEntry(logrus.StandardLogger())},
+	}
+	if err := pa.Set(&plugins.Configuration{
+		Plugins: map[string][]string{"org/repo": {"ordering-test"}},
+		Settings: map[string]plugins.Settings{"org": {
+			TrustedOrgs:   []string{"org"},
+			BotLogins:     []string{"bot"},
+			CommandPrefix: "@bot",
+		}},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+
+	ea := &EventAgent{
+		Plugins: pa,
+		Journal: j,
+		Workers: 4,
+	}
+	ea.Start()
+	const prs, comments = 5, 20
+	for i := 0; i < comments; i++ {
+		for n := 1; n <= prs; n++ {
+			guid := fmt.Sprintf("%d-%d", n, i)
+			payload := fmt.Sprintf(`{"action":"created","issue":{"number":%d},"comment":{"body":"%d"},"repository":{"name":"repo","full_name":"org/repo","owner":{"login":"org"}}}`, n, i)
+			e := Event{GUID: guid, Type: "issue_comment", Payload: []byte(payload), Received: time.Now()}
+			if _, err := j.Append(e); err != nil {
+				t.Fatal(err)
+			}
+			ea.Dispatch(e)
+		}
+	}
+	ea.Stop()
+	ea.Wait()
+
+	for n := 1; n <= prs; n++ {
+		if len(handled[n]) != comments {
+			t.Errorf("Expected %d events for #%d, got %d.", comments, n, len(handled[n]))
+			continue
+		}
+		for i, c := range handled[n] {
+			if c != i {
+				t.Errorf("Events for #%d out of order: %v", n, handled[n])
+				break
+			}
+		}
+	}
+	if pending, err := j.Pending(); err != nil {
+		t.Fatalf("Error reading journal: %v", err)
+	} else if len(pending) != 0 {
+		t.Errorf("Expected every event to be acknowledged, got %d pending.", len(pending))
+	}
+	if d := metricValue(workerQueueDepth); d != 0 {
+		t.Errorf("Expected an empty queue, got depth %v.", d)
+	}
+	if n := metricValue(journalSize); n != 0 {
+		t.Errorf("Expected an empty journal, got %v events.", n)
+	}
+}
+
+// A PR whose handler stalls must not hold up events for other PRs, and its
+// own events must still be handled in order once it recovers.
+func TestEventAgentStalledKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Find two PRs that different workers handle.
+	const workers = 2
+	stalled, other := 1, 2
+	for worker(fmt.Sprintf("org/repo#%d", stalled), workers) == worker(fmt.Sprintf("org/repo#%d", other), workers) {
+		other++
+	}
+
+	release := make(chan struct{})
+	var mut sync.Mutex
+	handled := map[int][]int{}
+	plugins.RegisterIssueCommentHandler("stall-test", func(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+		n := ic.Issue.Number
+		if n == stalled {
+			<-release
+		}
+		i, _ := strconv.Atoi(ic.Comment.Body)
+		mut.Lock()
+		handled[n] = append(handled[n], i)
+		mut.Unlock()
+		return nil
+	}, nil)
+	pa := &plugins.PluginAgent{
+		PluginClient: plugins.PluginClient{Logger: logrus.NewEntry(logrus.StandardLogger())},
+	}
+	if err := pa.Set(&plugins.Configuration{
+		Plugins: map[string][]string{"org/repo": {"stall-test"}},
+		Settings: map[string]plugins.Settings{"org": {
+			TrustedOrgs:   []string{"org"},
+			BotLogins:     []string{"bot"},
+			CommandPrefix: "@bot",
+		}},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+
+	ea := &EventAgent{
+		Plugins:        pa,
+		Journal:        j,
+		Workers:        workers,
+		ReplayInterval: 10 * time.Millisecond,
+	}
+	ea.Start()
+	send := func(n, i int) {
+		guid := fmt.Sprintf("%d-%d", n, i)
+		payload := fmt.Sprintf(`{"action":"created","issue":{"number":%d},"comment":{"body":"%d"},"repository":{"name":"repo","full_name":"org/repo","owner":{"login":"org"}}}`, n, i)
+		e := Event{GUID: guid, Type: "issue_comment", Payload: []byte(payload), Received: time.Now()}
+		if _, err := j.Append(e); err != nil {
+			t.Fatal(err)
+		}
+		ea.Dispatch(e)
+	}
+	// More than fit in the stalled worker's queue.
+	const comments = 2 * workerQueueSize
+	for i := 0; i < comments; i++ {
+		send(stalled, i)
+	}
+	send(other, 0)
+
+	count := func(n int) int {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(handled[n])
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for count(other) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if count(other) != 1 {
+		t.Fatal("The event for the other PR wasn't handled while the first PR was stalled.")
+	}
+
+	close(release)
+	for count(stalled) < comments && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ea.Stop()
+	ea.Wait()
+	if len(handled[stalled]) != comments {
+		t.Fatalf("Expected %d events for the stalled PR, got %d.", comments, len(handled[stalled]))
+	}
+	for i, c := range handled[stalled] {
+		if c != i {
+			t.Errorf("Events for the stalled PR out of order: %v", handled[stalled])
+			break
+		}
+	}
+}
+
+// Dispatch must not block when the agent is busy, and the events after one
+// that it drops must wait for the replay so that they stay in order.
+func TestDispatchDoesNotBlock(t *testing.T) {
+	ea := &EventAgent{events: make(chan Event, 1)}
+	ea.Dispatch(Event{GUID: "a"})
+	ea.Dispatch(Event{GUID: "b"})
+	if e := <-ea.events; e.GUID != "a" {
+		t.Errorf("Expected event a, got %s.", e.GUID)
+	}
+	ea.Dispatch(Event{GUID: "c"})
+	if len(ea.events) != 0 {
+		t.Error("Expected the event after a dropped one to wait for the replay.")
+	}
+	if !ea.dropping {
+		t.Error("Expected a replay to be due.")
+	}
+}
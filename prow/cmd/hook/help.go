@@ -0,0 +1,49 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// handlePluginHelp serves the help for the plugins enabled for the "repo"
+// query, such as "kubernetes/test-infra", as JSON. Without a repo it serves
+// the help for every plugin.
+func handlePluginHelp(pa *plugins.PluginAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var org, repo string
+		if fullName := r.URL.Query().Get("repo"); fullName != "" {
+			parts := strings.Split(fullName, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				http.Error(w, "repo must be of the form org/repo", http.StatusBadRequest)
+				return
+			}
+			org, repo = parts[0], parts[1]
+		}
+		b, err := json.Marshal(pa.Help(org, repo))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestHandlePluginHelp(t *testing.T) {
+	pa := &plugins.PluginAgent{}
+	if err := pa.Set(&plugins.Configuration{
+		Plugins: map[string][]string{
+			"org":       {"lgtm"},
+			"org/repo":  {"hold"},
+			"other/foo": {"size"},
+		},
+		Settings: map[string]plugins.Settings{
+			"org":   {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}, CommandPrefix: "@bot"},
+			"other": {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}, CommandPrefix: "@bot"},
+		},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+	ts := httptest.NewServer(handlePluginHelp(pa))
+	defer ts.Close()
+
+	var testcases = []struct {
+		name    string
+		query   string
+		code    int
+		plugins []string
+	}{
+		{
+			name:    "repo with org and repo plugins",
+			query:   "?repo=org/repo",
+			code:    http.StatusOK,
+			plugins: []string{"hold", "lgtm"},
+		},
+		{
+			name:    "repo with only org plugins",
+			query:   "?repo=org/other",
+			code:    http.StatusOK,
+			plugins: []string{"lgtm"},
+		},
+		{
+			name:    "every plugin",
+			code:    http.StatusOK,
+			plugins: []string{"approve", "assign", "cla", "close", "hold", "label", "lgtm", "lifecycle", "needs-rebase", "notify", "release-note", "size", "trigger"},
+		},
+		{
+			name:  "bad repo",
+			query: "?repo=org",
+			code:  http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testcases {
+		resp, err := http.Get(ts.URL + tc.query)
+		if err != nil {
+			t.Fatalf("For case %s, error getting help: %v", tc.name, err)
+		}
+		var h plugins.Help
+		err = json.NewDecoder(resp.Body).Decode(&h)
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Errorf("For case %s, expected status %d, got %d.", tc.name, tc.code, resp.StatusCode)
+			continue
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+		if err != nil {
+			t.Errorf("For case %s, error decoding help: %v", tc.name, err)
+			continue
+		}
+		for _, p := range tc.plugins {
+			if h.Plugins[p].Description == "" {
+				t.Errorf("For case %s, expected help for %s.", tc.name, p)
+			}
+		}
+		// Other tests register plugins too, so only count those for a repo.
+		if tc.query != "" && len(h.Plugins) != len(tc.plugins) {
+			t.Errorf("For case %s, expected help for %v, got %v.", tc.name, tc.plugins, h.Plugins)
+		}
+		if expected := []string{"org", "org/repo", "other/foo"}; !reflect.DeepEqual(h.Repos, expected) {
+			t.Errorf("For case %s, expected repos %v, got %v.", tc.name, expected, h.Repos)
+		}
+	}
+}
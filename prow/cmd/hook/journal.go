@@ -0,0 +1,175 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a validated webhook along with its GitHub delivery ID.
+type Event struct {
+	GUID    string          `json:"guid"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// When we first received the event. Replays happen in this order.
+	Received time.Time `json:"received"`
+	// Orders events that were received at the same time.
+	Seq uint64 `json:"seq"`
+}
+
+// Journal keeps events on disk from the time we acknowledge the webhook until
+// every plugin has handled it, so that a restart does not lose them.
+// Each pending event is stored in its own file named after its delivery ID.
+type Journal struct {
+	dir string
+	seq uint64
+}
+
+const tmpPrefix = ".tmp-"
+
+// NewJournal creates the journal directory if it does not exist. It also
+// cleans up partial writes left behind by a crash.
+func NewJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pending := 0
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), tmpPrefix) {
+			if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+				return nil, err
+			}
+		} else if filepath.Ext(fi.Name()) == ".json" {
+			pending++
+		}
+	}
+	journalSize.Set(float64(pending))
+	return &Journal{dir: dir}, nil
+}
+
+func (j *Journal) path(guid string) string {
+	return filepath.Join(j.dir, guid+".json")
+}
+
+// Append durably records the event. It returns false if an event with the same
+// delivery ID is already pending, which happens when GitHub redelivers.
+func (j *Journal) Append(e Event) (bool, error) {
+	e.Seq = atomic.AddUint64(&j.seq, 1)
+	b, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+	f, err := ioutil.TempFile(j.dir, tmpPrefix)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	// Unlike rename, link fails if the event is already there.
+	if err := os.Link(f.Name(), j.path(e.GUID)); os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	journalSize.Inc()
+	return true, j.syncDir()
+}
+
+// Ack removes the event from the journal once it has been handled.
+func (j *Journal) Ack(guid string) error {
+	if err := os.Remove(j.path(guid)); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	journalSize.Dec()
+	return nil
+}
+
+// Has returns true if the event has not been acknowledged yet.
+func (j *Journal) Has(guid string) bool {
+	_, err := os.Stat(j.path(guid))
+	return err == nil
+}
+
+// Pending returns all events that were never acknowledged, oldest first.
+func (j *Journal) Pending() ([]Event, error) {
+	fis, err := ioutil.ReadDir(j.dir)
+	if err != nil {
+		return nil, err
+	}
+	var es []Event
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(j.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var e Event
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	sort.Sort(byReceived(es))
+	return es, nil
+}
+
+// syncDir makes sure that the new directory entry survives a crash.
+func (j *Journal) syncDir() error {
+	d, err := os.Open(j.dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+type byReceived []Event
+
+func (a byReceived) Len() int           { return len(a) }
+func (a byReceived) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byReceived) Less(i, j int) bool {
+	if a[i].Received.Equal(a[j].Received) {
+		return a[i].Seq < a[j].Seq
+	}
+	return a[i].Received.Before(a[j].Received)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(filepath.Join(dir, "j"))
+	if err != nil {
+		t.Fatalf("Error creating journal: %v", err)
+	}
+
+	now := time.Now()
+	events := []Event{
+		{GUID: "b", Type: "status", Payload: []byte(`{"sha":"abc"}`), Received: now.Add(time.Second)},
+		{GUID: "a", Type: "pull_request", Payload: []byte(`{"number":1}`), Received: now},
+		{GUID: "c", Type: "issue_comment", Payload: []byte(`{}`), Received: now.Add(2 * time.Second)},
+		// Received together, so they replay in the order they were appended.
+		{GUID: "e", Type: "pull_request", Payload: []byte(`{"number":2}`), Received: now.Add(3 * time.Second)},
+		{GUID: "d", Type: "pull_request", Payload: []byte(`{"number":2}`), Received: now.Add(3 * time.Second)},
+	}
+	for _, e := range events {
+		if fresh, err := j.Append(e); err != nil {
+			t.Fatalf("Error appending %s: %v", e.GUID, err)
+		} else if !fresh {
+			t.Errorf("Event %s should be fresh.", e.GUID)
+		}
+	}
+	if fresh, err := j.Append(events[0]); err != nil {
+		t.Fatalf("Error appending duplicate: %v", err)
+	} else if fresh {
+		t.Error("Duplicate event should not be fresh.")
+	}
+	if err := j.Ack("c"); err != nil {
+		t.Fatalf("Error acking: %v", err)
+	}
+	if err := j.Ack("c"); err != nil {
+		t.Errorf("Acking twice should not fail: %v", err)
+	}
+	if n := metricValue(journalSize); n != 4 {
+		t.Errorf("Expected 4 events in the journal, got %v.", n)
+	}
+	// Simulate a crash in the middle of an append.
+	if err := ioutil.WriteFile(filepath.Join(dir, "j", tmpPrefix+"123"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen it as though hook restarted.
+	j, err = NewJournal(filepath.Join(dir, "j"))
+	if err != nil {
+		t.Fatalf("Error reopening journal: %v", err)
+	}
+	if n := metricValue(journalSize); n != 4 {
+		t.Errorf("Expected 4 events in the reopened journal, got %v.", n)
+	}
+	pending, err := j.Pending()
+	if err != nil {
+		t.Fatalf("Error reading journal: %v", err)
+	}
+	if len(pending) != 4 {
+		t.Fatalf("Expected 4 pending events, got %d: %+v", len(pending), pending)
+	}
+	if pending[0].GUID != "a" || pending[1].GUID != "b" || pending[2].GUID != "e" || pending[3].GUID != "d" {
+		t.Errorf("Events out of order: %+v", pending)
+	}
+	if string(pending[1].Payload) != `{"sha":"abc"}` || pending[1].Type != "status" {
+		t.Errorf("Event not preserved: %+v", pending[1])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "j", tmpPrefix+"123")); !os.IsNotExist(err) {
+		t.Errorf("Expected partial write to be cleaned up, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"io/ioutil"
 	"net/http"
@@ -25,26 +26,31 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/jobs"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/plugins"
-
-	_ "k8s.io/test-infra/prow/plugins/cla"
-	_ "k8s.io/test-infra/prow/plugins/close"
-	_ "k8s.io/test-infra/prow/plugins/lgtm"
-	_ "k8s.io/test-infra/prow/plugins/releasenote"
-	_ "k8s.io/test-infra/prow/plugins/trigger"
+	_ "k8s.io/test-infra/prow/plugins/all"
 )
 
 var (
 	port = flag.Int("port", 8888, "Port to listen on.")
 
-	jobConfig    = flag.String("job-config", "/etc/jobs/jobs", "Path to job config file.")
-	pluginConfig = flag.String("plugin-config", "/etc/plugins/plugins", "Path to plugin config file.")
+	configPath = flag.String("config-path", "/etc/config/config", "Path to the prow config file.")
+
+	workers = flag.Int("workers", 20, "How many events to handle at once. Events for the same PR or issue are handled one at a time.")
+
+	periodicInterval = flag.Duration("periodic-interval", time.Hour, "Time between runs of the plugins' periodic handlers, such as to check for merge conflicts.")
+
+	journalDir = flag.String("journal-dir", "/var/lib/hook/journal", "Directory in which to keep webhooks until they are handled.")
+
+	githubCacheTTL = flag.Duration("github-cache-ttl", 5*time.Minute, "How long to trust cached org and team membership and collaborator permissions. Zero turns off caching, including conditional requests.")
 
 	local = flag.Bool("local", false, "Run locally for testing purposes only. Does not require secret files.")
 
@@ -54,6 +60,7 @@ var (
 
 func main() {
 	flag.Parse()
+	registerMetrics()
 
 	var webhookSecret []byte
 	var githubClient *github.Client
@@ -72,11 +79,6 @@ func main() {
 	} else {
 		logrus.SetFormatter(&logrus.JSONFormatter{})
 
-		// Ignore SIGTERM so that we don't drop hooks when the pod is removed.
-		// We'll get SIGTERM first and then SIGKILL after our graceful termination
-		// deadline.
-		signal.Ignore(syscall.SIGTERM)
-
 		webhookSecretRaw, err := ioutil.ReadFile(*webhookSecretFile)
 		if err != nil {
 			logrus.WithError(err).Fatal("Could not read webhook secret file.")
@@ -99,6 +101,7 @@ func main() {
 		} else {
 			githubClient = github.NewClient(oauthSecret)
 		}
+		githubClient.CacheTTL = *githubCacheTTL
 
 		kubeClient, err = kube.NewClientInCluster("default")
 		if err != nil {
@@ -107,10 +110,6 @@ func main() {
 	}
 
 	jobAgent := &jobs.JobAgent{}
-	if err := jobAgent.Start(*jobConfig); err != nil {
-		logrus.WithError(err).Fatal("Error starting job agent.")
-	}
-
 	pluginAgent := &plugins.PluginAgent{
 		PluginClient: plugins.PluginClient{
 			GitHubClient: githubClient,
@@ -119,31 +118,65 @@ func main() {
 			Logger:       logrus.NewEntry(logrus.StandardLogger()),
 		},
 	}
-	if err := pluginAgent.Start(*pluginConfig); err != nil {
-		logrus.WithError(err).Fatal("Error starting plugins.")
+	configAgent := &config.Agent{
+		JobAgent:    jobAgent,
+		PluginAgent: pluginAgent,
+	}
+	if err := configAgent.Start(*configPath); err != nil {
+		logrus.WithError(err).Fatal("Error starting config agent.")
 	}
 
-	prc := make(chan github.PullRequestEvent)
-	icc := make(chan github.IssueCommentEvent)
-	sec := make(chan github.StatusEvent)
-	server := &Server{
-		HMACSecret:         webhookSecret,
-		PullRequestEvents:  prc,
-		IssueCommentEvents: icc,
-		StatusEvents:       sec,
+	journal, err := NewJournal(*journalDir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error opening journal.")
 	}
 
 	events := &EventAgent{
-		Plugins:            pluginAgent,
-		PullRequestEvents:  prc,
-		IssueCommentEvents: icc,
-		StatusEvents:       sec,
+		Plugins: pluginAgent,
+		Journal: journal,
+		Workers: *workers,
 	}
 	events.Start()
+	server := &Server{
+		HMACSecret: webhookSecret,
+		Journal:    journal,
+		Dispatch:   events.Dispatch,
+	}
+
+	periodic := &PeriodicAgent{
+		Plugins:  pluginAgent,
+		Interval: *periodicInterval,
+	}
+	periodic.Start()
 
 	// Return 200 on / for health checks.
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
 	// For /hook, handle a webhook normally.
 	http.Handle("/hook", server)
-	logrus.Fatal(http.ListenAndServe(":"+strconv.Itoa(*port), nil))
+	// Serve the hash and load time of the config on /config.
+	http.Handle("/config", configAgent)
+	// Serve the help for plugins on /plugin-help.
+	http.Handle("/plugin-help", handlePluginHelp(pluginAgent))
+	// Serve metrics for Prometheus on /metrics.
+	http.Handle("/metrics", promhttp.Handler())
+	httpServer := &http.Server{Addr: ":" + strconv.Itoa(*port)}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			logrus.WithError(err).Fatal("Error serving.")
+		}
+	}()
+
+	// On SIGTERM, stop taking webhooks and finish the events that we have
+	// before exiting. Kubernetes sends SIGKILL after the grace period, but
+	// anything that we don't finish by then is replayed from the journal.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	<-sig
+	logrus.Infof("Draining %v queued events. %v events are in the journal.", metricValue(workerQueueDepth), metricValue(journalSize))
+	if err := httpServer.Shutdown(context.Background()); err != nil {
+		logrus.WithError(err).Error("Error shutting down HTTP server.")
+	}
+	events.Stop()
+	events.Wait()
+	logrus.Info("Finished draining events.")
 }
@@ -0,0 +1,92 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// Plugins can take a while, especially if they wait on GitHub.
+var handlerBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+var (
+	webhookCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_hook_webhooks_total",
+		Help: "Validated webhooks received, by event type and action.",
+	}, []string{"event_type", "action"})
+	hmacFailureCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "prow_hook_hmac_failures_total",
+		Help: "Webhooks rejected because their signature did not match.",
+	})
+	workerQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "prow_hook_worker_queue_depth",
+		Help: "Events queued for workers but not yet handled.",
+	})
+	journalSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "prow_hook_journal_events",
+		Help: "Events in the journal, which are those received but not yet handled, including any that didn't fit in the worker queues.",
+	})
+	handlerLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prow_hook_plugin_handle_duration_seconds",
+		Help:    "How long each plugin takes to handle an event, by event type and plugin.",
+		Buckets: handlerBuckets,
+	}, []string{"event_type", "plugin"})
+	handlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_hook_plugin_handle_errors_total",
+		Help: "Events that a plugin failed to handle, by event type and plugin.",
+	}, []string{"event_type", "plugin"})
+)
+
+// registerMetrics registers hook's metrics, along with those of the GitHub
+// client, so that /metrics serves them.
+func registerMetrics() {
+	prometheus.MustRegister(
+		webhookCounter,
+		hmacFailureCounter,
+		workerQueueDepth,
+		journalSize,
+		handlerLatency,
+		handlerErrors,
+		github.RequestCounter,
+	)
+}
+
+// observeHandler records how long a plugin took to handle an event and
+// whether it failed.
+func observeHandler(eventType, plugin string, start time.Time, err error) {
+	handlerLatency.WithLabelValues(eventType, plugin).Observe(time.Since(start).Seconds())
+	if err != nil {
+		handlerErrors.WithLabelValues(eventType, plugin).Inc()
+	}
+}
+
+// metricValue returns the current value of a counter or gauge.
+func metricValue(m prometheus.Metric) float64 {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		return 0
+	}
+	if pb.Counter != nil {
+		return pb.Counter.GetValue()
+	}
+	return pb.Gauge.GetValue()
+}
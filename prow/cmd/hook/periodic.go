@@ -0,0 +1,63 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// PeriodicAgent calls the periodic handlers of every plugin for each org and
+// repo that enables it, such as to catch up on anything that events missed.
+type PeriodicAgent struct {
+	Plugins  *plugins.PluginAgent
+	Interval time.Duration
+}
+
+// Start runs the handlers now and then once every interval. It does not
+// block.
+func (pa *PeriodicAgent) Start() {
+	go func() {
+		pa.Run()
+		for range time.Tick(pa.Interval) {
+			pa.Run()
+		}
+	}()
+}
+
+// Run calls every periodic handler once.
+func (pa *PeriodicAgent) Run() {
+	for k, hs := range pa.Plugins.PeriodicHandlers() {
+		for p, h := range hs {
+			pc := pa.Plugins.PluginClient
+			pc.Logger = logrus.WithFields(logrus.Fields{
+				"plugin": p,
+				"scope":  k,
+			})
+			pc.PluginConfig = pa.Plugins.Config()
+			start := time.Now()
+			err := h(pc, k)
+			observeHandler("periodic", p, start, err)
+			if err != nil {
+				pc.Logger.WithError(err).Error("Error running periodic handler.")
+			}
+		}
+	}
+}
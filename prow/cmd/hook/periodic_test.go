@@ -0,0 +1,60 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestPeriodicAgentRun(t *testing.T) {
+	var scopes []string
+	plugins.RegisterPeriodicHandler("periodic-test", func(pc plugins.PluginClient, orgOrRepo string) error {
+		if pc.PluginConfig == nil {
+			t.Error("Expected plugin config.")
+		}
+		scopes = append(scopes, orgOrRepo)
+		return nil
+	}, nil)
+	pa := &plugins.PluginAgent{
+		PluginClient: plugins.PluginClient{Logger: logrus.NewEntry(logrus.StandardLogger())},
+	}
+	if err := pa.Set(&plugins.Configuration{
+		Plugins: map[string][]string{
+			"org":        {"periodic-test"},
+			"other/repo": {"periodic-test"},
+			"third":      {},
+		},
+		Settings: map[string]plugins.Settings{
+			"org":   {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}, CommandPrefix: "@bot"},
+			"other": {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}, CommandPrefix: "@bot"},
+			"third": {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}, CommandPrefix: "@bot"},
+		},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+	(&PeriodicAgent{Plugins: pa}).Run()
+	sort.Strings(scopes)
+	if expected := []string{"org", "other/repo"}; !reflect.DeepEqual(scopes, expected) {
+		t.Errorf("Expected handlers for %v, got %v.", expected, scopes)
+	}
+}
@@ -19,24 +19,32 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/Sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
 
 	"k8s.io/test-infra/prow/github"
 )
 
-// Server implements http.Handler. It validates incoming GitHub webhooks and
-// then places them into the appropriate event channels.
+// Delivery IDs become file names in the journal, so be strict about them.
+var guidRe = regexp.MustCompile(`^[\w-]+$`)
+
+// Server implements http.Handler. It validates incoming GitHub webhooks,
+// records them in the journal, and then hands them to Dispatch.
 type Server struct {
-	PullRequestEvents  chan<- github.PullRequestEvent
-	IssueCommentEvents chan<- github.IssueCommentEvent
-	StatusEvents       chan<- github.StatusEvent
+	Journal *Journal
+	// Dispatch takes journaled events. It must not block, since GitHub
+	// waits for the response.
+	Dispatch func(Event)
 
 	HMACSecret []byte
 }
 
-// ServeHTTP validates an incoming webhook and puts it into the event channel.
+// ServeHTTP validates an incoming webhook and hands it off to be handled.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -55,6 +63,14 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "403 Forbidden: Missing X-Hub-Signature", http.StatusForbidden)
 		return
 	}
+	// GitHub always sends a delivery ID, but make one up for hand-crafted hooks.
+	guid := r.Header.Get("X-GitHub-Delivery")
+	if guid == "" {
+		guid = uuid.NewV4().String()
+	} else if !guidRe.MatchString(guid) {
+		http.Error(w, "400 Bad Request: Invalid X-GitHub-Delivery Header", http.StatusBadRequest)
+		return
+	}
 
 	payload, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -64,43 +80,34 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Validate the payload with our HMAC secret.
 	if !github.ValidatePayload(payload, sig, s.HMACSecret) {
+		hmacFailureCounter.Inc()
 		http.Error(w, "403 Forbidden: Invalid X-Hub-Signature", http.StatusForbidden)
 		return
 	}
-	fmt.Fprint(w, "Event received. Have a nice day.")
 
-	if err := s.demuxEvent(eventType, payload); err != nil {
-		logrus.WithError(err).Error("Error parsing event.")
+	// Most events have an action, such as "opened" or "created".
+	var a struct {
+		Action string `json:"action"`
 	}
-}
+	json.Unmarshal(payload, &a)
+	webhookCounter.WithLabelValues(eventType, a.Action).Inc()
 
-func (s *Server) demuxEvent(eventType string, payload []byte) error {
-	switch eventType {
-	case "pull_request":
-		var pr github.PullRequestEvent
-		if err := json.Unmarshal(payload, &pr); err != nil {
-			return err
-		}
-		go func() {
-			s.PullRequestEvents <- pr
-		}()
-	case "issue_comment":
-		var ic github.IssueCommentEvent
-		if err := json.Unmarshal(payload, &ic); err != nil {
-			return err
-		}
-		go func() {
-			s.IssueCommentEvents <- ic
-		}()
-	case "status":
-		var se github.StatusEvent
-		if err := json.Unmarshal(payload, &se); err != nil {
-			return err
-		}
-		go func() {
-			s.StatusEvents <- se
-		}()
+	// Don't acknowledge the webhook until it is safely on disk.
+	e := Event{
+		GUID:     guid,
+		Type:     eventType,
+		Payload:  payload,
+		Received: time.Now(),
+	}
+	if fresh, err := s.Journal.Append(e); err != nil {
+		logrus.WithError(err).WithField("guid", guid).Error("Error journaling event.")
+		http.Error(w, "500 Internal Server Error: Failed to journal event", http.StatusInternalServerError)
+		return
+	} else if !fresh {
+		fmt.Fprint(w, "Event already received.")
+		return
 	}
+	fmt.Fprint(w, "Event received. Have a nice day.")
 
-	return nil
+	s.Dispatch(e)
 }
@@ -17,15 +17,29 @@ limitations under the License.
 package main
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 )
 
 func TestServeHTTPErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dispatched []Event
 	s := &Server{
 		HMACSecret: []byte("abc"),
+		Journal:    j,
+		Dispatch:   func(e Event) { dispatched = append(dispatched, e) },
 	}
 	// This is the SHA1 signature for payload "{}" and signature "abc"
 	// echo -n '{}' | openssl dgst -sha1 -hmac abc
@@ -75,6 +89,17 @@ func TestServeHTTPErrors(t *testing.T) {
 			Body: body,
 			Code: http.StatusForbidden,
 		},
+		{
+			// Bad delivery ID
+			Method: http.MethodPost,
+			Header: map[string]string{
+				"X-GitHub-Event":    "ping",
+				"X-GitHub-Delivery": "../../etc/passwd",
+				"X-Hub-Signature":   hmac,
+			},
+			Body: body,
+			Code: http.StatusBadRequest,
+		},
 		{
 			// Good
 			Method: http.MethodPost,
@@ -87,6 +112,8 @@ func TestServeHTTPErrors(t *testing.T) {
 		},
 	}
 
+	hmacFailures := metricValue(hmacFailureCounter)
+	pings := metricValue(webhookCounter.WithLabelValues("ping", ""))
 	for _, tc := range testcases {
 		w := httptest.NewRecorder()
 		r, err := http.NewRequest(tc.Method, "", strings.NewReader(tc.Body))
@@ -100,5 +127,62 @@ func TestServeHTTPErrors(t *testing.T) {
 		if w.Code != tc.Code {
 			t.Errorf("For test case: %+v\nExpected code %v, got code %v", tc, tc.Code, w.Code)
 		}
+		if tc.Code == http.StatusOK && len(dispatched) != 1 {
+			t.Errorf("For test case: %+v\nExpected the event to be dispatched.", tc)
+		}
+		dispatched = nil
+	}
+	if n := metricValue(hmacFailureCounter) - hmacFailures; n != 1 {
+		t.Errorf("Expected one HMAC failure, got %v", n)
+	}
+	if n := metricValue(webhookCounter.WithLabelValues("ping", "")) - pings; n != 1 {
+		t.Errorf("Expected one valid ping webhook, got %v", n)
+	}
+}
+
+func TestServeHTTPJournals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j, err := NewJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dispatched []Event
+	s := &Server{
+		HMACSecret: []byte("abc"),
+		Journal:    j,
+		Dispatch:   func(e Event) { dispatched = append(dispatched, e) },
+	}
+	// GitHub redelivers the same hook twice, we should only handle it once.
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodPost, "", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-GitHub-Event", "issue_comment")
+		r.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		r.Header.Set("X-Hub-Signature", "sha1=db5c76f4264d0ad96cf21baec394964b4b8ce580")
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected code 200, got %d", w.Code)
+		}
+	}
+	if len(dispatched) != 1 {
+		t.Fatalf("Expected one event, got %d", len(dispatched))
+	}
+	e := dispatched[0]
+	if e.GUID != "72d3162e-cc78-11e3-81ab-4c9367dc0958" || e.Type != "issue_comment" {
+		t.Errorf("Wrong event: %+v", e)
+	}
+	pending, err := j.Pending()
+	if err != nil {
+		t.Fatalf("Error reading journal: %v", err)
+	}
+	if len(pending) != 1 || pending[0].GUID != e.GUID {
+		t.Errorf("Expected the event to be pending, got %+v", pending)
 	}
 }
@@ -0,0 +1,202 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Horologium starts periodic jobs on their schedules.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/line"
+)
+
+var (
+	configPath = flag.String("config-path", "/etc/config/config", "Path to the prow config file.")
+)
+
+const (
+	period    = time.Minute
+	namespace = "default"
+	// Sinker deletes old jobs, so we remember when we last started each
+	// periodic in this ConfigMap.
+	stateConfigMap = "horologium"
+)
+
+type kubeClient interface {
+	ListJobs(labels map[string]string) ([]kube.Job, error)
+	GetConfigMap(name string) (kube.ConfigMap, error)
+	CreateConfigMap(cm kube.ConfigMap) (kube.ConfigMap, error)
+	ReplaceConfigMap(name string, cm kube.ConfigMap) (kube.ConfigMap, error)
+}
+
+type jobAgent interface {
+	AllPeriodics() []jobs.Periodic
+}
+
+// scheduler decides which periodics are due. It records when it started each
+// job in the state ConfigMap so that a restart does not start them all again,
+// and it also reads the start-time annotation that line puts on every job.
+type scheduler struct {
+	kc    kubeClient
+	ja    jobAgent
+	start func(name string) error
+
+	// Cron periodics that have never run wait for their next scheduled time
+	// after this rather than running right away.
+	started time.Time
+	lastRun map[string]time.Time
+	// The state ConfigMap as we last read or wrote it, or nil if we need to
+	// read it again.
+	state *kube.ConfigMap
+}
+
+func main() {
+	flag.Parse()
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	ja := &jobs.JobAgent{}
+	ca := &config.Agent{JobAgent: ja}
+	if err := ca.Start(*configPath); err != nil {
+		logrus.WithError(err).Fatal("Could not start config agent.")
+	}
+
+	kc, err := kube.NewClientInCluster(namespace)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting kube client.")
+	}
+
+	s := &scheduler{
+		kc: kc,
+		ja: ja,
+		start: func(name string) error {
+			return line.StartJob(kc, name, "", line.BuildRequest{})
+		},
+		started: time.Now(),
+		lastRun: make(map[string]time.Time),
+	}
+	for now := range time.Tick(period) {
+		if err := s.sync(now); err != nil {
+			logrus.WithError(err).Error("Error syncing periodic jobs.")
+		}
+	}
+}
+
+// sync starts every periodic that is due at now.
+func (s *scheduler) sync(now time.Time) error {
+	if s.state == nil {
+		if err := s.load(); err != nil {
+			return fmt.Errorf("could not read the last runs: %v", err)
+		}
+	}
+	kjs, err := s.kc.ListJobs(map[string]string{"type": "periodic"})
+	if err != nil {
+		return err
+	}
+	for _, kj := range kjs {
+		name := kj.Metadata.Labels["jenkins-job-name"]
+		st, err := time.Parse(time.RFC3339, kj.Metadata.Annotations["start-time"])
+		if err != nil {
+			st = kj.Status.StartTime
+		}
+		if st.After(s.lastRun[name]) {
+			s.lastRun[name] = st
+		}
+	}
+	ps := s.ja.AllPeriodics()
+	started := false
+	for _, p := range ps {
+		var next time.Time
+		if last, ok := s.lastRun[p.Name]; ok {
+			next = p.NextRun(last)
+		} else if p.Cron != "" {
+			// A cron job that never ran waits for its next time after we
+			// started, rather than running as soon as it is added.
+			next = p.NextRun(s.started)
+		} else {
+			// An interval job that never ran is due now.
+			next = now
+		}
+		// A cron schedule may never come round, such as on February 30th.
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+		if err := s.start(p.Name); err != nil {
+			logrus.WithError(err).WithField("job", p.Name).Error("Error starting periodic job.")
+			continue
+		}
+		logrus.WithField("job", p.Name).Info("Started periodic job.")
+		s.lastRun[p.Name] = now
+		started = true
+	}
+	if started {
+		if err := s.save(ps); err != nil {
+			return fmt.Errorf("could not record the last runs: %v", err)
+		}
+	}
+	return nil
+}
+
+// load reads when each periodic last started from the state ConfigMap. It
+// creates the ConfigMap if it does not exist yet.
+func (s *scheduler) load() error {
+	cm, err := s.kc.GetConfigMap(stateConfigMap)
+	if _, ok := err.(kube.NotFoundError); ok {
+		cm, err = s.kc.CreateConfigMap(kube.ConfigMap{
+			Metadata: kube.ObjectMeta{Name: stateConfigMap},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	for name, v := range cm.Data {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logrus.WithError(err).WithField("job", name).Warning("Bad last run time.")
+			continue
+		}
+		if t.After(s.lastRun[name]) {
+			s.lastRun[name] = t
+		}
+	}
+	s.state = &cm
+	return nil
+}
+
+// save records when each of the periodics last started in the state
+// ConfigMap. If that fails, the next sync reads the ConfigMap again.
+func (s *scheduler) save(ps []jobs.Periodic) error {
+	cm := *s.state
+	cm.Data = make(map[string]string)
+	for _, p := range ps {
+		if t, ok := s.lastRun[p.Name]; ok {
+			cm.Data[p.Name] = t.Format(time.RFC3339)
+		}
+	}
+	cm, err := s.kc.ReplaceConfigMap(stateConfigMap, cm)
+	if err != nil {
+		s.state = nil
+		return err
+	}
+	s.state = &cm
+	return nil
+}
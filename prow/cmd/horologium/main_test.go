@@ -0,0 +1,259 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/kube"
+)
+
+type fakeKube struct {
+	jobs      []kube.Job
+	configMap *kube.ConfigMap
+}
+
+func (f *fakeKube) ListJobs(labels map[string]string) ([]kube.Job, error) {
+	return f.jobs, nil
+}
+
+func (f *fakeKube) GetConfigMap(name string) (kube.ConfigMap, error) {
+	if f.configMap == nil || f.configMap.Metadata.Name != name {
+		return kube.ConfigMap{}, kube.NotFoundError{}
+	}
+	return *f.configMap, nil
+}
+
+func (f *fakeKube) CreateConfigMap(cm kube.ConfigMap) (kube.ConfigMap, error) {
+	f.configMap = &cm
+	return cm, nil
+}
+
+func (f *fakeKube) ReplaceConfigMap(name string, cm kube.ConfigMap) (kube.ConfigMap, error) {
+	f.configMap = &cm
+	return cm, nil
+}
+
+type fakeJobAgent struct {
+	periodics []jobs.Periodic
+}
+
+func (f *fakeJobAgent) AllPeriodics() []jobs.Periodic {
+	return f.periodics
+}
+
+func kubeJob(name string, start time.Time) kube.Job {
+	return kube.Job{
+		Metadata: kube.ObjectMeta{
+			Labels:      map[string]string{"jenkins-job-name": name, "type": "periodic"},
+			Annotations: map[string]string{"start-time": start.Format(time.RFC3339)},
+		},
+	}
+}
+
+func periodics(t *testing.T) []jobs.Periodic {
+	ja := &jobs.JobAgent{}
+	if err := ja.SetPeriodics([]jobs.Periodic{
+		{Name: "every-hour", Interval: "1h"},
+		{Name: "on-the-half-hour", Cron: "30 * * * *"},
+	}); err != nil {
+		t.Fatalf("Error setting periodics: %v", err)
+	}
+	return ja.AllPeriodics()
+}
+
+func TestSync(t *testing.T) {
+	started := time.Date(2017, time.January, 1, 10, 0, 0, 0, time.UTC)
+	now := started.Add(30 * time.Minute)
+	var testcases = []struct {
+		name     string
+		kubeJobs []kube.Job
+		lastRun  map[string]time.Time
+		expected []string
+	}{
+		{
+			name:     "nothing has run",
+			expected: []string{"every-hour", "on-the-half-hour"},
+		},
+		{
+			name: "interval job ran recently",
+			kubeJobs: []kube.Job{
+				kubeJob("every-hour", now.Add(-10*time.Minute)),
+			},
+			expected: []string{"on-the-half-hour"},
+		},
+		{
+			name: "interval job ran long ago",
+			kubeJobs: []kube.Job{
+				kubeJob("every-hour", now.Add(-2*time.Hour)),
+				kubeJob("every-hour", now.Add(-3*time.Hour)),
+			},
+			expected: []string{"every-hour", "on-the-half-hour"},
+		},
+		{
+			name: "remembered run but the job is gone",
+			lastRun: map[string]time.Time{
+				"every-hour":       now.Add(-5 * time.Minute),
+				"on-the-half-hour": now,
+			},
+		},
+		{
+			name: "cron job already ran this half hour",
+			kubeJobs: []kube.Job{
+				kubeJob("on-the-half-hour", now),
+				kubeJob("every-hour", now),
+			},
+		},
+	}
+	for _, tc := range testcases {
+		var ran []string
+		s := &scheduler{
+			kc: &fakeKube{jobs: tc.kubeJobs},
+			ja: &fakeJobAgent{periodics: periodics(t)},
+			start: func(name string) error {
+				ran = append(ran, name)
+				return nil
+			},
+			started: started,
+			lastRun: tc.lastRun,
+		}
+		if s.lastRun == nil {
+			s.lastRun = make(map[string]time.Time)
+		}
+		if err := s.sync(now); err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		sort.Strings(ran)
+		if !reflect.DeepEqual(ran, tc.expected) {
+			t.Errorf("For case %s, expected to start %v, started %v.", tc.name, tc.expected, ran)
+		}
+	}
+}
+
+func TestSyncNeverRun(t *testing.T) {
+	started := time.Date(2017, time.January, 1, 10, 0, 0, 0, time.UTC)
+	var testcases = []struct {
+		name     string
+		periodic jobs.Periodic
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "interval job right after starting",
+			periodic: jobs.Periodic{Name: "p", Interval: "1h"},
+			now:      started,
+			expected: true,
+		},
+		{
+			name:     "long interval job right after starting",
+			periodic: jobs.Periodic{Name: "p", Interval: "10000h"},
+			now:      started,
+			expected: true,
+		},
+		{
+			name:     "cron job before its time",
+			periodic: jobs.Periodic{Name: "p", Cron: "30 * * * *"},
+			now:      started.Add(29 * time.Minute),
+		},
+		{
+			name:     "cron job at its time",
+			periodic: jobs.Periodic{Name: "p", Cron: "30 * * * *"},
+			now:      started.Add(30 * time.Minute),
+			expected: true,
+		},
+		{
+			name:     "cron job that never comes round",
+			periodic: jobs.Periodic{Name: "p", Cron: "0 0 30 2 *"},
+			now:      started.Add(24 * time.Hour),
+		},
+	}
+	for _, tc := range testcases {
+		ja := &jobs.JobAgent{}
+		if err := ja.SetPeriodics([]jobs.Periodic{tc.periodic}); err != nil {
+			t.Fatalf("For case %s, error setting periodics: %v", tc.name, err)
+		}
+		ran := false
+		s := &scheduler{
+			kc: &fakeKube{},
+			ja: ja,
+			start: func(name string) error {
+				ran = true
+				return nil
+			},
+			started: started,
+			lastRun: make(map[string]time.Time),
+		}
+		if err := s.sync(tc.now); err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		if ran != tc.expected {
+			t.Errorf("For case %s, expected started %t, got %t.", tc.name, tc.expected, ran)
+		}
+	}
+}
+
+// After a restart, horologium must not start jobs again just because sinker
+// deleted the old ones.
+func TestSyncAfterRestart(t *testing.T) {
+	started := time.Date(2017, time.January, 1, 10, 0, 0, 0, time.UTC)
+	now := started.Add(30 * time.Minute)
+	fk := &fakeKube{}
+	var testcases = []struct {
+		name     string
+		now      time.Time
+		expected []string
+	}{
+		{
+			name:     "first run",
+			now:      now,
+			expected: []string{"every-hour", "on-the-half-hour"},
+		},
+		{
+			name: "restarted soon after",
+			now:  now.Add(10 * time.Minute),
+		},
+		{
+			name:     "restarted an hour later",
+			now:      now.Add(61 * time.Minute),
+			expected: []string{"every-hour", "on-the-half-hour"},
+		},
+	}
+	for _, tc := range testcases {
+		var ran []string
+		s := &scheduler{
+			kc: fk,
+			ja: &fakeJobAgent{periodics: periodics(t)},
+			start: func(name string) error {
+				ran = append(ran, name)
+				return nil
+			},
+			started: tc.now.Add(-time.Minute),
+			lastRun: make(map[string]time.Time),
+		}
+		if err := s.sync(tc.now); err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		sort.Strings(ran)
+		if !reflect.DeepEqual(ran, tc.expected) {
+			t.Errorf("For case %s, expected to start %v, started %v.", tc.name, tc.expected, ran)
+		}
+	}
+}
@@ -28,11 +28,11 @@ import (
 	"os"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/jenkins"
 	"k8s.io/test-infra/prow/jobs"
@@ -54,7 +54,7 @@ var (
 	dryRun    = flag.Bool("dry-run", true, "Whether or not to make mutating GitHub/Jenkins calls.")
 	report    = flag.Bool("report", true, "Whether or not to report the status on GitHub.")
 
-	jobConfigs       = flag.String("job-config", "/etc/jobs/jobs", "Where the job-config configmap is mounted.")
+	configPath       = flag.String("config-path", "/etc/config/config", "Path to the prow config file.")
 	labelsPath       = flag.String("labels-path", "/etc/labels/labels", "Where our metadata.labels are mounted.")
 	githubTokenFile  = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth secret.")
 	jenkinsURL       = flag.String("jenkins-url", "http://pull-jenkins-master:8080", "Jenkins URL")
@@ -63,12 +63,15 @@ var (
 )
 
 const (
-	guberBase = "https://k8s-gubernator.appspot.com/build/kubernetes-jenkins/pr-logs/pull"
-	testInfra = "https://github.com/kubernetes/test-infra/issues"
+	guberBase     = "https://k8s-gubernator.appspot.com/build/kubernetes-jenkins/pr-logs/pull"
+	guberLogsBase = "https://k8s-gubernator.appspot.com/build/kubernetes-jenkins/logs"
+	testInfra     = "https://github.com/kubernetes/test-infra/issues"
 )
 
 type testClient struct {
 	Job jobs.JenkinsJob
+	// The type label from line.StartJob, such as "pr" or "postsubmit".
+	Type string
 
 	RepoOwner string
 	RepoName  string
@@ -78,6 +81,9 @@ type testClient struct {
 	PullSHA   string
 	Refs      string
 
+	// The repo's prefix for bot commands, such as "@k8s-bot".
+	CommandPrefix string
+
 	DryRun bool
 	Report bool
 
@@ -88,10 +94,12 @@ type testClient struct {
 }
 
 type githubClient interface {
+	BotName() (string, error)
 	CreateStatus(owner, repo, ref string, s github.Status) error
 	ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error)
 	CreateComment(owner, repo string, number int, comment string) error
 	DeleteComment(owner, repo string, ID int) error
+	EditComment(owner, repo string, ID int, comment string) error
 }
 
 func main() {
@@ -131,22 +139,31 @@ func main() {
 		logrus.Fatalf("Error getting client: %v", err)
 	}
 
-	kubeJob, err := getKubeJob(*labelsPath)
+	labels, err := getLabels(*labelsPath)
 	if err != nil {
-		logrus.Fatalf("Error getting kube job name: %v", err)
+		logrus.Fatalf("Error getting kube job labels: %v", err)
+	}
+	kubeJob := labels["job-name"]
+	if kubeJob == "" {
+		logrus.Fatalf("Could not find job-name in %s", *labelsPath)
 	}
 
+	c, err := config.Load(*configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading config.")
+	}
 	ja := jobs.JobAgent{}
-	if err := ja.LoadOnce(*jobConfigs); err != nil {
+	if err := ja.SetConfig(c.JobConfig); err != nil {
 		logrus.WithError(err).Fatal("Error loading job config.")
 	}
-	found, jenkinsJob := ja.GetJob(fmt.Sprintf("%s/%s", *repoOwner, *repoName), *job)
-	if !found {
-		logrus.Fatalf("Could not find job %s in job config.", *job)
+	jenkinsJob, err := findJob(&ja, labels["type"], fmt.Sprintf("%s/%s", *repoOwner, *repoName), *job)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error finding job.")
 	}
 
 	client := &testClient{
 		Job:       jenkinsJob,
+		Type:      labels["type"],
 		RepoOwner: *repoOwner,
 		RepoName:  *repoName,
 		PRNumber:  *pr,
@@ -155,6 +172,8 @@ func main() {
 		PullSHA:   *pullSHA,
 		Refs:      *refs,
 
+		CommandPrefix: c.SettingsFor(*repoOwner, *repoName).CommandPrefix,
+
 		DryRun: *dryRun,
 		Report: *report && !jenkinsJob.SkipReport,
 
@@ -174,6 +193,39 @@ func main() {
 	}
 }
 
+// findJob looks up the job in the job config. Postsubmits and periodics are
+// converted into a JenkinsJob that never reports to GitHub since there is no
+// PR to report to.
+func findJob(ja *jobs.JobAgent, jobType, repo, name string) (jobs.JenkinsJob, error) {
+	if jobType == "periodic" {
+		found, p := ja.GetPeriodic(name)
+		if !found {
+			return jobs.JenkinsJob{}, fmt.Errorf("could not find periodic %s in job config", name)
+		}
+		return jobs.JenkinsJob{
+			Name:       p.Name,
+			SkipReport: true,
+			Spec:       p.Spec,
+		}, nil
+	}
+	if jobType == "postsubmit" {
+		found, ps := ja.GetPostsubmit(repo, name)
+		if !found {
+			return jobs.JenkinsJob{}, fmt.Errorf("could not find postsubmit %s for %s in job config", name, repo)
+		}
+		return jobs.JenkinsJob{
+			Name:       ps.Name,
+			SkipReport: true,
+			Spec:       ps.Spec,
+		}, nil
+	}
+	found, jenkinsJob := ja.GetJob(repo, name)
+	if !found {
+		return jobs.JenkinsJob{}, fmt.Errorf("could not find job %s for %s in job config", name, repo)
+	}
+	return jenkinsJob, nil
+}
+
 func fields(c *testClient) logrus.Fields {
 	return logrus.Fields{
 		"job":      c.Job.Name,
@@ -272,10 +324,11 @@ func (c *testClient) TestPRKubernetes() error {
 		}
 		if po.Status.Phase == kube.PodSucceeded {
 			c.tryCreateStatus(podName, github.StatusSuccess, "Build succeeded.", resultURL)
+			c.tryUpdateFailureComment(resultURL, false)
 			break
 		} else if po.Status.Phase == kube.PodFailed {
 			c.tryCreateStatus(podName, github.StatusFailure, "Build failed.", resultURL)
-			c.tryCreateFailureComment(resultURL)
+			c.tryUpdateFailureComment(resultURL, true)
 			break
 		} else if po.Status.Phase == kube.PodUnknown {
 			c.tryCreateStatus(podName, github.StatusError, "Error watching build.", resultURL)
@@ -342,10 +395,11 @@ func (c *testClient) TestPRJenkins() error {
 		} else {
 			if result.Success {
 				c.tryCreateStatus("", github.StatusSuccess, "Build succeeded.", resultURL)
+				c.tryUpdateFailureComment(resultURL, false)
 				break
 			} else {
 				c.tryCreateStatus("", github.StatusFailure, "Build failed.", resultURL)
-				c.tryCreateFailureComment(resultURL)
+				c.tryUpdateFailureComment(resultURL, true)
 				break
 			}
 		}
@@ -355,6 +409,9 @@ func (c *testClient) TestPRJenkins() error {
 }
 
 func (c *testClient) guberURL(build string) string {
+	if c.Type == "postsubmit" || c.Type == "periodic" {
+		return fmt.Sprintf("%s/%s/%s/", guberLogsBase, c.Job.Name, build)
+	}
 	url := guberBase
 	if c.RepoOwner != "kubernetes" {
 		url = fmt.Sprintf("%s/%s_%s", url, c.RepoOwner, c.RepoName)
@@ -389,51 +446,25 @@ func (c *testClient) tryCreateStatus(podName, state, desc, url string) {
 	}
 }
 
-func (c *testClient) tryCreateFailureComment(url string) {
-	if !c.Report {
-		return
-	}
-	ics, err := c.GitHubClient.ListIssueComments(c.RepoOwner, c.RepoName, c.PRNumber)
-	if err != nil {
-		logrus.WithFields(fields(c)).WithError(err).Error("Error listing issue comments.")
-		return
-	}
-	for _, ic := range ics {
-		if ic.User.Login != "k8s-ci-robot" {
-			continue
-		}
-		if strings.HasPrefix(ic.Body, c.Job.Context) {
-			if err := c.GitHubClient.DeleteComment(c.RepoOwner, c.RepoName, ic.ID); err != nil {
-				logrus.WithFields(fields(c)).WithError(err).Error("Error deleting comment.")
-			}
-		}
-	}
-	// The deletion logic requires that it start with context.
-	// TODO: Fix pr-test link for non-kubernetes repos.
-	bodyFormat := `%s [**failed**](%s) for commit %s. [Full PR test history](http://pr-test.k8s.io/%d).
-
-The magic incantation to run this job again is ` + "`%s`" + `. Please help us cut down flakes by linking to an [open flake issue](https://github.com/%s/%s/issues?q=is:issue+label:kind/flake+is:open) when you hit one in your PR.`
-	body := fmt.Sprintf(bodyFormat, c.Job.Context, url, c.PullSHA, c.PRNumber, c.Job.RerunCommand, c.RepoOwner, c.RepoName)
-	if err := c.GitHubClient.CreateComment(c.RepoOwner, c.RepoName, c.PRNumber, body); err != nil {
-		logrus.WithFields(fields(c)).WithError(err).Error("Error creating comment.")
-	}
-}
-
-func getKubeJob(path string) (string, error) {
+// getLabels reads our metadata.labels from the downward API file, which has
+// lines such as job-name="abc".
+func getLabels(path string) (map[string]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	re := regexp.MustCompile(`^job-name="([^"]+)"$`)
+	defer f.Close()
+	re := regexp.MustCompile(`^([^=]+)="([^"]*)"$`)
+	labels := map[string]string{}
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		m := re.FindStringSubmatch(scanner.Text())
-		if len(m) == 2 {
-			return m[1], nil
+		if len(m) == 3 {
+			labels[m[1]] = m[2]
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return "", err
+		return nil, err
 	}
-	return "", fmt.Errorf("could not find job-name in %s", path)
+	return labels, nil
 }
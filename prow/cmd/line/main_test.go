@@ -17,7 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
@@ -53,17 +57,32 @@ func TestFailureComment(t *testing.T) {
 		},
 		IssueCommentID: 9,
 	}
-	cl := testClient{
-		Job: jobs.JenkinsJob{
-			Name:    "test-job",
-			Context: "Jenkins test",
-		},
-		PRNumber:     5,
-		PullSHA:      "abcde",
-		Report:       true,
-		GitHubClient: ghc,
+	newClient := func(context string) testClient {
+		return testClient{
+			Job: jobs.JenkinsJob{
+				Name:         "test-job",
+				Context:      context,
+				RerunCommand: "@k8s-bot " + context + " this",
+			},
+			PRNumber:     5,
+			PullSHA:      "abcde",
+			Report:       true,
+			GitHubClient: ghc,
+		}
 	}
-	cl.tryCreateFailureComment("url")
+	summaries := func() []github.IssueComment {
+		var ics []github.IssueComment
+		for _, ic := range ghc.IssueComments[5] {
+			if strings.Contains(ic.Body, failureMarker) {
+				ics = append(ics, ic)
+			}
+		}
+		return ics
+	}
+
+	// The first failure replaces the old style comments with a summary.
+	jenkins := newClient("Jenkins test")
+	jenkins.tryUpdateFailureComment("url1", true)
 	newComments, _ := ghc.ListIssueComments("", "", 5)
 	if len(newComments) != 3 {
 		t.Errorf("Expected 3 comments after creating failed comment, got %+v", newComments)
@@ -73,6 +92,125 @@ func TestFailureComment(t *testing.T) {
 			t.Errorf("Comment not deleted: %v", comment.ID)
 		}
 	}
+
+	// A second failure edits the same summary.
+	e2e := newClient("e2e test")
+	e2e.tryUpdateFailureComment("url2", true)
+	if s := summaries(); len(s) != 1 || s[0].ID != 9 {
+		t.Fatalf("Expected one summary with ID 9, got %+v", s)
+	} else if rows := parseFailureComment(s[0].Body); len(rows) != 2 {
+		t.Errorf("Expected two failed jobs, got %+v from %s", rows, s[0].Body)
+	} else if rows[0] != (failedJob{"Jenkins test", "abcde", "url1", "@k8s-bot Jenkins test this"}) {
+		t.Errorf("Wrong first row: %+v", rows[0])
+	} else if rows[1] != (failedJob{"e2e test", "abcde", "url2", "@k8s-bot e2e test this"}) {
+		t.Errorf("Wrong second row: %+v", rows[1])
+	}
+
+	// Passing removes the job from the summary.
+	jenkins.tryUpdateFailureComment("url3", false)
+	if s := summaries(); len(s) != 1 {
+		t.Fatalf("Expected one summary, got %+v", s)
+	} else if rows := parseFailureComment(s[0].Body); len(rows) != 1 || rows[0].Context != "e2e test" {
+		t.Errorf("Expected only the e2e test, got %+v", rows)
+	}
+
+	// Once everything passes, the summary goes away.
+	e2e.tryUpdateFailureComment("url4", false)
+	if s := summaries(); len(s) != 0 {
+		t.Errorf("Expected no summary, got %+v", s)
+	}
+	if len(ghc.IssueComments[5]) != 2 {
+		t.Errorf("Expected only the unrelated comments, got %+v", ghc.IssueComments[5])
+	}
+}
+
+func TestMergeFailureComments(t *testing.T) {
+	first := failureComment([]failedJob{{"a", "abc", "url-a", "test a"}}, "org", "repo", 5, "@k8s-bot")
+	second := failureComment([]failedJob{{"a", "def", "url-a2", "test a"}, {"b", "abc", "url-b", "test b"}}, "org", "repo", 5, "@k8s-bot")
+	ghc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{
+			5: {
+				{ID: 1, Body: first, User: github.User{Login: "k8s-ci-robot"}},
+				{ID: 2, Body: second, User: github.User{Login: "k8s-ci-robot"}},
+			},
+		},
+		IssueCommentID: 3,
+	}
+	cl := testClient{
+		Job:          jobs.JenkinsJob{Context: "c", RerunCommand: "test c"},
+		PRNumber:     5,
+		PullSHA:      "abc",
+		Report:       true,
+		GitHubClient: ghc,
+	}
+	cl.tryUpdateFailureComment("url-c", true)
+	ics := ghc.IssueComments[5]
+	if len(ics) != 1 || ics[0].ID != 1 {
+		t.Fatalf("Expected only the first summary to remain, got %+v", ics)
+	}
+	var contexts []string
+	for _, r := range parseFailureComment(ics[0].Body) {
+		contexts = append(contexts, r.Context)
+	}
+	if strings.Join(contexts, ",") != "a,b,c" {
+		t.Errorf("Expected rows for a, b and c, got %v", contexts)
+	}
+}
+
+// clobberingClient replaces the summary with another job's once, just after
+// our first edit, as if that job had read the summary before we wrote it.
+type clobberingClient struct {
+	*fakegithub.FakeClient
+	other   string
+	clobber bool
+}
+
+func (c *clobberingClient) EditComment(owner, repo string, ID int, comment string) error {
+	if err := c.FakeClient.EditComment(owner, repo, ID, comment); err != nil {
+		return err
+	}
+	if !c.clobber {
+		c.clobber = true
+		return c.FakeClient.EditComment(owner, repo, ID, c.other)
+	}
+	return nil
+}
+
+func TestFailureCommentRace(t *testing.T) {
+	oldSleep := summarySleep
+	defer func() { summarySleep = oldSleep }()
+	summarySleep = func(time.Duration) {}
+
+	first := failureComment([]failedJob{{"a", "abc", "url-a", "test a"}}, "org", "repo", 5, "@k8s-bot")
+	other := failureComment([]failedJob{{"a", "abc", "url-a", "test a"}, {"b", "abc", "url-b", "test b"}}, "org", "repo", 5, "@k8s-bot")
+	ghc := &clobberingClient{
+		FakeClient: &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{
+				5: {{ID: 1, Body: first, User: github.User{Login: "k8s-ci-robot"}}},
+			},
+			IssueCommentID: 2,
+		},
+		other: other,
+	}
+	cl := testClient{
+		Job:          jobs.JenkinsJob{Context: "c", RerunCommand: "test c"},
+		PRNumber:     5,
+		PullSHA:      "abc",
+		Report:       true,
+		GitHubClient: ghc,
+	}
+	cl.tryUpdateFailureComment("url-c", true)
+	ics := ghc.IssueComments[5]
+	if len(ics) != 1 {
+		t.Fatalf("Expected one summary, got %+v", ics)
+	}
+	var contexts []string
+	for _, r := range parseFailureComment(ics[0].Body) {
+		contexts = append(contexts, r.Context)
+	}
+	if strings.Join(contexts, ",") != "a,b,c" {
+		t.Errorf("Expected rows for a, b and c, got %v", contexts)
+	}
 }
 
 func TestGuberURL(t *testing.T) {
@@ -125,4 +263,97 @@ func TestGuberURL(t *testing.T) {
 			t.Errorf("Gubernator URL wrong. Got %s, expected %s", actual, tc.ExpectedURL)
 		}
 	}
+	c := &testClient{
+		Job:       jobs.JenkinsJob{Name: "j"},
+		Type:      "postsubmit",
+		RepoOwner: "kubernetes",
+		RepoName:  "kubernetes",
+	}
+	if actual, expected := c.guberURL("1"), guberLogsBase+"/j/1/"; actual != expected {
+		t.Errorf("Gubernator URL wrong for postsubmit. Got %s, expected %s", actual, expected)
+	}
+	c = &testClient{
+		Job:  jobs.JenkinsJob{Name: "j"},
+		Type: "periodic",
+	}
+	if actual, expected := c.guberURL("1"), guberLogsBase+"/j/1/"; actual != expected {
+		t.Errorf("Gubernator URL wrong for periodic. Got %s, expected %s", actual, expected)
+	}
+}
+
+func TestGetLabels(t *testing.T) {
+	f, err := ioutil.TempFile("", "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("jenkins-job-name=\"j\"\njob-name=\"abc-123\"\ntype=\"postsubmit\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	labels, err := getLabels(f.Name())
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if labels["job-name"] != "abc-123" || labels["type"] != "postsubmit" {
+		t.Errorf("Wrong labels: %v", labels)
+	}
+}
+
+// countingClient counts the calls that every job would otherwise make.
+type countingClient struct {
+	*fakegithub.FakeClient
+	botNames, lists int
+}
+
+func (c *countingClient) BotName() (string, error) {
+	c.botNames++
+	return c.FakeClient.BotName()
+}
+
+func (c *countingClient) ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error) {
+	c.lists++
+	return c.FakeClient.ListIssueComments(owner, repo, number)
+}
+
+func TestPassWithoutSummary(t *testing.T) {
+	ghc := &countingClient{
+		FakeClient: &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{
+				5: {{ID: 1, Body: "looks nice", User: github.User{Login: "someone"}}},
+			},
+		},
+	}
+	cl := testClient{
+		Job:          jobs.JenkinsJob{Context: "c", RerunCommand: "test c"},
+		PRNumber:     5,
+		PullSHA:      "abc",
+		Report:       true,
+		GitHubClient: ghc,
+	}
+	cl.tryUpdateFailureComment("url-c", false)
+	if ghc.botNames != 0 || ghc.lists != 1 {
+		t.Errorf("Expected one listing and no bot name lookup, got %d and %d.", ghc.lists, ghc.botNames)
+	}
+}
+
+func TestFailureCommentRoundTrip(t *testing.T) {
+	rows := []failedJob{
+		{"a | b", "abc", "url-a", "@bot test a|b"},
+		{"c --> d", "def", "url-c", "@bot test c"},
+	}
+	body := failureComment(rows, "org", "repo", 5, "@bot")
+	if !strings.Contains(body, "say `@bot retest`") {
+		t.Errorf("Expected the retest command to use the prefix, got %s", body)
+	}
+	if !strings.Contains(body, "a \\| b | abc |") {
+		t.Errorf("Expected the pipe in the context to be escaped, got %s", body)
+	}
+	got := parseFailureComment(body)
+	if len(got) != 2 || got[0] != rows[0] || got[1] != rows[1] {
+		t.Errorf("Expected %+v back, got %+v", rows, got)
+	}
+	if body := failureComment(rows, "org", "repo", 5, ""); !strings.Contains(body, "say `/retest`") {
+		t.Errorf("Expected /retest without a prefix, got %s", body)
+	}
 }
@@ -0,0 +1,268 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// The bot's failure summary ends with its rows as JSON in an HTML comment
+// that starts with this, so that we can find the summary again and read the
+// rows back without parsing the table.
+const failureMarker = "<!-- test report: "
+
+// failedJob is one row in the failure summary.
+type failedJob struct {
+	Context      string `json:"context"`
+	SHA          string `json:"sha"`
+	URL          string `json:"url"`
+	RerunCommand string `json:"rerun_command"`
+}
+
+// How many times to try to get our row into the failure summary, and how
+// long to wait at most before trying again.
+const (
+	maxSummaryAttempts = 5
+	maxSummaryBackoff  = 2 * time.Second
+)
+
+var summarySleep = time.Sleep
+
+// tryUpdateFailureComment adds the job to the PR's failure summary if it
+// failed and removes it if it passed. There is at most one summary per PR,
+// which we delete once every job in it has passed. Line runs one job per pod,
+// so the summary itself is the only record of the other jobs.
+//
+// Jobs that finish together edit the same summary, and GitHub can't make an
+// edit conditional on what we read. So after each write we read the summary
+// again, and start over if another job's write replaced ours.
+func (c *testClient) tryUpdateFailureComment(url string, failed bool) {
+	if !c.Report {
+		return
+	}
+	l := logrus.WithFields(fields(c))
+	ics, err := c.GitHubClient.ListIssueComments(c.RepoOwner, c.RepoName, c.PRNumber)
+	if err != nil {
+		l.WithError(err).Error("Error listing issue comments.")
+		return
+	}
+	// Most jobs pass on PRs with nothing to clean up.
+	if !failed && !c.hasFailureComment(ics) {
+		return
+	}
+	botName, err := c.GitHubClient.BotName()
+	if err != nil {
+		l.WithError(err).Error("Error getting bot name.")
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		if err := c.updateFailureComment(ics, botName, url, failed); err != nil {
+			l.WithError(err).Error("Error updating failure summary.")
+			return
+		}
+		ics, err = c.GitHubClient.ListIssueComments(c.RepoOwner, c.RepoName, c.PRNumber)
+		if err != nil {
+			l.WithError(err).Error("Error listing issue comments.")
+			return
+		}
+		if c.summaryIsCurrent(ics, botName, url, failed) {
+			return
+		}
+		if attempt == maxSummaryAttempts {
+			break
+		}
+		l.Info("Another job replaced the failure summary, trying again.")
+		summarySleep(time.Duration(rand.Int63n(int64(maxSummaryBackoff))))
+	}
+	l.Error("Gave up updating the failure summary.")
+}
+
+// hasFailureComment returns true if there may be a summary, or an old style
+// comment about this job, to update.
+func (c *testClient) hasFailureComment(ics []github.IssueComment) bool {
+	for _, ic := range ics {
+		if strings.Contains(ic.Body, failureMarker) || strings.HasPrefix(ic.Body, c.Job.Context) {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryIsCurrent returns true if the summary lists the job with this
+// result if it failed, or doesn't list the job if it passed.
+func (c *testClient) summaryIsCurrent(ics []github.IssueComment, botName, url string, failed bool) bool {
+	for _, ic := range ics {
+		if ic.User.Login != botName || !strings.Contains(ic.Body, failureMarker) {
+			continue
+		}
+		for _, j := range parseFailureComment(ic.Body) {
+			if j.Context == c.Job.Context {
+				return failed && j.SHA == c.PullSHA && j.URL == url
+			}
+		}
+		return !failed
+	}
+	return !failed
+}
+
+func (c *testClient) updateFailureComment(ics []github.IssueComment, botName, url string, failed bool) error {
+	var summaryID int
+	var summaryBody string
+	var rows []failedJob
+	for _, ic := range ics {
+		if ic.User.Login != botName {
+			continue
+		}
+		var del bool
+		if strings.Contains(ic.Body, failureMarker) {
+			if summaryBody == "" {
+				summaryID = ic.ID
+				summaryBody = ic.Body
+				rows = parseFailureComment(ic.Body)
+				continue
+			}
+			// Two jobs raced to create the summary. Keep the first one and
+			// move anything else into it.
+			rows = mergeFailedJobs(rows, parseFailureComment(ic.Body))
+			del = true
+		} else if strings.HasPrefix(ic.Body, c.Job.Context) {
+			// We used to comment once per failed job, starting with its
+			// context.
+			del = true
+		}
+		if del {
+			if err := c.GitHubClient.DeleteComment(c.RepoOwner, c.RepoName, ic.ID); err != nil {
+				return fmt.Errorf("error deleting comment: %v", err)
+			}
+		}
+	}
+
+	var updated []failedJob
+	for _, j := range rows {
+		if j.Context != c.Job.Context {
+			updated = append(updated, j)
+		}
+	}
+	if failed {
+		updated = append(updated, failedJob{
+			Context:      c.Job.Context,
+			SHA:          c.PullSHA,
+			URL:          url,
+			RerunCommand: c.Job.RerunCommand,
+		})
+	}
+
+	if len(updated) == 0 {
+		if summaryBody != "" {
+			if err := c.GitHubClient.DeleteComment(c.RepoOwner, c.RepoName, summaryID); err != nil {
+				return fmt.Errorf("error deleting comment: %v", err)
+			}
+		}
+		return nil
+	}
+	body := failureComment(updated, c.RepoOwner, c.RepoName, c.PRNumber, c.CommandPrefix)
+	if summaryBody == "" {
+		if err := c.GitHubClient.CreateComment(c.RepoOwner, c.RepoName, c.PRNumber, body); err != nil {
+			return fmt.Errorf("error creating comment: %v", err)
+		}
+	} else if body != summaryBody {
+		if err := c.GitHubClient.EditComment(c.RepoOwner, c.RepoName, summaryID, body); err != nil {
+			return fmt.Errorf("error editing comment: %v", err)
+		}
+	}
+	return nil
+}
+
+// mergeFailedJobs adds the rows in more whose contexts are not yet in rows.
+func mergeFailedJobs(rows, more []failedJob) []failedJob {
+	contexts := map[string]bool{}
+	for _, j := range rows {
+		contexts[j.Context] = true
+	}
+	for _, j := range more {
+		if !contexts[j.Context] {
+			contexts[j.Context] = true
+			rows = append(rows, j)
+		}
+	}
+	return rows
+}
+
+func parseFailureComment(body string) []failedJob {
+	i := strings.Index(body, failureMarker)
+	if i == -1 {
+		return nil
+	}
+	state := body[i+len(failureMarker):]
+	if j := strings.Index(state, " -->"); j != -1 {
+		state = state[:j]
+	}
+	var rows []failedJob
+	if err := json.Unmarshal([]byte(state), &rows); err != nil {
+		logrus.WithError(err).Warning("Error reading the failure summary's rows.")
+		return nil
+	}
+	return rows
+}
+
+// TODO: Fix pr-test link for non-kubernetes repos.
+func failureComment(rows []failedJob, org, repo string, number int, prefix string) string {
+	sorted := append([]failedJob{}, rows...)
+	sort.Sort(byContext(sorted))
+
+	retest := "/retest"
+	if prefix != "" {
+		retest = prefix + " retest"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "The following tests **failed**, say `%s` to rerun them all:\n", retest)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Test name | Commit | Details | Rerun command")
+	fmt.Fprintln(&b, "--- | --- | --- | ---")
+	for _, j := range sorted {
+		fmt.Fprintf(&b, "%s | %s | [link](%s) | `%s`\n", tableCell(j.Context), tableCell(j.SHA), tableCell(j.URL), tableCell(j.RerunCommand))
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "[Full PR test history](http://pr-test.k8s.io/%d). Please help us cut down flakes by linking to an [open flake issue](https://github.com/%s/%s/issues?q=is:issue+label:kind/flake+is:open) when you hit one in your PR.\n", number, org, repo)
+	fmt.Fprintln(&b)
+	// The JSON encoder escapes "<" and ">", so nothing in the rows can end
+	// the HTML comment early.
+	state, _ := json.Marshal(sorted)
+	fmt.Fprintf(&b, "%s%s -->", failureMarker, state)
+	return b.String()
+}
+
+// tableCell escapes pipes, which would otherwise start a new column.
+func tableCell(s string) string {
+	return strings.Replace(s, "|", "\\|", -1)
+}
+
+type byContext []failedJob
+
+func (a byContext) Len() int           { return len(a) }
+func (a byContext) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byContext) Less(i, j int) bool { return a[i].Context < a[j].Context }
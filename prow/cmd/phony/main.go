@@ -23,6 +23,7 @@ import (
 	"net/http"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
 
 	"k8s.io/test-infra/prow/github"
 )
@@ -53,6 +54,7 @@ func main() {
 		logrus.WithError(err).Fatal("Could not make request.")
 	}
 	req.Header.Set("X-GitHub-Event", *event)
+	req.Header.Set("X-GitHub-Delivery", uuid.NewV4().String())
 	req.Header.Set("X-Hub-Signature", github.PayloadSignature(body, []byte(*hmac)))
 
 	c := &http.Client{}
@@ -29,6 +29,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/jobs"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/line"
@@ -40,7 +41,7 @@ var (
 	orgName        = flag.String("org", "kubernetes", "Org name")
 	repoName       = flag.String("repo", "kubernetes", "Repo name")
 	logJson        = flag.Bool("log-json", false, "output log in JSON format")
-	jobConfigs     = flag.String("job-config", "/etc/jobs/jobs", "Where the job-config configmap is mounted.")
+	configPath     = flag.String("config-path", "/etc/config/config", "Path to the prow config file.")
 	maxBatchSize   = flag.Int("batch-size", 5, "Maximum batch size")
 )
 
@@ -217,8 +218,9 @@ func main() {
 	defer splicer.cleanup()
 
 	ja := &jobs.JobAgent{}
-	if err := ja.Start(*jobConfigs); err != nil {
-		log.WithError(err).Fatal("Could not start job agent.")
+	ca := &config.Agent{JobAgent: ja}
+	if err := ca.Start(*configPath); err != nil {
+		log.WithError(err).Fatal("Could not start config agent.")
 	}
 
 	kc, err := kube.NewClientInCluster("default")
@@ -284,7 +286,7 @@ func main() {
 				// TODO(rmmh): read required contexts from submit queue
 				continue
 			}
-			if job.AlwaysRun {
+			if job.AlwaysRun && job.RunsAgainstBranch(buildReq.BaseRef) {
 				if succeeded[buildReq.GetRefs()+job.Context] {
 					log.Infof("not triggering job %v (already succeeded previously)", job.Name)
 					continue
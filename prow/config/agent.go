@@ -0,0 +1,218 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/fsnotify/fsnotify"
+
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// Agent keeps the job and plugin agents up to date with the config file.
+// Either agent may be nil.
+type Agent struct {
+	JobAgent    *jobs.JobAgent
+	PluginAgent *plugins.PluginAgent
+
+	mut     sync.Mutex
+	path    string
+	hash    string
+	loaded  time.Time
+	lastErr error
+}
+
+// Status describes the config that the agents are using. It is served as
+// JSON so that we can confirm that a change took effect.
+type Status struct {
+	Path string `json:"path"`
+	// SHA256 of the file that was loaded.
+	Hash   string    `json:"sha256"`
+	Loaded time.Time `json:"loaded"`
+	// Why the most recent change to the file was rejected, if it was.
+	Error string `json:"error,omitempty"`
+}
+
+// Load loads the config into the agents. If it is invalid then neither agent
+// changes. Loading a file identical to the current one does nothing.
+func (a *Agent) Load(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return a.failed(err)
+	}
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	a.mut.Lock()
+	same := path == a.path && hash == a.hash
+	a.mut.Unlock()
+	if same {
+		return nil
+	}
+	c, err := Parse(b)
+	if err != nil {
+		return a.failed(err)
+	}
+	// Check everything that either agent would reject before changing
+	// either of them. Validate covers both the job and the plugin config.
+	errs := c.Validate()
+	if a.PluginAgent != nil {
+		errs = append(errs, c.UnknownPlugins()...)
+	}
+	if len(errs) > 0 {
+		sort.Sort(Errors(errs))
+		return a.failed(Errors(errs))
+	}
+	if a.JobAgent != nil {
+		if err := a.JobAgent.SetConfig(c.JobConfig); err != nil {
+			return a.failed(err)
+		}
+	}
+	if a.PluginAgent != nil {
+		if err := a.PluginAgent.Set(&c.Configuration); err != nil {
+			return a.failed(err)
+		}
+	}
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.path = path
+	a.hash = hash
+	a.loaded = time.Now()
+	a.lastErr = nil
+	return nil
+}
+
+func (a *Agent) failed(err error) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.lastErr = err
+	return err
+}
+
+// Status returns the hash and load time of the current config.
+func (a *Agent) Status() Status {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	s := Status{
+		Path:   a.path,
+		Hash:   a.hash,
+		Loaded: a.loaded,
+	}
+	if a.lastErr != nil {
+		s.Error = a.lastErr.Error()
+	}
+	return s
+}
+
+// ServeHTTP serves the Status as JSON.
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(a.Status())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// How long to wait before trying to watch the config again if watching
+// fails.
+const watchRetry = time.Minute
+
+// Start loads the config and then reloads it whenever its directory changes.
+// We watch the directory rather than the file because Kubernetes updates a
+// ConfigMap volume by swapping a symlink. If a reload fails then the agents
+// keep the last good config.
+func (a *Agent) Start(path string) error {
+	// Watch before loading so that we can't miss a change in between.
+	w, werr := newWatcher(filepath.Dir(path))
+	if err := a.Load(path); err != nil {
+		if w != nil {
+			w.Close()
+		}
+		return err
+	}
+	go func() {
+		for {
+			if werr == nil {
+				werr = a.watch(w, path)
+				w.Close()
+			}
+			logrus.WithField("path", path).WithError(werr).Error("Error watching config.")
+			time.Sleep(watchRetry)
+			w, werr = newWatcher(filepath.Dir(path))
+			// We may have missed changes while not watching.
+			a.reload(path)
+		}
+	}()
+	return nil
+}
+
+func newWatcher(dir string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// watch reloads the config on every change in its directory until watching
+// fails or the directory itself goes away.
+func (a *Agent) watch(w *fsnotify.Watcher, path string) error {
+	dir := filepath.Dir(path)
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return fmt.Errorf("watcher closed")
+			}
+			if e.Name == dir && e.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				return fmt.Errorf("config directory went away")
+			}
+			a.reload(path)
+		case err := <-w.Errors:
+			return err
+		}
+	}
+}
+
+func (a *Agent) reload(path string) {
+	old := a.Status().Hash
+	if err := a.Load(path); err != nil {
+		logrus.WithField("path", path).WithError(err).Error("Error loading config.")
+	} else if hash := a.Status().Hash; hash != old {
+		logrus.WithFields(logrus.Fields{
+			"path":   path,
+			"sha256": hash,
+		}).Info("Loaded config.")
+	}
+}
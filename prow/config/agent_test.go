@@ -0,0 +1,187 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const jobConfig = `
+version: 1
+presubmits:
+  org/repo:
+  - name: %s
+    context: ctx
+    trigger: "@bot job"
+    rerun_command: "@bot job"
+`
+
+// update changes the config the way that Kubernetes updates a ConfigMap
+// volume: it writes a new directory and then swaps the ..data symlink.
+func update(t *testing.T, dir, version, config string) {
+	if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, version, "config"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(version, filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor polls until the condition holds or ten seconds pass.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func jobNames(ja *jobs.JobAgent) []string {
+	var names []string
+	for _, j := range ja.AllJobs("org/repo") {
+		names = append(names, j.Name)
+	}
+	return names
+}
+
+func TestAgentWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	update(t, dir, "..1", fmt.Sprintf(jobConfig, "first"))
+	path := filepath.Join(dir, "config")
+	if err := os.Symlink(filepath.Join("..data", "config"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	ja := &jobs.JobAgent{}
+	a := &Agent{JobAgent: ja}
+	if err := a.Start(path); err != nil {
+		t.Fatalf("Didn't expect error starting agent: %v", err)
+	}
+	first := a.Status()
+	if first.Hash == "" || first.Loaded.IsZero() || first.Error != "" {
+		t.Errorf("Bad status after start: %+v", first)
+	}
+	if names := jobNames(ja); len(names) != 1 || names[0] != "first" {
+		t.Errorf("Expected job first, got %v", names)
+	}
+
+	update(t, dir, "..2", fmt.Sprintf(jobConfig, "second"))
+	if !waitFor(func() bool { return a.Status().Hash != first.Hash }) {
+		t.Fatalf("Config was not reloaded after it changed.")
+	}
+	second := a.Status()
+	if names := jobNames(ja); len(names) != 1 || names[0] != "second" {
+		t.Errorf("Expected job second, got %v", names)
+	}
+
+	// A bad config is rejected and we keep the last good one.
+	update(t, dir, "..3", "version: 1\nbogus: true\n")
+	if !waitFor(func() bool { return a.Status().Error != "" }) {
+		t.Fatalf("Bad config was not reported.")
+	}
+	if s := a.Status(); s.Hash != second.Hash || !s.Loaded.Equal(second.Loaded) {
+		t.Errorf("Expected status to keep %+v, got %+v", second, s)
+	}
+	if names := jobNames(ja); len(names) != 1 || names[0] != "second" {
+		t.Errorf("Expected to keep job second, got %v", names)
+	}
+}
+
+func TestAgentServeHTTP(t *testing.T) {
+	f, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(fmt.Sprintf(jobConfig, "job")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	a := &Agent{JobAgent: &jobs.JobAgent{}}
+	if err := a.Load(f.Name()); err != nil {
+		t.Fatalf("Didn't expect error loading config: %v", err)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/config", nil))
+	var s Status
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatalf("Could not unmarshal status %s: %v", w.Body.String(), err)
+	}
+	if s.Path != f.Name() || len(s.Hash) != 64 || s.Loaded.IsZero() || s.Error != "" {
+		t.Errorf("Bad status: %+v", s)
+	}
+}
+
+// A config that the plugin agent would reject must not reach the job agent
+// either.
+func TestAgentLoadIsAllOrNothing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(jobConfig, "first")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ja := &jobs.JobAgent{}
+	a := &Agent{JobAgent: ja, PluginAgent: &plugins.PluginAgent{}}
+	if err := a.Load(path); err != nil {
+		t.Fatalf("Didn't expect error loading config: %v", err)
+	}
+
+	bad := fmt.Sprintf(jobConfig, "second") + `plugins:
+  org: [no-such-plugin]
+settings:
+  org:
+    trusted_orgs: [org]
+    bot_logins: [bot]
+    command_prefix: "@bot"
+`
+	if err := ioutil.WriteFile(path, []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Load(path); err == nil {
+		t.Fatalf("Expected an error for an unknown plugin.")
+	}
+	if names := jobNames(ja); len(names) != 1 || names[0] != "first" {
+		t.Errorf("Expected to keep job first, got %v", names)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the single prow config file that holds both the jobs
+// and the plugin configuration, and checks it for mistakes.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// Version is the only config version that we understand. Bump it when
+// making incompatible changes to the format.
+const Version = 1
+
+// Config is the format of the prow config file.
+type Config struct {
+	Version int `json:"version"`
+	jobs.JobConfig
+	plugins.Configuration
+
+	// Fields in the file that don't correspond to anything in Config.
+	unknown []error
+}
+
+// Errors is every problem found with a config.
+type Errors []error
+
+func (e Errors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e Errors) Len() int           { return len(e) }
+func (e Errors) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e Errors) Less(i, j int) bool { return e[i].Error() < e[j].Error() }
+
+// Parse unmarshals the config and notes any fields that we don't recognize.
+// It only returns an error if the file isn't YAML. Call Validate to find
+// other problems.
+func Parse(b []byte) (*Config, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	c.unknown = unknownFields(raw, reflect.TypeOf(*c), "")
+	return c, nil
+}
+
+// Validate returns every problem with the config other than unknown plugins,
+// which only binaries that import the plugins can check.
+func (c *Config) Validate() []error {
+	errs := append([]error{}, c.unknown...)
+	if c.Version != Version {
+		errs = append(errs, fmt.Errorf("unsupported config version %d, expected %d", c.Version, Version))
+	}
+	errs = append(errs, c.JobConfig.Validate()...)
+	errs = append(errs, c.Configuration.Validate()...)
+	for repo, js := range c.Presubmits {
+		parts := strings.Split(repo, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errs = append(errs, fmt.Errorf("presubmits key %s is not of the form org/repo", repo))
+			continue
+		}
+		prefix := c.SettingsFor(parts[0], parts[1]).CommandPrefix
+		if prefix == "" {
+			continue
+		}
+		// Jobs that run automatically also run on "test this".
+		testAll := prefix + " test this"
+		for _, j := range js {
+			// Line shows the rerun command to users, so it has to be
+			// addressed to this repo's bot.
+			if j.RerunCommand != "" && !strings.HasPrefix(j.RerunCommand, prefix+" ") {
+				errs = append(errs, fmt.Errorf("rerun command for %s does not start with %q", j.Name, prefix))
+			}
+			if !j.AlwaysRun && j.RunIfChanged == "" {
+				continue
+			}
+			if j.Trigger == "" {
+				continue
+			}
+			if ok, err := regexp.MatchString(j.Trigger, testAll); err == nil && !ok {
+				errs = append(errs, fmt.Errorf("trigger for %s does not match %q", j.Name, testAll))
+			}
+		}
+	}
+	sort.Sort(Errors(errs))
+	return errs
+}
+
+// Load reads and validates the config. If anything is wrong it returns
+// Errors.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return load(b)
+}
+
+func load(b []byte) (*Config, error) {
+	c, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	if errs := c.Validate(); len(errs) > 0 {
+		return nil, Errors(errs)
+	}
+	return c, nil
+}
+
+// unknownFields walks the raw config alongside the type that it unmarshals
+// into and returns an error for each key that the type doesn't have.
+func unknownFields(v interface{}, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var errs []error
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for k, e := range v {
+				errs = append(errs, unknownFields(e, t.Elem(), join(path, k))...)
+			}
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for k, e := range v {
+				ft, ok := fields[k]
+				if !ok {
+					errs = append(errs, fmt.Errorf("unknown field %s", join(path, k)))
+					continue
+				}
+				errs = append(errs, unknownFields(e, ft, join(path, k))...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, e := range v {
+				errs = append(errs, unknownFields(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// jsonFields returns the JSON keys of a struct along with their types,
+// including those of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, ft := range jsonFields(f.Type) {
+				fields[k] = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	_ "k8s.io/test-infra/prow/plugins/all"
+)
+
+// Make sure that our config is valid and that every job has a script.
+func TestConfig(t *testing.T) {
+	c, err := Load("../config.yaml")
+	if err != nil {
+		t.Fatalf("Could not load config: %v", err)
+	}
+	for _, err := range c.UnknownPlugins() {
+		t.Errorf("Invalid plugins: %v", err)
+	}
+	if len(c.Presubmits) == 0 {
+		t.Fatalf("No presubmits found in config.yaml.")
+	}
+	for _, js := range c.Presubmits {
+		for _, job := range js {
+			// Ensure that jobs have a shell script of the same name.
+			if s, err := os.Stat(fmt.Sprintf("../../jobs/%s.sh", job.Name)); err != nil {
+				t.Errorf("Cannot find test-infra/jobs/%s.sh", job.Name)
+			} else {
+				if s.Mode()&0111 == 0 {
+					t.Errorf("Not executable: %s.sh (%o)", job.Name, s.Mode()&0777)
+				}
+				if s.Mode()&0444 == 0 {
+					t.Errorf("Not readable: %s.sh (%o)", job.Name, s.Mode()&0777)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "valid",
+			config: `
+version: 1
+presubmits:
+  org/repo:
+  - name: job
+    always_run: true
+    context: ctx
+    trigger: "@bot (job )?test this"
+    rerun_command: "@bot job test this"
+    spec:
+      containers:
+      - image: alpine
+settings:
+  org:
+    trusted_orgs: [org]
+    bot_logins: [bot]
+    command_prefix: "@bot"
+`,
+		},
+		{
+			name: "unknown fields",
+			config: `
+version: 1
+presubmits:
+  org/repo:
+  - name: job
+    context: ctx
+    trigger: "@bot job"
+    rerun_command: "@bot job"
+    alwaysrun: true
+    spec:
+      containers:
+      - image: alpine
+        imag: alpine
+periodic: []
+`,
+			expected: []string{
+				"unknown field periodic",
+				"unknown field presubmits.org/repo[0].alwaysrun",
+				"unknown field presubmits.org/repo[0].spec.containers[0].imag",
+			},
+		},
+		{
+			name: "wrong version and bad repo",
+			config: `
+version: 2
+presubmits:
+  repo:
+  - name: job
+    context: ctx
+    trigger: "job"
+    rerun_command: "job"
+`,
+			expected: []string{
+				"presubmits key repo is not of the form org/repo",
+				"unsupported config version 2, expected 1",
+			},
+		},
+		{
+			name: "automatic job does not run on test this",
+			config: `
+version: 1
+presubmits:
+  org/repo:
+  - name: job
+    run_if_changed: "^docs/"
+    context: ctx
+    trigger: "@bot job"
+    rerun_command: "@bot job"
+settings:
+  org/repo:
+    trusted_orgs: [org]
+    bot_logins: [bot]
+    command_prefix: "@bot"
+`,
+			expected: []string{
+				`trigger for job does not match "@bot test this"`,
+			},
+		},
+		{
+			name: "rerun command for another bot",
+			config: `
+version: 1
+presubmits:
+  org/repo:
+  - name: job
+    context: ctx
+    trigger: "@other-bot job"
+    rerun_command: "@other-bot job"
+settings:
+  org:
+    trusted_orgs: [org]
+    bot_logins: [bot]
+    command_prefix: "@bot"
+`,
+			expected: []string{
+				`rerun command for job does not start with "@bot"`,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		c, err := Parse([]byte(tc.config))
+		if err != nil {
+			t.Errorf("For case %s, didn't expect error parsing: %v", tc.name, err)
+			continue
+		}
+		errs := c.Validate()
+		if len(errs) != len(tc.expected) {
+			t.Errorf("For case %s, expected errors %v, got %v", tc.name, tc.expected, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tc.expected[i] {
+				t.Errorf("For case %s, expected error %q, got %q", tc.name, tc.expected[i], err)
+			}
+		}
+	}
+}
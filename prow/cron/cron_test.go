@@ -0,0 +1,115 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	var testcases = []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+	}
+	for _, tc := range testcases {
+		if _, err := Parse(tc); err == nil {
+			t.Errorf("Expected error parsing %q.", tc)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday.
+	start := time.Date(2017, time.February, 1, 10, 30, 0, 0, time.UTC)
+	var testcases = []struct {
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			spec:     "* * * * *",
+			from:     start,
+			expected: time.Date(2017, time.February, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			spec:     "*/15 * * * *",
+			from:     start,
+			expected: time.Date(2017, time.February, 1, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 * * * *",
+			from:     start,
+			expected: time.Date(2017, time.February, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 9 * * *",
+			from:     start,
+			expected: time.Date(2017, time.February, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 9-17/4 * * MON-FRI",
+			from:     time.Date(2017, time.February, 3, 17, 0, 0, 0, time.UTC),
+			expected: time.Date(2017, time.February, 6, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 0 * * 7",
+			from:     start,
+			expected: time.Date(2017, time.February, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "30 2 1 jan,jul *",
+			from:     start,
+			expected: time.Date(2017, time.July, 1, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			// Either the 15th or a Monday.
+			spec:     "0 0 15 * 1",
+			from:     start,
+			expected: time.Date(2017, time.February, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 0 29 2 *",
+			from:     start,
+			expected: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 30 2 *",
+			from: start,
+		},
+	}
+	for _, tc := range testcases {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("Error parsing %q: %v", tc.spec, err)
+			continue
+		}
+		if actual := s.Next(tc.from); !actual.Equal(tc.expected) {
+			t.Errorf("For %q from %v, expected %v, got %v.", tc.spec, tc.from, tc.expected, actual)
+		}
+	}
+}
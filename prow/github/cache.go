@@ -0,0 +1,208 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Keep at most this many answers and this many responses, forgetting the
+// least recently used first, so that the caches don't grow without bound.
+const maxCachedResponses = 5000
+
+var timeNow = time.Now
+
+// conditionalPaths match the GETs whose responses we keep for conditional
+// requests: team and permission lookups. They are small and asked for over
+// and over. Other responses, such as file contents and PR changes, can be
+// large and are rarely asked for twice. Org membership lookups answer 204 or
+// 404 with no ETag, so IsMember only keeps its answer for CacheTTL.
+var conditionalPaths = []*regexp.Regexp{
+	regexp.MustCompile(`^/orgs/[^/]+/teams(\?.*)?$`),
+	regexp.MustCompile(`^/teams/\d+/memberships/[^/]+$`),
+	regexp.MustCompile(`^/repos/[^/]+/[^/]+/collaborators/[^/]+/permission$`),
+}
+
+// conditional returns true if we keep responses to the request so that we
+// can repeat it with the ETag.
+func (c *Client) conditional(method, path string) bool {
+	if c.CacheTTL <= 0 || method != http.MethodGet {
+		return false
+	}
+	p := strings.TrimPrefix(path, c.base)
+	for _, re := range conditionalPaths {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// lru holds up to max values by key, and drops the least recently used one
+// to make room for another.
+type lru struct {
+	max int
+	// Most recently used at the front.
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	value interface{}
+}
+
+func newLRU(max int) *lru {
+	return &lru{max: max, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (l *lru) get(key string) (interface{}, bool) {
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*lruItem).value, true
+}
+
+func (l *lru) add(key string, value interface{}) {
+	if e, ok := l.items[key]; ok {
+		e.Value.(*lruItem).value = value
+		l.order.MoveToFront(e)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruItem{key: key, value: value})
+	for l.order.Len() > l.max {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+// cacheEntry is an answer from GitHub and when it goes stale.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// cachedResponse is a GET response that GitHub can tell us is still current
+// by answering 304 to a request with its ETag.
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// cached returns the answer stored under the key if it hasn't gone stale.
+func (c *Client) cached(key string) (interface{}, bool) {
+	if c.CacheTTL <= 0 {
+		return nil, false
+	}
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+	if c.cache == nil {
+		return nil, false
+	}
+	v, ok := c.cache.get(key)
+	if !ok {
+		return nil, false
+	}
+	e := v.(cacheEntry)
+	if timeNow().After(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// setCached stores the answer under the key for CacheTTL.
+func (c *Client) setCached(key string, value interface{}) {
+	if c.CacheTTL <= 0 {
+		return
+	}
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+	if c.cache == nil {
+		c.cache = newLRU(maxCachedResponses)
+	}
+	c.cache.add(key, cacheEntry{value: value, expires: timeNow().Add(c.CacheTTL)})
+}
+
+// cachedResponse returns the last response to a GET of the path, or nil.
+func (c *Client) cachedResponse(method, path string) *cachedResponse {
+	if !c.conditional(method, path) {
+		return nil
+	}
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+	if c.responses == nil {
+		return nil
+	}
+	if cr, ok := c.responses.get(path); ok {
+		return cr.(*cachedResponse)
+	}
+	return nil
+}
+
+// updateResponses answers a 304 with the cached response, and remembers
+// successful responses to conditional GETs that have an ETag. GitHub doesn't
+// count 304s against the rate limit.
+func (c *Client) updateResponses(method, path string, resp *http.Response, cr *cachedResponse) (*http.Response, error) {
+	if !c.conditional(method, path) {
+		return resp, nil
+	}
+	if resp.StatusCode == http.StatusNotModified && cr != nil {
+		resp.Body.Close()
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      resp.Proto,
+			ProtoMajor: resp.ProtoMajor,
+			ProtoMinor: resp.ProtoMinor,
+			Header:     cr.header,
+			Body:       ioutil.NopCloser(bytes.NewReader(cr.body)),
+			Request:    resp.Request,
+		}, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+	if c.responses == nil {
+		c.responses = newLRU(maxCachedResponses)
+	}
+	c.responses.add(path, &cachedResponse{etag: etag, header: resp.Header, body: b})
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachedLookups(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/orgs/k8s/members/person":
+			http.Error(w, "204 No Content", http.StatusNoContent)
+		case "/teams/5/memberships/person":
+			fmt.Fprint(w, `{"state": "active"}`)
+		case "/repos/k8s/kuber/collaborators/person/permission":
+			fmt.Fprint(w, `{"permission": "write"}`)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	c.CacheTTL = time.Minute
+	lookup := func() {
+		if m, err := c.IsMember("k8s", "person"); err != nil || !m {
+			t.Errorf("Expected member and no error, got %t and %v.", m, err)
+		}
+		if m, err := c.IsTeamMember(5, "person"); err != nil || !m {
+			t.Errorf("Expected team member and no error, got %t and %v.", m, err)
+		}
+		if p, err := c.CollaboratorPermission("k8s", "kuber", "person"); err != nil || p != "write" {
+			t.Errorf("Expected write permission and no error, got %s and %v.", p, err)
+		}
+	}
+	lookup()
+	lookup()
+	if requests != 3 {
+		t.Errorf("Expected 3 requests while cached, got %d.", requests)
+	}
+	now = now.Add(2 * time.Minute)
+	lookup()
+	if requests != 6 {
+		t.Errorf("Expected 6 requests after the cache went stale, got %d.", requests)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	var etags []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etags = append(etags, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"teams"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"teams"`)
+		fmt.Fprint(w, `[{"id": 1, "slug": "maintainers"}]`)
+	}))
+	defer ts.Close()
+	var testcases = []struct {
+		name     string
+		ttl      time.Duration
+		expected []string
+	}{
+		{
+			name:     "cache",
+			ttl:      time.Minute,
+			expected: []string{"", `"teams"`, `"teams"`},
+		},
+		{
+			name:     "no cache",
+			expected: []string{"", "", ""},
+		},
+	}
+	for _, tc := range testcases {
+		etags = nil
+		c := getClient(ts.URL)
+		c.CacheTTL = tc.ttl
+		for i := 0; i < 3; i++ {
+			teams, err := c.ListTeams("k8s")
+			if err != nil {
+				t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			} else if len(teams) != 1 || teams[0].Slug != "maintainers" {
+				t.Errorf("For case %s, wrong teams: %v", tc.name, teams)
+			}
+		}
+		if fmt.Sprint(etags) != fmt.Sprint(tc.expected) {
+			t.Errorf("For case %s, expected If-None-Match headers %q, got %q.", tc.name, tc.expected, etags)
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	c := &Client{base: "https://api.github.com", CacheTTL: time.Minute}
+	var testcases = []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{http.MethodGet, "/orgs/k8s/members/person", false},
+		{http.MethodGet, "/orgs/k8s/teams?per_page=100", true},
+		{http.MethodGet, "/teams/5/memberships/person", true},
+		{http.MethodGet, "/repos/k8s/kuber/collaborators/person/permission", true},
+		{http.MethodGet, "/repos/k8s/kuber/contents/OWNERS?ref=abc", false},
+		{http.MethodGet, "/repos/k8s/kuber/pulls/5/files?per_page=100", false},
+		{http.MethodPut, "/orgs/k8s/members/person", false},
+	}
+	for _, tc := range testcases {
+		if actual := c.conditional(tc.method, c.base+tc.path); actual != tc.expected {
+			t.Errorf("For %s %s, expected %t, got %t.", tc.method, tc.path, tc.expected, actual)
+		}
+	}
+	c.CacheTTL = 0
+	if c.conditional(http.MethodGet, c.base+"/orgs/k8s/teams?per_page=100") {
+		t.Error("Expected no conditional requests without a cache TTL.")
+	}
+}
+
+func TestRepeatedIsMember(t *testing.T) {
+	requests := map[string]int{}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/orgs/k8s/members/person":
+			w.WriteHeader(http.StatusNoContent)
+		case "/orgs/k8s/members/stranger":
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	c.CacheTTL = time.Minute
+	for i := 0; i < 3; i++ {
+		if m, err := c.IsMember("k8s", "person"); err != nil || !m {
+			t.Errorf("Expected member and no error, got %t and %v.", m, err)
+		}
+		if m, err := c.IsMember("k8s", "stranger"); err != nil || m {
+			t.Errorf("Expected non-member and no error, got %t and %v.", m, err)
+		}
+	}
+	if requests["/orgs/k8s/members/person"] != 1 || requests["/orgs/k8s/members/stranger"] != 1 {
+		t.Errorf("Expected one request for each user, got %v.", requests)
+	}
+	if c.responses != nil && c.responses.len() != 0 {
+		t.Errorf("Expected no responses kept for membership lookups, got %d.", c.responses.len())
+	}
+}
+
+func TestLRU(t *testing.T) {
+	l := newLRU(2)
+	l.add("a", 1)
+	l.add("b", 2)
+	// Using a keeps it over b.
+	if v, ok := l.get("a"); !ok || v != 1 {
+		t.Errorf("Expected 1 for a, got %v.", v)
+	}
+	l.add("c", 3)
+	if _, ok := l.get("b"); ok {
+		t.Error("Expected b to be dropped.")
+	}
+	if _, ok := l.get("a"); !ok {
+		t.Error("Expected a to be kept.")
+	}
+	if v, ok := l.get("c"); !ok || v != 3 {
+		t.Errorf("Expected 3 for c, got %v.", v)
+	}
+	if l.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d.", l.len())
+	}
+}
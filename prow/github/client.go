@@ -18,14 +18,19 @@ package github
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Logger interface {
@@ -35,12 +40,27 @@ type Logger interface {
 type Client struct {
 	// If Logger is non-nil, log all method calls with it.
 	Logger Logger
+	// If CacheTTL is positive, remember org membership, team membership, and
+	// collaborator permissions for that long. Also remember GET responses and
+	// ask GitHub whether they changed, so that unchanged ones don't use up
+	// API tokens.
+	CacheTTL time.Duration
 
 	client *http.Client
 	token  string
 	base   string
 	dry    bool
 	fake   bool
+
+	// The login of the authenticated user, once we know it.
+	mut     sync.Mutex
+	botName string
+
+	cacheMut sync.Mutex
+	// Answers by what was looked up, such as "member:org/user".
+	cache *lru
+	// GET responses by URL.
+	responses *lru
 }
 
 const (
@@ -93,14 +113,27 @@ func (c *Client) log(methodName string, args ...interface{}) {
 
 var timeSleep = time.Sleep
 
+// RequestCounter counts GitHub API requests. Binaries that serve metrics
+// register it.
+var RequestCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prow_github_requests_total",
+	Help: "GitHub API requests, by method and response code, including retries.",
+}, []string{"method", "code"})
+
 // Retry on transport failures. Retries on 500s and retries after sleep on
 // ratelimit exceeded.
 func (c *Client) request(method, path string, body interface{}) (*http.Response, error) {
 	var resp *http.Response
 	var err error
+	cr := c.cachedResponse(method, path)
 	backoff := initialDelay
 	for retries := 0; retries < maxRetries; retries++ {
-		resp, err = c.doRequest(method, path, body)
+		resp, err = c.doRequest(method, path, body, cr)
+		if err != nil {
+			RequestCounter.WithLabelValues(method, "error").Inc()
+		} else {
+			RequestCounter.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+		}
 		if err == nil {
 			// If we are out of API tokens, sleep first. The X-RateLimit-Reset
 			// header tells us the time at which we can request again.
@@ -131,10 +164,13 @@ func (c *Client) request(method, path string, body interface{}) (*http.Response,
 			backoff *= 2
 		}
 	}
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	return c.updateResponses(method, path, resp, cr)
 }
 
-func (c *Client) doRequest(method, path string, body interface{}) (*http.Response, error) {
+func (c *Client) doRequest(method, path string, body interface{}, cr *cachedResponse) (*http.Response, error) {
 	var buf io.Reader
 	if body != nil {
 		b, err := json.Marshal(body)
@@ -149,6 +185,9 @@ func (c *Client) doRequest(method, path string, body interface{}) (*http.Respons
 	}
 	req.Header.Set("Authorization", "Token "+c.token)
 	req.Header.Add("Accept", "application/vnd.github.v3+json")
+	if cr != nil {
+		req.Header.Set("If-None-Match", cr.etag)
+	}
 	// Disable keep-alive so that we don't get flakes when GitHub closes the
 	// connection prematurely.
 	// https://go-review.googlesource.com/#/c/3210/ fixed it for GET, but not
@@ -157,20 +196,58 @@ func (c *Client) doRequest(method, path string, body interface{}) (*http.Respons
 	return c.client.Do(req)
 }
 
+// BotName returns the login of the user that the token belongs to. It only
+// asks GitHub the first time.
+func (c *Client) BotName() (string, error) {
+	c.log("BotName")
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.botName != "" {
+		return c.botName, nil
+	}
+	if c.fake {
+		return "k8s-ci-robot", nil
+	}
+	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/user", c.base), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var u User
+	if err := json.Unmarshal(b, &u); err != nil {
+		return "", err
+	}
+	c.botName = u.Login
+	return c.botName, nil
+}
+
 // IsMember returns whether or not the user is a member of the org.
 func (c *Client) IsMember(org, user string) (bool, error) {
 	c.log("IsMember", org, user)
 	if c.fake {
 		return true, nil
 	}
+	key := "member:" + org + "/" + user
+	if m, ok := c.cached(key); ok {
+		return m.(bool), nil
+	}
 	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/orgs/%s/members/%s", c.base, org, user), nil)
 	if err != nil {
 		return false, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == 204 {
+		c.setCached(key, true)
 		return true, nil
 	} else if resp.StatusCode == 404 {
+		c.setCached(key, false)
 		return false, nil
 	} else if resp.StatusCode == 302 {
 		return false, fmt.Errorf("requester is not %s org member", org)
@@ -178,6 +255,107 @@ func (c *Client) IsMember(org, user string) (bool, error) {
 	return false, fmt.Errorf("unexpected status: %s", resp.Status)
 }
 
+// ListTeams returns every team in the org.
+func (c *Client) ListTeams(org string) ([]Team, error) {
+	c.log("ListTeams", org)
+	if c.fake {
+		return nil, nil
+	}
+	nextURL := fmt.Sprintf("%s/orgs/%s/teams?per_page=100", c.base, org)
+	var teams []Team
+	for nextURL != "" {
+		resp, err := c.request(http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
+		}
+
+		var ts []Team
+		if err := json.Unmarshal(b, &ts); err != nil {
+			return nil, err
+		}
+		teams = append(teams, ts...)
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return teams, nil
+}
+
+// IsTeamMember returns whether or not the user is an active member of the
+// team. Invitations that haven't been accepted don't count.
+func (c *Client) IsTeamMember(id int, user string) (bool, error) {
+	c.log("IsTeamMember", id, user)
+	if c.fake {
+		return true, nil
+	}
+	key := fmt.Sprintf("team:%d/%s", id, user)
+	if m, ok := c.cached(key); ok {
+		return m.(bool), nil
+	}
+	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/teams/%d/memberships/%s", c.base, id, user), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		c.setCached(key, false)
+		return false, nil
+	} else if resp.StatusCode != 200 {
+		return false, fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	var m struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return false, err
+	}
+	c.setCached(key, m.State == "active")
+	return m.State == "active", nil
+}
+
+// CollaboratorPermission returns the user's permission on the repo, which is
+// one of "admin", "write", "read", or "none".
+func (c *Client) CollaboratorPermission(org, repo, user string) (string, error) {
+	c.log("CollaboratorPermission", org, repo, user)
+	if c.fake {
+		return "write", nil
+	}
+	key := "permission:" + org + "/" + repo + "/" + user
+	if p, ok := c.cached(key); ok {
+		return p.(string), nil
+	}
+	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/repos/%s/%s/collaborators/%s/permission", c.base, org, repo, user), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return "", err
+	}
+	c.setCached(key, p.Permission)
+	return p.Permission, nil
+}
+
 // CreateComment creates a comment on the issue.
 func (c *Client) CreateComment(org, repo string, number int, comment string) error {
 	c.log("CreateComment", org, repo, number, comment)
@@ -231,15 +409,14 @@ func (c *Client) ListIssueComments(org, repo string, number int) ([]IssueComment
 		if err != nil {
 			return nil, err
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
-		}
-
 		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return nil, err
 		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
+		}
 
 		var ics []IssueComment
 		if err := json.Unmarshal(b, &ics); err != nil {
@@ -251,6 +428,27 @@ func (c *Client) ListIssueComments(org, repo string, number int) ([]IssueComment
 	return comments, nil
 }
 
+// EditComment replaces the body of the comment.
+func (c *Client) EditComment(org, repo string, ID int, comment string) error {
+	c.log("EditComment", org, repo, ID, comment)
+	if c.dry {
+		return nil
+	}
+
+	ic := IssueComment{
+		Body: comment,
+	}
+	resp, err := c.request(http.MethodPatch, fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", c.base, org, repo, ID), ic)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	return nil
+}
+
 // GetPullRequest gets a pull request.
 func (c *Client) GetPullRequest(org, repo string, number int) (*PullRequest, error) {
 	c.log("GetPullRequest", org, repo, number)
@@ -276,6 +474,39 @@ func (c *Client) GetPullRequest(org, repo string, number int) (*PullRequest, err
 	return &pr, nil
 }
 
+// GetPullRequestChanges gets a list of files modified in a pull request. This
+// may use more than one API token.
+func (c *Client) GetPullRequestChanges(org, repo string, number int) ([]PullRequestChange, error) {
+	c.log("GetPullRequestChanges", org, repo, number)
+	if c.fake {
+		return nil, nil
+	}
+	nextURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=100", c.base, org, repo, number)
+	var changes []PullRequestChange
+	for nextURL != "" {
+		resp, err := c.request(http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
+		}
+
+		var prcs []PullRequestChange
+		if err := json.Unmarshal(b, &prcs); err != nil {
+			return nil, err
+		}
+		changes = append(changes, prcs...)
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return changes, nil
+}
+
 // CreateStatus creates or updates the status of a commit.
 func (c *Client) CreateStatus(org, repo, ref string, s Status) error {
 	c.log("CreateStatus", org, repo, ref, s)
@@ -294,6 +525,75 @@ func (c *Client) CreateStatus(org, repo, ref string, s Status) error {
 	return nil
 }
 
+// ListLabels returns every label that exists in the repo.
+func (c *Client) ListLabels(org, repo string) ([]Label, error) {
+	c.log("ListLabels", org, repo)
+	if c.fake {
+		return nil, nil
+	}
+	nextURL := fmt.Sprintf("%s/repos/%s/%s/labels?per_page=100", c.base, org, repo)
+	var labels []Label
+	for nextURL != "" {
+		resp, err := c.request(http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
+		}
+
+		var ls []Label
+		if err := json.Unmarshal(b, &ls); err != nil {
+			return nil, err
+		}
+		labels = append(labels, ls...)
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return labels, nil
+}
+
+// GetCombinedStatus returns the latest status for each context of the ref,
+// which may be a SHA or a branch.
+func (c *Client) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error) {
+	c.log("GetCombinedStatus", org, repo, ref)
+	if c.fake {
+		return &CombinedStatus{}, nil
+	}
+	nextURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s/status?per_page=100", c.base, org, repo, ref)
+	var combined *CombinedStatus
+	for nextURL != "" {
+		resp, err := c.request(http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("response not 200: %s", resp.Status)
+		}
+		var cs CombinedStatus
+		if err := json.Unmarshal(b, &cs); err != nil {
+			return nil, err
+		}
+		// Each page repeats the overall state.
+		if combined == nil {
+			combined = &cs
+		} else {
+			combined.Statuses = append(combined.Statuses, cs.Statuses...)
+		}
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return combined, nil
+}
+
 func (c *Client) AddLabel(org, repo string, number int, label string) error {
 	c.log("AddLabel", org, repo, number, label)
 	if c.dry {
@@ -343,7 +643,70 @@ func (c *Client) CloseIssue(org, repo string, number int) error {
 	return nil
 }
 
-// GetRef returns the SHA of the given ref, such as "heads/master".
+// ReopenIssue reopens a closed issue or PR. GitHub refuses to reopen a PR
+// whose head branch was deleted.
+func (c *Client) ReopenIssue(org, repo string, number int) error {
+	c.log("ReopenIssue", org, repo, number)
+	if c.dry {
+		return nil
+	}
+	resp, err := c.request(http.MethodPatch, fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.base, org, repo, number), map[string]string{"state": "open"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	return nil
+}
+
+// AddAssignee assigns the logins to the issue or PR. GitHub quietly ignores
+// logins that can't be assigned in the repo.
+func (c *Client) AddAssignee(org, repo string, number int, logins []string) error {
+	c.log("AddAssignee", org, repo, number, logins)
+	if c.dry {
+		return nil
+	}
+	resp, err := c.request(http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", c.base, org, repo, number), map[string][]string{"assignees": logins})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		return fmt.Errorf("response not 201: %s", resp.Status)
+	}
+	return nil
+}
+
+// RemoveAssignee unassigns the logins from the issue or PR.
+func (c *Client) RemoveAssignee(org, repo string, number int, logins []string) error {
+	c.log("RemoveAssignee", org, repo, number, logins)
+	if c.dry {
+		return nil
+	}
+	resp, err := c.request(http.MethodDelete, fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", c.base, org, repo, number), map[string][]string{"assignees": logins})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	return nil
+}
+
+// RefNotFound is the error from GetRef when the ref doesn't exist.
+type RefNotFound struct {
+	Org, Repo, Ref string
+}
+
+func (e *RefNotFound) Error() string {
+	return fmt.Sprintf("%s/%s has no ref %s", e.Org, e.Repo, e.Ref)
+}
+
+// GetRef returns the SHA of the given ref, such as "heads/master". If the ref
+// doesn't exist then the error is a *RefNotFound.
 func (c *Client) GetRef(org, repo, ref string) (string, error) {
 	c.log("GetRef", org, repo, ref)
 	if c.fake {
@@ -354,6 +717,9 @@ func (c *Client) GetRef(org, repo, ref string) (string, error) {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		return "", &RefNotFound{org, repo, ref}
+	}
 	if resp.StatusCode != 200 {
 		return "", fmt.Errorf("response not 200: %s", resp.Status)
 	}
@@ -370,6 +736,51 @@ func (c *Client) GetRef(org, repo, ref string) (string, error) {
 	return res.Object["sha"], nil
 }
 
+// FileNotFound is the error from GetFile when the file doesn't exist at the
+// commit.
+type FileNotFound struct {
+	Org, Repo, Path, Commit string
+}
+
+func (e *FileNotFound) Error() string {
+	return fmt.Sprintf("%s/%s/%s not found at %s", e.Org, e.Repo, e.Path, e.Commit)
+}
+
+// GetFile returns the contents of the file at the commit, which may be a SHA
+// or a branch. If the file doesn't exist then the error is a *FileNotFound.
+func (c *Client) GetFile(org, repo, path, commit string) ([]byte, error) {
+	c.log("GetFile", org, repo, path, commit)
+	if c.fake {
+		return nil, &FileNotFound{org, repo, path, commit}
+	}
+	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", c.base, org, repo, path, url.QueryEscape(commit)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		return nil, &FileNotFound{org, repo, path, commit}
+	} else if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("response not 200: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	if res.Encoding != "base64" {
+		return nil, fmt.Errorf("unexpected encoding %q for %s", res.Encoding, path)
+	}
+	// GitHub wraps the base64 at 60 characters.
+	return base64.StdEncoding.DecodeString(strings.Replace(res.Content, "\n", "", -1))
+}
+
 // FindIssues uses the github search API to find issues which match a particular query.
 // TODO(foxish): we should accept map[string][]string and use net/url properly.
 func (c *Client) FindIssues(query string) ([]Issue, error) {
@@ -377,22 +788,54 @@ func (c *Client) FindIssues(query string) ([]Issue, error) {
 	if c.fake {
 		return nil, nil
 	}
-	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/search/issues?q=%s", c.base, query), nil)
+	nextURL := fmt.Sprintf("%s/search/issues?q=%s&per_page=100", c.base, query)
+	var issues []Issue
+	for nextURL != "" {
+		resp, err := c.request(http.MethodGet, nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("response not 200: %s", resp.Status)
+		}
+		var issSearchResult IssuesSearchResult
+		if err := json.Unmarshal(b, &issSearchResult); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issSearchResult.Issues...)
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return issues, nil
+}
+
+// CountIssues uses the github search API to count the issues which match a
+// query, without listing them.
+func (c *Client) CountIssues(query string) (int, error) {
+	c.log("CountIssues", query)
+	if c.fake {
+		return 0, nil
+	}
+	resp, err := c.request(http.MethodGet, fmt.Sprintf("%s/search/issues?q=%s&per_page=1", c.base, query), nil)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("response not 200: %s", resp.Status)
+		return 0, fmt.Errorf("response not 200: %s", resp.Status)
 	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	var issSearchResult IssuesSearchResult
 	if err := json.Unmarshal(b, &issSearchResult); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return issSearchResult.Issues, nil
+	return issSearchResult.Total, nil
 }
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"testing"
 	"time"
@@ -83,6 +84,79 @@ func TestIsMember(t *testing.T) {
 	}
 }
 
+func TestListTeams(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path == "/orgs/k8s/teams" {
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+			fmt.Fprint(w, `[{"id": 1, "slug": "maintainers"}]`)
+		} else if r.URL.Path == "/someotherpath" {
+			fmt.Fprint(w, `[{"id": 2, "slug": "reviewers"}]`)
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	teams, err := c.ListTeams("k8s")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(teams) != 2 {
+		t.Errorf("Expected two teams, found %d: %v", len(teams), teams)
+	} else if teams[0].ID != 1 || teams[1].Slug != "reviewers" {
+		t.Errorf("Wrong teams: %v", teams)
+	}
+}
+
+func TestIsTeamMember(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/teams/5/memberships/active":
+			fmt.Fprint(w, `{"state": "active"}`)
+		case "/teams/5/memberships/invited":
+			fmt.Fprint(w, `{"state": "pending"}`)
+		case "/teams/5/memberships/other":
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	for user, expected := range map[string]bool{"active": true, "invited": false, "other": false} {
+		if mem, err := c.IsTeamMember(5, user); err != nil {
+			t.Errorf("Didn't expect error for %s: %v", user, err)
+		} else if mem != expected {
+			t.Errorf("Expected membership %t for %s, got %t.", expected, user, mem)
+		}
+	}
+}
+
+func TestCollaboratorPermission(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/collaborators/person/permission" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"permission": "write", "user": {"login": "person"}}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	p, err := c.CollaboratorPermission("k8s", "kuber", "person")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if p != "write" {
+		t.Errorf("Wrong permission: %s", p)
+	}
+}
+
 func TestCreateComment(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -127,6 +201,32 @@ func TestDeleteComment(t *testing.T) {
 	}
 }
 
+func TestEditComment(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/issues/comments/123" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var ic IssueComment
+		if err := json.Unmarshal(b, &ic); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if ic.Body != "hello" {
+			t.Errorf("Wrong body: %s", ic.Body)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.EditComment("k8s", "kuber", 123, "hello"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
 func TestGetPullRequest(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -159,10 +259,14 @@ func TestGetRef(t *testing.T) {
 		if r.Method != http.MethodGet {
 			t.Errorf("Bad method: %s", r.Method)
 		}
-		if r.URL.Path != "/repos/k8s/kuber/git/refs/heads/mastah" {
+		switch r.URL.Path {
+		case "/repos/k8s/kuber/git/refs/heads/mastah":
+			fmt.Fprint(w, bytes.NewBufferString(`{"object": {"sha":"abcde"}}`))
+		case "/repos/k8s/kuber/git/refs/heads/gone":
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+		default:
 			t.Errorf("Bad request path: %s", r.URL.Path)
 		}
-		fmt.Fprint(w, bytes.NewBufferString(`{"object": {"sha":"abcde"}}`))
 	}))
 	defer ts.Close()
 	c := getClient(ts.URL)
@@ -172,6 +276,11 @@ func TestGetRef(t *testing.T) {
 	} else if sha != "abcde" {
 		t.Errorf("Wrong sha: %s", sha)
 	}
+	if _, err := c.GetRef("k8s", "kuber", "heads/gone"); err == nil {
+		t.Errorf("Expected error for a missing ref.")
+	} else if _, ok := err.(*RefNotFound); !ok {
+		t.Errorf("Expected RefNotFound, got %v", err)
+	}
 }
 
 func TestCreateStatus(t *testing.T) {
@@ -239,6 +348,96 @@ func TestListIssueComments(t *testing.T) {
 	}
 }
 
+func TestGetPullRequestChanges(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path == "/repos/k8s/kuber/pulls/15/files" {
+			prcs := []PullRequestChange{{Filename: "foo.txt"}}
+			b, err := json.Marshal(prcs)
+			if err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<blorp>; rel="first", <https://%s/someotherpath>; rel="next"`, r.Host))
+			fmt.Fprint(w, bytes.NewBuffer(b))
+		} else if r.URL.Path == "/someotherpath" {
+			prcs := []PullRequestChange{{Filename: "bar.txt"}}
+			b, err := json.Marshal(prcs)
+			if err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			fmt.Fprint(w, bytes.NewBuffer(b))
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cs, err := c.GetPullRequestChanges("k8s", "kuber", 15)
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(cs) != 2 {
+		t.Errorf("Expected two changes, found %d: %v", len(cs), cs)
+	} else if cs[0].Filename != "foo.txt" || cs[1].Filename != "bar.txt" {
+		t.Errorf("Wrong filenames: %v", cs)
+	}
+}
+
+func TestListLabels(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path == "/repos/k8s/kuber/labels" {
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+			fmt.Fprint(w, `[{"name": "kind/bug"}]`)
+		} else if r.URL.Path == "/someotherpath" {
+			fmt.Fprint(w, `[{"name": "lgtm"}]`)
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	ls, err := c.ListLabels("k8s", "kuber")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(ls) != 2 {
+		t.Errorf("Expected two labels, found %d: %v", len(ls), ls)
+	} else if ls[0].Name != "kind/bug" || ls[1].Name != "lgtm" {
+		t.Errorf("Wrong labels: %v", ls)
+	}
+}
+
+func TestGetCombinedStatus(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path == "/repos/k8s/kuber/commits/abcde/status" {
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+			fmt.Fprint(w, `{"sha": "abcde", "state": "failure", "statuses": [{"context": "a", "state": "failure"}]}`)
+		} else if r.URL.Path == "/someotherpath" {
+			fmt.Fprint(w, `{"sha": "abcde", "state": "failure", "statuses": [{"context": "b", "state": "success"}]}`)
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cs, err := c.GetCombinedStatus("k8s", "kuber", "abcde")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if cs.SHA != "abcde" || cs.State != StatusFailure {
+		t.Errorf("Wrong combined status: %+v", cs)
+	}
+	if len(cs.Statuses) != 2 || cs.Statuses[0].Context != "a" || cs.Statuses[1].Context != "b" {
+		t.Errorf("Wrong statuses: %+v", cs.Statuses)
+	}
+}
+
 func TestAddLabel(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -312,6 +511,109 @@ func TestCloseIssue(t *testing.T) {
 	}
 }
 
+func TestReopenIssue(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/issues/5" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var ps map[string]string
+		if err := json.Unmarshal(b, &ps); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if len(ps) != 1 {
+			t.Errorf("Wrong length patch: %v", ps)
+		} else if ps["state"] != "open" {
+			t.Errorf("Wrong state: %s", ps["state"])
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.ReopenIssue("k8s", "kuber", 5); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestAddAssignee(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/issues/5/assignees" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var ps map[string][]string
+		if err := json.Unmarshal(b, &ps); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if len(ps["assignees"]) != 2 || ps["assignees"][0] != "a" || ps["assignees"][1] != "b" {
+			t.Errorf("Wrong assignees: %v", ps)
+		}
+		http.Error(w, "201 Created", http.StatusCreated)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.AddAssignee("k8s", "kuber", 5, []string{"a", "b"}); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestRemoveAssignee(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/issues/5/assignees" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var ps map[string][]string
+		if err := json.Unmarshal(b, &ps); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if len(ps["assignees"]) != 1 || ps["assignees"][0] != "a" {
+			t.Errorf("Wrong assignees: %v", ps)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.RemoveAssignee("k8s", "kuber", 5, []string{"a"}); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestCountIssues(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/search/issues" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if q := r.URL.Query().Get("q"); q != "is:pr assignee:a" {
+			t.Errorf("Bad query: %s", q)
+		}
+		fmt.Fprint(w, `{"total_count": 42, "items": [{"number": 1}]}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if n, err := c.CountIssues(url.QueryEscape("is:pr assignee:a")); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if n != 42 {
+		t.Errorf("Expected 42 issues, got %d.", n)
+	}
+}
+
 func TestFindIssues(t *testing.T) {
 	issueNum := 5
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -349,3 +651,82 @@ func TestFindIssues(t *testing.T) {
 	}
 
 }
+
+func TestFindIssuesPages(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search/issues" {
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+			fmt.Fprint(w, `{"total_count": 2, "items": [{"number": 1}]}`)
+		} else if r.URL.Path == "/someotherpath" {
+			fmt.Fprint(w, `{"total_count": 2, "items": [{"number": 2}]}`)
+		} else {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	is, err := c.FindIssues("type:pr")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(is) != 2 || is[0].Number != 1 || is[1].Number != 2 {
+		t.Errorf("Wrong issues: %v", is)
+	}
+}
+
+func TestGetFile(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Query().Get("ref") != "abc" {
+			t.Errorf("Bad ref: %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/repos/k8s/kuber/contents/pkg/OWNERS":
+			fmt.Fprint(w, `{"encoding":"base64","content":"YXBwcm92ZXJz\nOgotIGFs\naWNlCg==\n"}`)
+		case "/repos/k8s/kuber/contents/OWNERS":
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	b, err := c.GetFile("k8s", "kuber", "pkg/OWNERS", "abc")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if string(b) != "approvers:\n- alice\n" {
+		t.Errorf("Wrong contents: %q", b)
+	}
+	if _, err := c.GetFile("k8s", "kuber", "OWNERS", "abc"); err == nil {
+		t.Errorf("Expected error for a missing file.")
+	} else if _, ok := err.(*FileNotFound); !ok {
+		t.Errorf("Expected FileNotFound, got %v", err)
+	}
+}
+
+func TestBotName(t *testing.T) {
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/user" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"login": "wowza"}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	for i := 0; i < 2; i++ {
+		if name, err := c.BotName(); err != nil {
+			t.Errorf("Didn't expect error: %v", err)
+		} else if name != "wowza" {
+			t.Errorf("Wrong bot name: %s", name)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected one request, got %d.", requests)
+	}
+}
@@ -23,15 +23,44 @@ import (
 )
 
 type FakeClient struct {
-	Issues         []github.Issue
-	OrgMembers     []string
+	Issues     []github.Issue
+	OrgMembers []string
+	// org -> teams
+	Teams map[string][]github.Team
+	// team ID -> logins
+	TeamMembers map[int][]string
+	// login -> permission on the repo
+	Collaborators  map[string]string
 	IssueComments  map[int][]github.IssueComment
 	IssueCommentID int
 	PullRequests   map[int]*github.PullRequest
-
+	// PR number -> files changed
+	PullRequestChanges map[int][]github.PullRequestChange
+	// path -> commit -> contents
+	RemoteFiles map[string]map[string]string
+
+	// ref -> statuses
+	CombinedStatuses map[string]*github.CombinedStatus
+	// ref -> statuses that we created, in order
+	CreatedStatuses map[string][]github.Status
+	// Labels that exist in the repo.
+	ExistingLabels []string
 	// org/repo#number:label
 	LabelsAdded   []string
 	LabelsRemoved []string
+
+	// org/repo#number
+	IssuesClosed []string
+
+	// org/repo#number:login
+	AssigneesAdded   []string
+	AssigneesRemoved []string
+}
+
+const botName = "k8s-ci-robot"
+
+func (f *FakeClient) BotName() (string, error) {
+	return botName, nil
 }
 
 func (f *FakeClient) IsMember(org, user string) (bool, error) {
@@ -43,6 +72,26 @@ func (f *FakeClient) IsMember(org, user string) (bool, error) {
 	return false, nil
 }
 
+func (f *FakeClient) ListTeams(org string) ([]github.Team, error) {
+	return f.Teams[org], nil
+}
+
+func (f *FakeClient) IsTeamMember(id int, user string) (bool, error) {
+	for _, m := range f.TeamMembers[id] {
+		if m == user {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *FakeClient) CollaboratorPermission(org, repo, user string) (string, error) {
+	if p, ok := f.Collaborators[user]; ok {
+		return p, nil
+	}
+	return "none", nil
+}
+
 func (f *FakeClient) ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error) {
 	return append([]github.IssueComment{}, f.IssueComments[number]...), nil
 }
@@ -51,6 +100,7 @@ func (f *FakeClient) CreateComment(owner, repo string, number int, comment strin
 	f.IssueComments[number] = append(f.IssueComments[number], github.IssueComment{
 		ID:   f.IssueCommentID,
 		Body: comment,
+		User: github.User{Login: botName},
 	})
 	f.IssueCommentID++
 	return nil
@@ -68,18 +118,53 @@ func (f *FakeClient) DeleteComment(owner, repo string, ID int) error {
 	return fmt.Errorf("could not find issue comment %d", ID)
 }
 
+func (f *FakeClient) EditComment(owner, repo string, ID int, comment string) error {
+	for _, ics := range f.IssueComments {
+		for i, ic := range ics {
+			if ic.ID == ID {
+				ics[i].Body = comment
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("could not find issue comment %d", ID)
+}
+
 func (f *FakeClient) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
 	return f.PullRequests[number], nil
 }
 
+func (f *FakeClient) GetPullRequestChanges(owner, repo string, number int) ([]github.PullRequestChange, error) {
+	return f.PullRequestChanges[number], nil
+}
+
 func (f *FakeClient) GetRef(owner, repo, ref string) (string, error) {
 	return "abcde", nil
 }
 
+func (f *FakeClient) GetCombinedStatus(owner, repo, ref string) (*github.CombinedStatus, error) {
+	if cs, ok := f.CombinedStatuses[ref]; ok {
+		return cs, nil
+	}
+	return &github.CombinedStatus{SHA: ref}, nil
+}
+
 func (f *FakeClient) CreateStatus(owner, repo, ref string, s github.Status) error {
+	if f.CreatedStatuses == nil {
+		f.CreatedStatuses = map[string][]github.Status{}
+	}
+	f.CreatedStatuses[ref] = append(f.CreatedStatuses[ref], s)
 	return nil
 }
 
+func (f *FakeClient) ListLabels(owner, repo string) ([]github.Label, error) {
+	var ls []github.Label
+	for _, l := range f.ExistingLabels {
+		ls = append(ls, github.Label{Name: l})
+	}
+	return ls, nil
+}
+
 func (f *FakeClient) AddLabel(owner, repo string, number int, label string) error {
 	f.LabelsAdded = append(f.LabelsAdded, fmt.Sprintf("%s/%s#%d:%s", owner, repo, number, label))
 	return nil
@@ -90,6 +175,36 @@ func (f *FakeClient) RemoveLabel(owner, repo string, number int, label string) e
 	return nil
 }
 
+func (f *FakeClient) AddAssignee(owner, repo string, number int, logins []string) error {
+	for _, l := range logins {
+		f.AssigneesAdded = append(f.AssigneesAdded, fmt.Sprintf("%s/%s#%d:%s", owner, repo, number, l))
+	}
+	return nil
+}
+
+func (f *FakeClient) RemoveAssignee(owner, repo string, number int, logins []string) error {
+	for _, l := range logins {
+		f.AssigneesRemoved = append(f.AssigneesRemoved, fmt.Sprintf("%s/%s#%d:%s", owner, repo, number, l))
+	}
+	return nil
+}
+
+func (f *FakeClient) GetFile(owner, repo, path, commit string) ([]byte, error) {
+	if c, ok := f.RemoteFiles[path][commit]; ok {
+		return []byte(c), nil
+	}
+	return nil, &github.FileNotFound{Org: owner, Repo: repo, Path: path, Commit: commit}
+}
+
 func (f *FakeClient) FindIssues(query string) ([]github.Issue, error) {
 	return f.Issues, nil
 }
+
+func (f *FakeClient) CountIssues(query string) (int, error) {
+	return len(f.Issues), nil
+}
+
+func (f *FakeClient) CloseIssue(owner, repo string, number int) error {
+	f.IssuesClosed = append(f.IssuesClosed, fmt.Sprintf("%s/%s#%d", owner, repo, number))
+	return nil
+}
@@ -16,6 +16,12 @@ limitations under the License.
 
 package github
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
 // These are possible State entries for a Status.
 const (
 	StatusPending = "pending"
@@ -32,6 +38,14 @@ type Status struct {
 	Context     string `json:"context,omitempty"`
 }
 
+// CombinedStatus is the latest status for each context of a ref.
+type CombinedStatus struct {
+	SHA string `json:"sha"`
+	// The overall state, which is failure if any context failed.
+	State    string   `json:"state"`
+	Statuses []Status `json:"statuses"`
+}
+
 // User is a GitHub user account.
 type User struct {
 	Login string `json:"login"`
@@ -47,11 +61,21 @@ type PullRequestEvent struct {
 
 // PullRequest contains information about a PullRequest.
 type PullRequest struct {
-	Number  int               `json:"number"`
-	HTMLURL string            `json:"html_url"`
-	User    User              `json:"user"`
-	Base    PullRequestBranch `json:"base"`
-	Head    PullRequestBranch `json:"head"`
+	Number    int               `json:"number"`
+	HTMLURL   string            `json:"html_url"`
+	User      User              `json:"user"`
+	Title     string            `json:"title"`
+	State     string            `json:"state"`
+	Base      PullRequestBranch `json:"base"`
+	Head      PullRequestBranch `json:"head"`
+	Labels    []Label           `json:"labels"`
+	Assignees []User            `json:"assignees"`
+	Merged    bool              `json:"merged"`
+	// Lines changed across the whole PR.
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	// Mergeable is nil while GitHub is still working it out.
+	Mergeable *bool `json:"mergeable,omitempty"`
 }
 
 // PullRequestBranch contains information about a particular branch in a PR.
@@ -61,6 +85,19 @@ type PullRequestBranch struct {
 	Repo Repo   `json:"repo"`
 }
 
+// PullRequestChange contains information about what a PR changed.
+type PullRequestChange struct {
+	SHA       string `json:"sha"`
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Changes   int    `json:"changes"`
+	Patch     string `json:"patch"`
+	// Set when the file was renamed.
+	PreviousFilename string `json:"previous_filename"`
+}
+
 type Label struct {
 	URL   string `json:"url"`
 	Name  string `json:"name"`
@@ -82,6 +119,13 @@ type IssueCommentEvent struct {
 	Repo    Repo         `json:"repository"`
 }
 
+// Team is a team in an org.
+type Team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
 type Issue struct {
 	User      User    `json:"user"`
 	Number    int     `json:"number"`
@@ -95,6 +139,20 @@ type Issue struct {
 	PullRequest *struct{} `json:"pull_request,omitempty"`
 }
 
+// OrgRepo returns the org and repo of the issue from its web URL. Search
+// results don't say otherwise.
+func (i Issue) OrgRepo() (string, string, error) {
+	u, err := url.Parse(i.HTMLURL)
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("can't find the repo in %s", i.HTMLURL)
+	}
+	return parts[0], parts[1], nil
+}
+
 func (i Issue) IsAssignee(login string) bool {
 	for _, assignee := range i.Assignees {
 		if login == assignee.Login {
@@ -128,6 +186,81 @@ type IssueComment struct {
 	HTMLURL string `json:"html_url,omitempty"`
 }
 
+// These are possible State entries for a Review. GitHub sends them in lower
+// case in webhooks and in upper case from the API.
+const (
+	ReviewStateApproved         = "approved"
+	ReviewStateChangesRequested = "changes_requested"
+	ReviewStateCommented        = "commented"
+	ReviewStateDismissed        = "dismissed"
+)
+
+// ReviewEvent is what GitHub sends us when a PR review is changed.
+type ReviewEvent struct {
+	Action      string      `json:"action"`
+	PullRequest PullRequest `json:"pull_request"`
+	Repo        Repo        `json:"repository"`
+	Review      Review      `json:"review"`
+}
+
+// Review describes a Pull Request review.
+type Review struct {
+	ID      int    `json:"id"`
+	User    User   `json:"user"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+}
+
+// ReviewCommentEvent is what GitHub sends us when a PR review comment is changed.
+type ReviewCommentEvent struct {
+	Action      string        `json:"action"`
+	PullRequest PullRequest   `json:"pull_request"`
+	Repo        Repo          `json:"repository"`
+	Comment     ReviewComment `json:"comment"`
+}
+
+// ReviewComment describes a Pull Request review comment, which is attached to
+// a line of the diff rather than to the PR as a whole.
+type ReviewComment struct {
+	ID       int    `json:"id"`
+	ReviewID int    `json:"pull_request_review_id"`
+	User     User   `json:"user"`
+	Body     string `json:"body"`
+	Path     string `json:"path"`
+	HTMLURL  string `json:"html_url"`
+}
+
+// PushEvent is what GitHub sends us when a ref is pushed.
+type PushEvent struct {
+	Ref     string   `json:"ref"`
+	Before  string   `json:"before"`
+	After   string   `json:"after"`
+	Created bool     `json:"created"`
+	Deleted bool     `json:"deleted"`
+	Forced  bool     `json:"forced"`
+	Commits []Commit `json:"commits"`
+	Repo    Repo     `json:"repository"`
+}
+
+// Branch returns the name of the pushed branch, or the empty string if the ref
+// is not a branch, such as a tag.
+func (pe PushEvent) Branch() string {
+	if !strings.HasPrefix(pe.Ref, "refs/heads/") {
+		return ""
+	}
+	return strings.TrimPrefix(pe.Ref, "refs/heads/")
+}
+
+// Commit is a commit in a PushEvent.
+type Commit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
 type StatusEvent struct {
 	SHA         string `json:"sha,omitempty"`
 	State       string `json:"state,omitempty"`
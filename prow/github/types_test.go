@@ -14,18 +14,23 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package main
+package github
 
 import (
 	"testing"
-
-	"k8s.io/test-infra/prow/plugins"
 )
 
-// Make sure that our plugins are valid.
-func TestPlugins(t *testing.T) {
-	pa := &plugins.PluginAgent{}
-	if err := pa.Load("../../plugins.yaml"); err != nil {
-		t.Fatalf("Could not load plugins: %v.", err)
+func TestOrgRepo(t *testing.T) {
+	i := Issue{HTMLURL: "https://github.com/kubernetes/test-infra/pull/123"}
+	org, repo, err := i.OrgRepo()
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if org != "kubernetes" || repo != "test-infra" {
+		t.Errorf("Expected kubernetes/test-infra, got %s/%s.", org, repo)
+	}
+	i.HTMLURL = "https://github.com/"
+	if _, _, err := i.OrgRepo(); err == nil {
+		t.Error("Expected error for a URL without a repo.")
 	}
 }
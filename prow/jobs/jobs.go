@@ -17,14 +17,12 @@ limitations under the License.
 package jobs
 
 import (
-	"io/ioutil"
+	"fmt"
 	"regexp"
 	"sync"
 	"time"
 
-	"github.com/Sirupsen/logrus"
-	"github.com/ghodss/yaml"
-
+	"k8s.io/test-infra/prow/cron"
 	"k8s.io/test-infra/prow/kube"
 )
 
@@ -42,70 +40,367 @@ type JenkinsJob struct {
 	RerunCommand string `json:"rerun_command"`
 	// Whether or not to skip commenting and setting status on GitHub.
 	SkipReport bool `json:"skip_report"`
+	// Regexps for the base branches to run against. Each must match the whole
+	// branch name. Run against all branches if empty.
+	Branches []string `json:"branches"`
+	// Regexps for base branches not to run against, even if they match
+	// Branches.
+	SkipBranches []string `json:"skip_branches"`
+	// Run automatically, like AlwaysRun, but only if the PR changes a file
+	// whose path matches this regexp.
+	RunIfChanged string `json:"run_if_changed"`
+	// Kubernetes pod spec.
+	Spec *kube.PodSpec `json:"spec,omitempty"`
+
+	// We'll set these when we load it.
+	re        *regexp.Regexp
+	brs       []*regexp.Regexp
+	skipBrs   []*regexp.Regexp
+	reChanges *regexp.Regexp
+}
+
+// ChangedFilesProvider returns the paths of the files that a PR changes. The
+// JobAgent only calls it when it needs to check a run_if_changed job.
+type ChangedFilesProvider func() ([]string, error)
+
+// TriggerMatches returns true if the comment body asks for the job.
+func (j JenkinsJob) TriggerMatches(body string) bool {
+	return j.re.MatchString(body)
+}
+
+// RunsAgainstBranch returns true if the job should run for PRs against the
+// branch.
+func (j JenkinsJob) RunsAgainstBranch(branch string) bool {
+	if matchesAny(j.skipBrs, branch) {
+		return false
+	}
+	return len(j.brs) == 0 || matchesAny(j.brs, branch)
+}
+
+// RunsAgainstChanges returns true if one of the changed files matches the
+// job's run_if_changed regexp.
+func (j JenkinsJob) RunsAgainstChanges(changes []string) bool {
+	if j.reChanges == nil {
+		return false
+	}
+	for _, c := range changes {
+		if j.reChanges.MatchString(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// runsAutomatically returns true if the job runs without being explicitly
+// asked for, either because it always runs or because the PR changes
+// something it cares about.
+func (j JenkinsJob) runsAutomatically(changes ChangedFilesProvider) (bool, error) {
+	if j.AlwaysRun {
+		return true, nil
+	}
+	if j.reChanges == nil {
+		return false, nil
+	}
+	cs, err := changes()
+	if err != nil {
+		return false, err
+	}
+	return j.RunsAgainstChanges(cs), nil
+}
+
+// Postsubmit is the job-specific info for jobs that run when a branch is
+// pushed, such as when a PR merges.
+type Postsubmit struct {
+	// eg kubernetes-build
+	Name string `json:"name"`
+	// Regexps for the branches to run against. Each must match the whole
+	// branch name. Run against all branches if empty.
+	Branches []string `json:"branches"`
 	// Kubernetes pod spec.
 	Spec *kube.PodSpec `json:"spec,omitempty"`
 
 	// We'll set this when we load it.
-	re *regexp.Regexp
+	brs []*regexp.Regexp
+}
+
+// RunsAgainstBranch returns true if the postsubmit should run when the branch
+// is pushed.
+func (ps Postsubmit) RunsAgainstBranch(branch string) bool {
+	return len(ps.brs) == 0 || matchesAny(ps.brs, branch)
+}
+
+// Periodic is the job-specific info for jobs that run on a schedule.
+type Periodic struct {
+	// eg ci-kubernetes-cross-build
+	Name string `json:"name"`
+	// Time between runs, such as "2h" or "30m". Exactly one of Interval and
+	// Cron must be set.
+	Interval string `json:"interval"`
+	// Cron expression, such as "0 */6 * * *", in UTC.
+	Cron string `json:"cron"`
+	// Kubernetes pod spec.
+	Spec *kube.PodSpec `json:"spec,omitempty"`
+
+	// We'll set these when we load it.
+	interval time.Duration
+	schedule *cron.Schedule
+}
+
+// NextRun returns when the periodic should next run, given that it last ran at
+// last. For intervals, a zero last means that it should run right away.
+func (p Periodic) NextRun(last time.Time) time.Time {
+	if p.schedule != nil {
+		return p.schedule.Next(last)
+	}
+	return last.Add(p.interval)
+}
+
+func (p *Periodic) setSchedule() error {
+	if (p.Interval == "") == (p.Cron == "") {
+		return fmt.Errorf("periodic %s must set exactly one of interval and cron", p.Name)
+	}
+	if p.Cron != "" {
+		s, err := cron.Parse(p.Cron)
+		if err != nil {
+			return fmt.Errorf("periodic %s: %v", p.Name, err)
+		}
+		p.schedule = s
+		return nil
+	}
+	d, err := time.ParseDuration(p.Interval)
+	if err != nil {
+		return fmt.Errorf("periodic %s: %v", p.Name, err)
+	} else if d <= 0 {
+		return fmt.Errorf("periodic %s: interval must be positive", p.Name)
+	}
+	p.interval = d
+	return nil
+}
+
+// JobConfig is the job section of the prow config.
+type JobConfig struct {
+	// Repo FullName (eg "kubernetes/kubernetes") -> []JenkinsJob
+	Presubmits map[string][]JenkinsJob `json:"presubmits"`
+	// Repo FullName (eg "kubernetes/kubernetes") -> []Postsubmit
+	Postsubmits map[string][]Postsubmit `json:"postsubmits"`
+	Periodics   []Periodic              `json:"periodics"`
+}
+
+// Validate returns every problem with the job config, rather than stopping at
+// the first.
+func (jc JobConfig) Validate() []error {
+	var errs []error
+	for repo, js := range jc.Presubmits {
+		contexts := map[string]string{}
+		var valid []JenkinsJob
+		for _, j := range js {
+			if j.Name == "" {
+				errs = append(errs, fmt.Errorf("presubmit in %s needs a name", repo))
+				continue
+			}
+			if j.Context == "" {
+				errs = append(errs, fmt.Errorf("presubmit %s in %s needs a context", j.Name, repo))
+			} else if other, ok := contexts[j.Context]; ok {
+				errs = append(errs, fmt.Errorf("presubmits %s and %s in %s have the same context: %s", other, j.Name, repo, j.Context))
+			} else {
+				contexts[j.Context] = j.Name
+			}
+			if err := j.setRegexps(); err != nil {
+				errs = append(errs, fmt.Errorf("presubmit %s in %s: %v", j.Name, repo, err))
+				continue
+			}
+			if j.Trigger == "" || j.RerunCommand == "" {
+				errs = append(errs, fmt.Errorf("presubmit %s in %s needs a trigger and a rerun command", j.Name, repo))
+			} else if !j.TriggerMatches(j.RerunCommand) {
+				errs = append(errs, fmt.Errorf("presubmit %s in %s: rerun command %q does not match trigger %q", j.Name, repo, j.RerunCommand, j.Trigger))
+			}
+			valid = append(valid, j)
+		}
+		// Check that each rerun command doesn't run any other jobs. Jobs may
+		// share a name if they report to different contexts.
+		for i, j1 := range valid {
+			for k, j2 := range valid {
+				if i != k && j1.RerunCommand != "" && j2.TriggerMatches(j1.RerunCommand) {
+					errs = append(errs, fmt.Errorf("presubmit %s in %s: rerun command %q also triggers %s", j1.Name, repo, j1.RerunCommand, j2.Name))
+				}
+			}
+		}
+	}
+	for repo, pss := range jc.Postsubmits {
+		names := map[string]bool{}
+		for _, ps := range pss {
+			if ps.Name == "" {
+				errs = append(errs, fmt.Errorf("postsubmit in %s needs a name", repo))
+				continue
+			}
+			if names[ps.Name] {
+				errs = append(errs, fmt.Errorf("postsubmit %s is duplicated in %s", ps.Name, repo))
+			}
+			names[ps.Name] = true
+			if err := ps.setRegexps(); err != nil {
+				errs = append(errs, fmt.Errorf("postsubmit %s in %s: %v", ps.Name, repo, err))
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, p := range jc.Periodics {
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("periodic needs a name"))
+			continue
+		}
+		if names[p.Name] {
+			errs = append(errs, fmt.Errorf("periodic %s is duplicated", p.Name))
+		}
+		names[p.Name] = true
+		if err := p.setSchedule(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
 }
 
 type JobAgent struct {
 	mut sync.Mutex
 	// Repo FullName (eg "kubernetes/kubernetes") -> []JenkinsJob
 	jobs map[string][]JenkinsJob
+	// Repo FullName (eg "kubernetes/kubernetes") -> []Postsubmit
+	postsubmits map[string][]Postsubmit
+	periodics   []Periodic
 }
 
-func (ja *JobAgent) Start(path string) error {
-	if err := ja.LoadOnce(path); err != nil {
+// SetConfig replaces all of the jobs. It returns an error and changes nothing
+// if any of the jobs can't be set up. It does not fully validate the config.
+func (ja *JobAgent) SetConfig(jc JobConfig) error {
+	nj, err := copyPresubmits(jc.Presubmits)
+	if err != nil {
 		return err
 	}
-	ticker := time.Tick(1 * time.Minute)
-	go func() {
-		for range ticker {
-			ja.tryLoad(path)
-		}
-	}()
+	np, err := copyPostsubmits(jc.Postsubmits)
+	if err != nil {
+		return err
+	}
+	nper, err := copyPeriodics(jc.Periodics)
+	if err != nil {
+		return err
+	}
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	ja.jobs = nj
+	ja.postsubmits = np
+	ja.periodics = nper
 	return nil
 }
 
 func (ja *JobAgent) SetJobs(jobs map[string][]JenkinsJob) error {
+	nj, err := copyPresubmits(jobs)
+	if err != nil {
+		return err
+	}
 	ja.mut.Lock()
 	defer ja.mut.Unlock()
+	ja.jobs = nj
+	return nil
+}
+
+func (ja *JobAgent) SetPostsubmits(postsubmits map[string][]Postsubmit) error {
+	np, err := copyPostsubmits(postsubmits)
+	if err != nil {
+		return err
+	}
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	ja.postsubmits = np
+	return nil
+}
+
+func (ja *JobAgent) SetPeriodics(periodics []Periodic) error {
+	np, err := copyPeriodics(periodics)
+	if err != nil {
+		return err
+	}
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	ja.periodics = np
+	return nil
+}
+
+func copyPresubmits(jobs map[string][]JenkinsJob) (map[string][]JenkinsJob, error) {
 	nj := map[string][]JenkinsJob{}
 	for k, v := range jobs {
 		nj[k] = make([]JenkinsJob, len(v))
 		copy(nj[k], v)
-		for i := range v {
-			if re, err := regexp.Compile(v[i].Trigger); err != nil {
-				return err
-			} else {
-				nj[k][i].re = re
+		for i := range nj[k] {
+			if err := nj[k][i].setRegexps(); err != nil {
+				return nil, fmt.Errorf("job %s: %v", nj[k][i].Name, err)
 			}
 		}
 	}
-	ja.jobs = nj
-	return nil
+	return nj, nil
 }
 
-func (ja *JobAgent) LoadOnce(path string) error {
-	ja.mut.Lock()
-	defer ja.mut.Unlock()
-	return ja.load(path)
+func copyPostsubmits(postsubmits map[string][]Postsubmit) (map[string][]Postsubmit, error) {
+	np := map[string][]Postsubmit{}
+	for k, v := range postsubmits {
+		np[k] = make([]Postsubmit, len(v))
+		copy(np[k], v)
+		for i := range np[k] {
+			if err := np[k][i].setRegexps(); err != nil {
+				return nil, fmt.Errorf("postsubmit %s: %v", np[k][i].Name, err)
+			}
+		}
+	}
+	return np, nil
 }
 
-func (ja *JobAgent) MatchingJobs(fullRepoName, body string, testAll *regexp.Regexp) []JenkinsJob {
-	ja.mut.Lock()
-	defer ja.mut.Unlock()
+func copyPeriodics(periodics []Periodic) ([]Periodic, error) {
+	np := make([]Periodic, len(periodics))
+	copy(np, periodics)
+	for i := range np {
+		if err := np[i].setSchedule(); err != nil {
+			return nil, err
+		}
+	}
+	return np, nil
+}
+
+// MatchingJobs returns the jobs that the comment body asks for on a PR
+// against branch. A comment that matches testAll asks for every job that
+// would run automatically.
+func (ja *JobAgent) MatchingJobs(fullRepoName, branch, body string, testAll *regexp.Regexp, changes ChangedFilesProvider) ([]JenkinsJob, error) {
 	var result []JenkinsJob
 	ott := testAll.MatchString(body)
-	if jobs, ok := ja.jobs[fullRepoName]; ok {
-		for _, job := range jobs {
-			if job.re.MatchString(body) || (ott && job.AlwaysRun) {
+	for _, job := range ja.AllJobs(fullRepoName) {
+		if !job.RunsAgainstBranch(branch) {
+			continue
+		}
+		if job.TriggerMatches(body) {
+			result = append(result, job)
+		} else if ott {
+			if run, err := job.runsAutomatically(changes); err != nil {
+				return nil, err
+			} else if run {
 				result = append(result, job)
 			}
 		}
 	}
-	return result
+	return result, nil
+}
+
+// TestAllJobs returns the jobs that run automatically on a PR against branch:
+// always_run jobs and run_if_changed jobs that match one of the changes.
+func (ja *JobAgent) TestAllJobs(fullRepoName, branch string, changes ChangedFilesProvider) ([]JenkinsJob, error) {
+	var result []JenkinsJob
+	for _, job := range ja.AllJobs(fullRepoName) {
+		if !job.RunsAgainstBranch(branch) {
+			continue
+		}
+		if run, err := job.runsAutomatically(changes); err != nil {
+			return nil, err
+		} else if run {
+			result = append(result, job)
+		}
+	}
+	return result, nil
 }
 
 func (ja *JobAgent) AllJobs(fullRepoName string) []JenkinsJob {
@@ -127,33 +422,101 @@ func (ja *JobAgent) GetJob(repo, job string) (bool, JenkinsJob) {
 	return false, JenkinsJob{}
 }
 
-// Hold the lock.
-func (ja *JobAgent) load(path string) error {
-	b, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	nj := map[string][]JenkinsJob{}
-	if err := yaml.Unmarshal(b, &nj); err != nil {
-		return err
+// MatchingPostsubmits returns the postsubmits to run when the branch is
+// pushed.
+func (ja *JobAgent) MatchingPostsubmits(fullRepoName, branch string) []Postsubmit {
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	var result []Postsubmit
+	for _, ps := range ja.postsubmits[fullRepoName] {
+		if ps.RunsAgainstBranch(branch) {
+			result = append(result, ps)
+		}
 	}
-	for k, v := range nj {
-		for i, j := range v {
-			if re, err := regexp.Compile(j.Trigger); err == nil {
-				nj[k][i].re = re
-			} else {
-				return err
-			}
+	return result
+}
+
+func (ja *JobAgent) GetPostsubmit(repo, job string) (bool, Postsubmit) {
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	for _, ps := range ja.postsubmits[repo] {
+		if ps.Name == job {
+			return true, ps
 		}
 	}
-	ja.jobs = nj
-	return nil
+	return false, Postsubmit{}
+}
+
+func (ja *JobAgent) AllPeriodics() []Periodic {
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	res := make([]Periodic, len(ja.periodics))
+	copy(res, ja.periodics)
+	return res
 }
 
-func (ja *JobAgent) tryLoad(path string) {
+func (ja *JobAgent) GetPeriodic(job string) (bool, Periodic) {
 	ja.mut.Lock()
 	defer ja.mut.Unlock()
-	if err := ja.load(path); err != nil {
-		logrus.WithField("path", path).WithError(err).Error("Error loading config.")
+	for _, p := range ja.periodics {
+		if p.Name == job {
+			return true, p
+		}
+	}
+	return false, Periodic{}
+}
+
+func (j *JenkinsJob) setRegexps() error {
+	var err error
+	if j.re, err = regexp.Compile(j.Trigger); err != nil {
+		return fmt.Errorf("bad trigger: %v", err)
+	}
+	if j.brs, err = branchRegexps(j.Branches); err != nil {
+		return fmt.Errorf("bad branches: %v", err)
+	}
+	if j.skipBrs, err = branchRegexps(j.SkipBranches); err != nil {
+		return fmt.Errorf("bad skip_branches: %v", err)
+	}
+	j.reChanges = nil
+	if j.RunIfChanged != "" {
+		if j.AlwaysRun {
+			return fmt.Errorf("set at most one of always_run and run_if_changed")
+		}
+		if j.reChanges, err = regexp.Compile(j.RunIfChanged); err != nil {
+			return fmt.Errorf("bad run_if_changed: %v", err)
+		}
+	}
+	return nil
+}
+
+func (ps *Postsubmit) setRegexps() error {
+	brs, err := branchRegexps(ps.Branches)
+	if err != nil {
+		return fmt.Errorf("bad branches: %v", err)
+	}
+	ps.brs = brs
+	return nil
+}
+
+// branchRegexps compiles the branch regexps so that each must match the whole
+// branch name.
+func branchRegexps(branches []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, b := range branches {
+		re, err := regexp.Compile("^(?:" + b + ")$")
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
 	}
+	return false
 }
@@ -17,72 +17,12 @@ limitations under the License.
 package jobs
 
 import (
-	"fmt"
-	"os"
+	"reflect"
 	"regexp"
 	"testing"
+	"time"
 )
 
-const testThis = "@k8s-bot test this"
-
-// Make sure that our rerun commands match our triggers.
-func TestJobTriggers(t *testing.T) {
-	ja := &JobAgent{}
-	if err := ja.load("../jobs.yaml"); err != nil {
-		t.Fatalf("Could not load job configs: %v", err)
-	}
-	if len(ja.jobs) == 0 {
-		t.Fatalf("No jobs found in jobs.yaml.")
-	}
-	for _, jobs := range ja.jobs {
-		for i, job := range jobs {
-			if job.Name == "" {
-				t.Errorf("Job %v needs a name.", job)
-				continue
-			}
-			if job.Context == "" {
-				t.Errorf("Job %s needs a context.", job.Name)
-			}
-			if job.RerunCommand == "" || job.Trigger == "" {
-				t.Errorf("Job %s needs a trigger and a rerun command.", job.Name)
-				continue
-			}
-			// Check that the merge bot will run AlwaysRun jobs, otherwise it
-			// will attempt to rerun forever.
-			if job.AlwaysRun && !job.re.MatchString(testThis) {
-				t.Errorf("AlwaysRun job %s: \"%s\" does not match regex \"%v\".", job.Name, testThis, job.Trigger)
-			}
-			// Check that the rerun command actually runs the job.
-			if !job.re.MatchString(job.RerunCommand) {
-				t.Errorf("For job %s: RerunCommand \"%s\" does not match regex \"%v\".", job.Name, job.RerunCommand, job.Trigger)
-			}
-			// Next check that the rerun command doesn't run any other jobs.
-			for j, job2 := range jobs {
-				if i == j {
-					continue
-				}
-				if job.Context == job2.Context && i > j {
-					t.Errorf("Jobs %s and %s have the same context: %s", job.Name, job2.Name, job.Context)
-				}
-				if job2.re.MatchString(job.RerunCommand) {
-					t.Errorf("RerunCommand \"%s\" from job %s matches \"%v\" from job %s but shouldn't.", job.RerunCommand, job.Name, job2.Trigger, job2.Name)
-				}
-			}
-			// Ensure that jobs have a shell script of the same name.
-			if s, err := os.Stat(fmt.Sprintf("../../jobs/%s.sh", job.Name)); err != nil {
-				t.Errorf("Cannot find test-infra/jobs/%s.sh", job.Name)
-			} else {
-				if s.Mode()&0111 == 0 {
-					t.Errorf("Not executable: %s.sh (%o)", job.Name, s.Mode()&0777)
-				}
-				if s.Mode()&0444 == 0 {
-					t.Errorf("Not readable: %s.sh (%o)", job.Name, s.Mode()&0777)
-				}
-			}
-		}
-	}
-}
-
 func TestCommentBodyMatches(t *testing.T) {
 	var testcases = []struct {
 		repo         string
@@ -159,7 +99,10 @@ func TestCommentBodyMatches(t *testing.T) {
 		},
 	}
 	for _, tc := range testcases {
-		actualJobs := ja.MatchingJobs(tc.repo, tc.body, regexp.MustCompile(`ok to test`))
+		actualJobs, err := ja.MatchingJobs(tc.repo, "master", tc.body, regexp.MustCompile(`ok to test`), nil)
+		if err != nil {
+			t.Fatalf("Didn't expect error matching jobs: %v", err)
+		}
 		match := true
 		if len(actualJobs) != len(tc.expectedJobs) {
 			match = false
@@ -183,3 +126,333 @@ func TestCommentBodyMatches(t *testing.T) {
 		}
 	}
 }
+
+func TestPresubmitFilters(t *testing.T) {
+	ja := &JobAgent{}
+	if err := ja.SetJobs(map[string][]JenkinsJob{
+		"org/repo": {
+			{
+				Name:      "unit",
+				Trigger:   `@k8s-bot (unit )?test this`,
+				AlwaysRun: true,
+			},
+			{
+				Name:      "release",
+				Trigger:   `@k8s-bot (release )?test this`,
+				AlwaysRun: true,
+				Branches:  []string{`release-.*`},
+			},
+			{
+				Name:         "not-release",
+				Trigger:      `@k8s-bot (not-release )?test this`,
+				AlwaysRun:    true,
+				SkipBranches: []string{`release-.*`},
+			},
+			{
+				Name:         "docs",
+				Trigger:      `@k8s-bot docs test this`,
+				RunIfChanged: `^docs/`,
+			},
+		},
+	}); err != nil {
+		t.Fatalf("Could not set jobs: %v", err)
+	}
+	var testcases = []struct {
+		name    string
+		branch  string
+		body    string
+		changes []string
+
+		expectedTestAll  []string
+		expectedMatching []string
+	}{
+		{
+			name:             "master, code change",
+			branch:           "master",
+			body:             "ok to test",
+			changes:          []string{"pkg/foo.go"},
+			expectedTestAll:  []string{"unit", "not-release"},
+			expectedMatching: []string{"unit", "not-release"},
+		},
+		{
+			name:             "release branch, docs change",
+			branch:           "release-1.5",
+			body:             "ok to test",
+			changes:          []string{"pkg/foo.go", "docs/README.md"},
+			expectedTestAll:  []string{"unit", "release", "docs"},
+			expectedMatching: []string{"unit", "release", "docs"},
+		},
+		{
+			name:             "branch regexps must match the whole name",
+			branch:           "not-release-1.5",
+			body:             "@k8s-bot test this",
+			changes:          []string{"pkg/foo.go"},
+			expectedTestAll:  []string{"unit", "not-release"},
+			expectedMatching: []string{"unit", "not-release"},
+		},
+		{
+			name:             "explicit trigger ignores changes",
+			branch:           "master",
+			body:             "@k8s-bot docs test this",
+			changes:          []string{"pkg/foo.go"},
+			expectedTestAll:  []string{"unit", "not-release"},
+			expectedMatching: []string{"docs"},
+		},
+		{
+			name:             "explicit trigger honours branches",
+			branch:           "master",
+			body:             "@k8s-bot release test this",
+			expectedTestAll:  []string{"unit", "not-release"},
+			expectedMatching: nil,
+		},
+	}
+	names := func(js []JenkinsJob) []string {
+		var ns []string
+		for _, j := range js {
+			ns = append(ns, j.Name)
+		}
+		return ns
+	}
+	for _, tc := range testcases {
+		changes := func() ([]string, error) {
+			return tc.changes, nil
+		}
+		testAll, err := ja.TestAllJobs("org/repo", tc.branch, changes)
+		if err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		if actual := names(testAll); !reflect.DeepEqual(actual, tc.expectedTestAll) {
+			t.Errorf("For case %s, expected jobs %v to run automatically, got %v.", tc.name, tc.expectedTestAll, actual)
+		}
+		matching, err := ja.MatchingJobs("org/repo", tc.branch, tc.body, regexp.MustCompile(`ok to test`), changes)
+		if err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		if actual := names(matching); !reflect.DeepEqual(actual, tc.expectedMatching) {
+			t.Errorf("For case %s, expected matching jobs %v, got %v.", tc.name, tc.expectedMatching, actual)
+		}
+	}
+}
+
+func TestRunIfChangedAndAlwaysRun(t *testing.T) {
+	ja := &JobAgent{}
+	if err := ja.SetJobs(map[string][]JenkinsJob{
+		"org/repo": {
+			{
+				Name:         "both",
+				AlwaysRun:    true,
+				RunIfChanged: `^docs/`,
+			},
+		},
+	}); err == nil {
+		t.Error("Expected an error for a job with both always_run and run_if_changed.")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		config JobConfig
+		errors int
+	}{
+		{
+			name: "valid",
+			config: JobConfig{
+				Presubmits: map[string][]JenkinsJob{
+					"org/repo": {
+						{
+							Name:         "unit",
+							Context:      "unit",
+							Trigger:      "@k8s-bot (unit )?test this",
+							RerunCommand: "@k8s-bot unit test this",
+							AlwaysRun:    true,
+						},
+						{
+							Name:         "e2e",
+							Context:      "e2e",
+							Trigger:      "@k8s-bot (e2e )?test this",
+							RerunCommand: "@k8s-bot e2e test this",
+							RunIfChanged: "^pkg/",
+						},
+					},
+				},
+				Postsubmits: map[string][]Postsubmit{
+					"org/repo": {{Name: "build", Branches: []string{"master"}}},
+				},
+				Periodics: []Periodic{{Name: "nightly", Cron: "0 0 * * *"}},
+			},
+		},
+		{
+			name: "every problem is reported",
+			config: JobConfig{
+				Presubmits: map[string][]JenkinsJob{
+					"org/repo": {
+						{
+							// Missing a name.
+							Context: "nameless",
+						},
+						{
+							Name:         "bad-rerun",
+							Context:      "unit",
+							Trigger:      "@k8s-bot unit test this",
+							RerunCommand: "@k8s-bot test this",
+						},
+						{
+							Name:         "same-context",
+							Context:      "unit",
+							Trigger:      "@k8s-bot other test this",
+							RerunCommand: "@k8s-bot other test this",
+						},
+						{
+							Name:         "bad-trigger",
+							Context:      "bad",
+							Trigger:      "@k8s-bot (bad test this",
+							RerunCommand: "@k8s-bot bad test this",
+						},
+						{
+							Name:         "greedy",
+							Context:      "greedy",
+							Trigger:      "test this",
+							RerunCommand: "@k8s-bot greedy test this",
+						},
+					},
+				},
+				Postsubmits: map[string][]Postsubmit{
+					"org/repo": {
+						{Name: "build", Branches: []string{"("}},
+						{Name: "build"},
+					},
+				},
+				Periodics: []Periodic{
+					{Name: "nightly"},
+					{Name: "nightly", Interval: "1h"},
+				},
+			},
+			// Nameless, bad rerun, same context, bad trigger, greedy matches
+			// the rerun commands of bad-rerun and same-context, bad
+			// branches, duplicate postsubmit, no schedule, and duplicate
+			// periodic.
+			errors: 10,
+		},
+	}
+	for _, tc := range testcases {
+		if errs := tc.config.Validate(); len(errs) != tc.errors {
+			t.Errorf("For case %s, expected %d errors, got %d: %v", tc.name, tc.errors, len(errs), errs)
+		}
+	}
+}
+
+func TestMatchingPostsubmits(t *testing.T) {
+	ja := &JobAgent{}
+	if err := ja.SetPostsubmits(map[string][]Postsubmit{
+		"org/repo": {
+			{
+				Name: "all",
+			},
+			{
+				Name:     "master",
+				Branches: []string{"master"},
+			},
+			{
+				Name:     "release",
+				Branches: []string{"release-.*", "master"},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("Could not set postsubmits: %v", err)
+	}
+	var testcases = []struct {
+		repo         string
+		branch       string
+		expectedJobs []string
+	}{
+		{
+			repo:         "org/repo",
+			branch:       "master",
+			expectedJobs: []string{"all", "master", "release"},
+		},
+		{
+			repo:         "org/repo",
+			branch:       "release-1.5",
+			expectedJobs: []string{"all", "release"},
+		},
+		{
+			repo:         "org/repo",
+			branch:       "not-master",
+			expectedJobs: []string{"all"},
+		},
+		{
+			repo:   "org/repo2",
+			branch: "master",
+		},
+	}
+	for _, tc := range testcases {
+		var actual []string
+		for _, ps := range ja.MatchingPostsubmits(tc.repo, tc.branch) {
+			actual = append(actual, ps.Name)
+		}
+		if !reflect.DeepEqual(actual, tc.expectedJobs) {
+			t.Errorf("Wrong postsubmits for %s %s. Got %v, expected %v.", tc.repo, tc.branch, actual, tc.expectedJobs)
+		}
+	}
+}
+
+func TestPeriodicSchedule(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		periodic Periodic
+		last     time.Time
+		next     time.Time
+		err      bool
+	}{
+		{
+			name:     "neither interval nor cron",
+			periodic: Periodic{Name: "p"},
+			err:      true,
+		},
+		{
+			name:     "both interval and cron",
+			periodic: Periodic{Name: "p", Interval: "1h", Cron: "0 * * * *"},
+			err:      true,
+		},
+		{
+			name:     "bad interval",
+			periodic: Periodic{Name: "p", Interval: "an hour"},
+			err:      true,
+		},
+		{
+			name:     "bad cron",
+			periodic: Periodic{Name: "p", Cron: "every hour"},
+			err:      true,
+		},
+		{
+			name:     "interval",
+			periodic: Periodic{Name: "p", Interval: "2h"},
+			last:     time.Date(2017, time.January, 1, 10, 0, 0, 0, time.UTC),
+			next:     time.Date(2017, time.January, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "cron",
+			periodic: Periodic{Name: "p", Cron: "0 */6 * * *"},
+			last:     time.Date(2017, time.January, 1, 10, 0, 0, 0, time.UTC),
+			next:     time.Date(2017, time.January, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range testcases {
+		ja := &JobAgent{}
+		err := ja.SetPeriodics([]Periodic{tc.periodic})
+		if err != nil {
+			if !tc.err {
+				t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			}
+			continue
+		} else if tc.err {
+			t.Errorf("For case %s, expected error.", tc.name)
+			continue
+		}
+		_, p := ja.GetPeriodic("p")
+		if next := p.NextRun(tc.last); !next.Equal(tc.next) {
+			t.Errorf("For case %s, expected next run %v, got %v.", tc.name, tc.next, next)
+		}
+	}
+}
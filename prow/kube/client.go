@@ -64,6 +64,15 @@ func (c *Client) log(methodName string, args ...interface{}) {
 
 type ConflictError error
 
+// NotFoundError means that the object does not exist.
+type NotFoundError struct {
+	body string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("not found, body: %s", e.body)
+}
+
 // Retry on transport failures. Does not retry on 500s.
 func (c *Client) request(method, urlPath string, query map[string]string, body io.Reader) ([]byte, error) {
 	if c.fake {
@@ -92,6 +101,8 @@ func (c *Client) request(method, urlPath string, query map[string]string, body i
 	}
 	if resp.StatusCode == 409 {
 		return nil, ConflictError(fmt.Errorf("body: %s", string(rb)))
+	} else if resp.StatusCode == 404 {
+		return nil, NotFoundError{body: string(rb)}
 	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return nil, fmt.Errorf("response has status \"%s\" and body \"%s\"", resp.Status, string(rb))
 	}
@@ -339,6 +350,60 @@ func (c *Client) ReplaceSecret(name string, s Secret) error {
 	return err
 }
 
+func (c *Client) GetConfigMap(name string) (ConfigMap, error) {
+	c.log("GetConfigMap", name)
+	path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", c.namespace, name)
+	body, err := c.request(http.MethodGet, path, map[string]string{}, nil)
+	if err != nil {
+		return ConfigMap{}, err
+	}
+	var retConfigMap ConfigMap
+	if err = json.Unmarshal(body, &retConfigMap); err != nil {
+		return ConfigMap{}, err
+	}
+	return retConfigMap, nil
+}
+
+func (c *Client) CreateConfigMap(cm ConfigMap) (ConfigMap, error) {
+	c.log("CreateConfigMap", cm)
+	b, err := json.Marshal(cm)
+	if err != nil {
+		return ConfigMap{}, err
+	}
+	buf := bytes.NewBuffer(b)
+	path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps", c.namespace)
+	body, err := c.request(http.MethodPost, path, map[string]string{}, buf)
+	if err != nil {
+		return ConfigMap{}, err
+	}
+	var retConfigMap ConfigMap
+	if err = json.Unmarshal(body, &retConfigMap); err != nil {
+		return ConfigMap{}, err
+	}
+	return retConfigMap, nil
+}
+
+// ReplaceConfigMap fails with a ConflictError if the ConfigMap changed since
+// the resource version in its metadata.
+func (c *Client) ReplaceConfigMap(name string, cm ConfigMap) (ConfigMap, error) {
+	c.log("ReplaceConfigMap", name, cm)
+	b, err := json.Marshal(cm)
+	if err != nil {
+		return ConfigMap{}, err
+	}
+	buf := bytes.NewBuffer(b)
+	path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", c.namespace, name)
+	body, err := c.request(http.MethodPut, path, map[string]string{}, buf)
+	if err != nil {
+		return ConfigMap{}, err
+	}
+	var retConfigMap ConfigMap
+	if err = json.Unmarshal(body, &retConfigMap); err != nil {
+		return ConfigMap{}, err
+	}
+	return retConfigMap, nil
+}
+
 func (c *Client) GetLog(pod string) ([]byte, error) {
 	c.log("GetLog", pod)
 	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", c.namespace, pod)
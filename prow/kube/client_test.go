@@ -233,3 +233,75 @@ func TestPatchJobStatus(t *testing.T) {
 		t.Errorf("Didn't expect error: %v", err)
 	}
 }
+
+func TestGetConfigMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/ns/configmaps/cm":
+			fmt.Fprint(w, `{"metadata": {"name": "cm"}, "data": {"a": "b"}}`)
+		case "/api/v1/namespaces/ns/configmaps/missing":
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cm, err := c.GetConfigMap("cm")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if cm.Data["a"] != "b" {
+		t.Errorf("Wrong data: %v", cm.Data)
+	}
+	if _, err := c.GetConfigMap("missing"); err == nil {
+		t.Error("Expected an error for a missing ConfigMap.")
+	} else if _, ok := err.(NotFoundError); !ok {
+		t.Errorf("Expected a NotFoundError, got %v", err)
+	}
+}
+
+func TestCreateConfigMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/api/v1/namespaces/ns/configmaps" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"metadata": {"name": "cm"}}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cm, err := c.CreateConfigMap(ConfigMap{Metadata: ObjectMeta{Name: "cm"}})
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if cm.Metadata.Name != "cm" {
+		t.Errorf("Wrong name: %s", cm.Metadata.Name)
+	}
+}
+
+func TestReplaceConfigMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/api/v1/namespaces/ns/configmaps/cm" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"metadata": {"name": "cm", "resourceVersion": "2"}}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cm, err := c.ReplaceConfigMap("cm", ConfigMap{Metadata: ObjectMeta{Name: "cm", ResourceVersion: "1"}})
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if cm.Metadata.ResourceVersion != "2" {
+		t.Errorf("Wrong resource version: %s", cm.Metadata.ResourceVersion)
+	}
+}
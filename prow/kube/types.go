@@ -35,6 +35,11 @@ type Secret struct {
 	Data     map[string]string `json:"data,omitempty"`
 }
 
+type ConfigMap struct {
+	Metadata ObjectMeta        `json:"metadata,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
 type Job struct {
 	Metadata ObjectMeta `json:"metadata,omitempty"`
 	Spec     JobSpec    `json:"spec,omitempty"`
@@ -72,6 +72,10 @@ type BuildRequest struct {
 }
 
 func (b BuildRequest) GetRefs() string {
+	// Periodic jobs don't test any particular ref.
+	if b.BaseRef == "" && len(b.Pulls) == 0 {
+		return ""
+	}
 	rs := []string{fmt.Sprintf("%s:%s", b.BaseRef, b.BaseSHA)}
 	for _, pull := range b.Pulls {
 		rs = append(rs, fmt.Sprintf("%d:%s", pull.Number, pull.SHA))
@@ -118,6 +122,7 @@ func startJob(k startClient, jobName, context string, br BuildRequest) error {
 		"base-ref":    br.BaseRef,
 		"base-sha":    br.BaseSHA,
 		"context":     context,
+		"start-time":  time.Now().UTC().Format(time.RFC3339),
 	}
 	args := []string{
 		"--job-name=" + jobName,
@@ -140,6 +145,14 @@ func startJob(k startClient, jobName, context string, br BuildRequest) error {
 	} else if len(br.Pulls) > 1 {
 		labels["type"] = "batch"
 		args = append(args, "--report=false")
+	} else if br.BaseRef != "" {
+		// There are no PRs to report to when testing a pushed branch.
+		labels["type"] = "postsubmit"
+		args = append(args, "--report=false")
+	} else {
+		// Periodic jobs are started by horologium with no refs at all.
+		labels["type"] = "periodic"
+		args = append(args, "--report=false")
 	}
 
 	name := uuid.NewV1().String()
@@ -180,9 +193,9 @@ func startJob(k startClient, jobName, context string, br BuildRequest) error {
 									MountPath: "/etc/labels",
 								},
 								{
-									Name:      "job-configs",
+									Name:      "config",
 									ReadOnly:  true,
-									MountPath: "/etc/jobs",
+									MountPath: "/etc/config",
 								},
 							},
 							Env: []kube.EnvVar{
@@ -225,9 +238,9 @@ func startJob(k startClient, jobName, context string, br BuildRequest) error {
 							},
 						},
 						{
-							Name: "job-configs",
+							Name: "config",
 							ConfigMap: &kube.ConfigMapSource{
-								Name: "job-configs",
+								Name: "config",
 							},
 						},
 					},
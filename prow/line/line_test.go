@@ -18,6 +18,7 @@ package line
 
 import (
 	"testing"
+	"time"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/kube"
@@ -86,6 +87,61 @@ func TestStartJob(t *testing.T) {
 	}
 }
 
+func TestStartJobType(t *testing.T) {
+	var testcases = []struct {
+		baseRef      string
+		pulls        []Pull
+		expectedType string
+		report       bool
+	}{
+		{
+			baseRef:      "master",
+			pulls:        []Pull{{Number: 5, SHA: "123"}},
+			expectedType: "pr",
+			report:       true,
+		},
+		{
+			baseRef:      "master",
+			pulls:        []Pull{{Number: 5, SHA: "123"}, {Number: 6, SHA: "456"}},
+			expectedType: "batch",
+		},
+		{
+			baseRef:      "master",
+			expectedType: "postsubmit",
+		},
+		{
+			expectedType: "periodic",
+		},
+	}
+	for _, tc := range testcases {
+		c := &kc{}
+		br := BuildRequest{
+			Org:     "owner",
+			Repo:    "kube",
+			BaseRef: tc.baseRef,
+			Pulls:   tc.pulls,
+		}
+		if err := startJob(c, "job-name", "Context", br); err != nil {
+			t.Fatalf("Didn't expect error starting job: %v", err)
+		}
+		if tp := c.job.Metadata.Labels["type"]; tp != tc.expectedType {
+			t.Errorf("Expected type %s, got %s", tc.expectedType, tp)
+		}
+		reported := false
+		for _, arg := range c.job.Spec.Template.Spec.Containers[0].Args {
+			if arg == "--report=true" {
+				reported = true
+			}
+		}
+		if reported != tc.report {
+			t.Errorf("For type %s, expected report %t, got %t", tc.expectedType, tc.report, reported)
+		}
+		if _, err := time.Parse(time.RFC3339, c.job.Metadata.Annotations["start-time"]); err != nil {
+			t.Errorf("For type %s, bad start time annotation: %v", tc.expectedType, err)
+		}
+	}
+}
+
 // Just make sure we set the parallelism to 0.
 func TestDeleteJob(t *testing.T) {
 	c := &kc{}
@@ -0,0 +1,142 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package owners reads OWNERS files. An OWNERS file lists who may review and
+// approve changes to the files in its directory and every subdirectory.
+package owners
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+// File is a parsed OWNERS file.
+type File struct {
+	// Path of the OWNERS file in the repo, such as "pkg/OWNERS".
+	Path string `json:"-"`
+	// People who may approve changes.
+	Approvers []string `json:"approvers"`
+	// People who may review changes.
+	Reviewers []string `json:"reviewers"`
+}
+
+// Dir returns the directory that the file owns, which is "" for the root.
+func (f *File) Dir() string {
+	if d := path.Dir(f.Path); d != "." {
+		return d
+	}
+	return ""
+}
+
+// IsApprover returns true if the login is listed as an approver. GitHub
+// logins are case insensitive.
+func (f *File) IsApprover(login string) bool {
+	return contains(f.Approvers, login)
+}
+
+// IsReviewer returns true if the login is listed as a reviewer.
+func (f *File) IsReviewer(login string) bool {
+	return contains(f.Reviewers, login)
+}
+
+func contains(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
+
+type githubClient interface {
+	GetFile(org, repo, path, commit string) ([]byte, error)
+}
+
+// Loader reads the OWNERS files of a repo at one commit. It remembers every
+// file that it reads, including the ones that don't exist.
+type Loader struct {
+	gc                githubClient
+	org, repo, commit string
+
+	files map[string]*File
+}
+
+// NewLoader returns a loader for the repo at the commit.
+func NewLoader(gc githubClient, org, repo, commit string) *Loader {
+	return &Loader{
+		gc:     gc,
+		org:    org,
+		repo:   repo,
+		commit: commit,
+		files:  map[string]*File{},
+	}
+}
+
+// For returns the OWNERS files that apply to the file, nearest first. It
+// returns nothing if no OWNERS file applies.
+func (l *Loader) For(file string) ([]*File, error) {
+	var fs []*File
+	for dir := path.Dir(file); ; dir = path.Dir(dir) {
+		if dir == "." || dir == "/" {
+			dir = ""
+		}
+		f, err := l.load(path.Join(dir, "OWNERS"))
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			fs = append(fs, f)
+		}
+		if dir == "" {
+			return fs, nil
+		}
+	}
+}
+
+// load returns the OWNERS file at the path, or nil if there is none.
+func (l *Loader) load(p string) (*File, error) {
+	if f, ok := l.files[p]; ok {
+		return f, nil
+	}
+	b, err := l.gc.GetFile(l.org, l.repo, p, l.commit)
+	if _, ok := err.(*github.FileNotFound); ok {
+		l.files[p] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	f, err := Parse(p, b)
+	if err != nil {
+		return nil, err
+	}
+	l.files[p] = f
+	return f, nil
+}
+
+// Parse parses the contents of the OWNERS file at the path.
+func Parse(p string, b []byte) (*File, error) {
+	f := &File{}
+	if err := yaml.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", p, err)
+	}
+	f.Path = p
+	return f, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package owners
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestFor(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		RemoteFiles: map[string]map[string]string{
+			"OWNERS": {
+				"abc": "approvers:\n- root\n",
+			},
+			"pkg/OWNERS": {
+				"abc": "approvers:\n- Alice\nreviewers:\n- bob\n",
+			},
+			"pkg/api/v1/OWNERS": {
+				"abc": "approvers:\n- carol\n",
+				"def": "approvers:\n- dave\n",
+			},
+		},
+	}
+	var testcases = []struct {
+		file     string
+		expected []string
+	}{
+		{"README.md", []string{"OWNERS"}},
+		{"pkg/foo.go", []string{"pkg/OWNERS", "OWNERS"}},
+		{"pkg/api/types.go", []string{"pkg/OWNERS", "OWNERS"}},
+		{"pkg/api/v1/types.go", []string{"pkg/api/v1/OWNERS", "pkg/OWNERS", "OWNERS"}},
+	}
+	l := NewLoader(fc, "org", "repo", "abc")
+	for _, tc := range testcases {
+		fs, err := l.For(tc.file)
+		if err != nil {
+			t.Errorf("For %s, didn't expect error: %v", tc.file, err)
+			continue
+		}
+		var paths []string
+		for _, f := range fs {
+			paths = append(paths, f.Path)
+		}
+		if !reflect.DeepEqual(paths, tc.expected) {
+			t.Errorf("For %s, expected %v, got %v", tc.file, tc.expected, paths)
+		}
+	}
+
+	fs, err := l.For("pkg/foo.go")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if !fs[0].IsApprover("alice") || fs[0].IsApprover("bob") || !fs[0].IsReviewer("BOB") {
+		t.Errorf("Wrong approvers or reviewers in %+v", fs[0])
+	}
+	if d := fs[0].Dir(); d != "pkg" {
+		t.Errorf("Expected dir pkg, got %q", d)
+	}
+	if d := fs[1].Dir(); d != "" {
+		t.Errorf("Expected the root dir, got %q", d)
+	}
+}
+
+func TestNoOwners(t *testing.T) {
+	l := NewLoader(&fakegithub.FakeClient{}, "org", "repo", "abc")
+	fs, err := l.For("pkg/foo.go")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fs) != 0 {
+		t.Errorf("Expected no OWNERS files, got %v", fs)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse("OWNERS", []byte("approvers: [")); err == nil {
+		t.Errorf("Expected error parsing bad OWNERS file.")
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package approve implements the approve plugin. Each file that a PR changes
+// needs an "/approve" from an approver in an OWNERS file in its directory or
+// a parent directory. Once every file has one, the PR gets the approved
+// label. The bot keeps a comment up to date with what is still needed.
+package approve
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/owners"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "approve"
+
+// The bot's status comment contains this so that we can find it again.
+const statusMarker = "<!-- approve status -->"
+
+var (
+	approveRe       = regexp.MustCompile(`(?mi)^\/approve\r?$`)
+	approveCancelRe = regexp.MustCompile(`(?mi)^\/approve cancel\r?$`)
+)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Adds the " + c.Approve.Label + " label once an approver from the OWNERS files for every changed file has approved the PR.",
+		Commands: []plugins.Command{{
+			Usage:       "/approve [cancel]",
+			Description: "Approves the files you own in the PR, or cancels your approval.",
+			Regex:       approveRe.String(),
+			Example:     "/approve",
+			WhoCanUse:   "Approvers in the OWNERS files of the changed files",
+		}},
+		Config: map[string]string{"approve.label": c.Approve.Label},
+	}
+}
+
+type githubClient interface {
+	BotName() (string, error)
+	CreateComment(owner, repo string, number int, comment string) error
+	DeleteComment(owner, repo string, ID int) error
+	EditComment(owner, repo string, ID int, comment string) error
+	ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error)
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	GetPullRequestChanges(owner, repo string, number int) ([]github.PullRequestChange, error)
+	GetFile(owner, repo, path, commit string) ([]byte, error)
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleComment(pc.GitHubClient, pc.Logger, pc.PluginConfig.Approve, ic)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pc.PluginConfig.Approve, pre)
+}
+
+func handleComment(gc githubClient, log *logrus.Entry, cfg plugins.Approve, ic github.IssueCommentEvent) error {
+	if !ic.Issue.IsPullRequest() || ic.Issue.State != "open" || ic.Action != "created" {
+		return nil
+	}
+	if _, ok := approveCommand(ic.Comment.Body); !ok {
+		return nil
+	}
+	return update(gc, log, cfg, ic.Repo.Owner.Login, ic.Repo.Name, ic.Issue.Number)
+}
+
+func handlePR(gc githubClient, log *logrus.Entry, cfg plugins.Approve, pre github.PullRequestEvent) error {
+	switch pre.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return nil
+	}
+	return update(gc, log, cfg, pre.PullRequest.Base.Repo.Owner.Login, pre.PullRequest.Base.Repo.Name, pre.Number)
+}
+
+// approveCommand returns whether the body approves or cancels an approval,
+// and false for its second value if it does neither.
+func approveCommand(body string) (bool, bool) {
+	if approveRe.MatchString(body) {
+		return true, true
+	} else if approveCancelRe.MatchString(body) {
+		return false, true
+	}
+	return false, false
+}
+
+// approvers returns everyone whose most recent approve command approved.
+func approvers(comments []github.IssueComment, botName string) map[string]bool {
+	approved := map[string]bool{}
+	for _, c := range comments {
+		if c.User.Login == botName {
+			continue
+		}
+		if a, ok := approveCommand(c.Body); ok {
+			approved[strings.ToLower(c.User.Login)] = a
+		}
+	}
+	return approved
+}
+
+// ownersStatus is whether someone approved the files under one OWNERS file.
+type ownersStatus struct {
+	// The nearest OWNERS file, followed by its parents. Approvers in any of
+	// them can approve.
+	files      []*owners.File
+	approvedBy []string
+}
+
+func (s ownersStatus) suggested() []string {
+	return s.files[0].Approvers
+}
+
+// update recomputes which OWNERS files still need approval and then sets the
+// label and status comment to match.
+func update(gc githubClient, log *logrus.Entry, cfg plugins.Approve, org, repo string, number int) error {
+	pr, err := gc.GetPullRequest(org, repo, number)
+	if err != nil {
+		return err
+	}
+	changes, err := gc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return err
+	}
+	l := owners.NewLoader(gc, org, repo, pr.Base.SHA)
+	needed := map[string]*ownersStatus{}
+	for _, c := range changes {
+		paths := []string{c.Filename}
+		// Moving a file out of a directory needs approval from its owners
+		// too.
+		if c.PreviousFilename != "" {
+			paths = append(paths, c.PreviousFilename)
+		}
+		for _, p := range paths {
+			fs, err := l.For(p)
+			if err != nil {
+				return err
+			}
+			// Nobody owns the file, so it needs no approval.
+			if len(fs) == 0 {
+				continue
+			}
+			needed[fs[0].Path] = &ownersStatus{files: fs}
+		}
+	}
+
+	comments, err := gc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return err
+	}
+	botName, err := gc.BotName()
+	if err != nil {
+		return err
+	}
+	approved := approvers(comments, botName)
+	// A PR that touches no owned files isn't approved, but it doesn't need a
+	// status comment either.
+	allApproved := len(needed) > 0
+	for _, s := range needed {
+		seen := map[string]bool{}
+		for _, f := range s.files {
+			for _, a := range f.Approvers {
+				if login := strings.ToLower(a); approved[login] && !seen[login] {
+					seen[login] = true
+					s.approvedBy = append(s.approvedBy, a)
+				}
+			}
+		}
+		sort.Strings(s.approvedBy)
+		if len(s.approvedBy) == 0 {
+			allApproved = false
+		}
+	}
+
+	hasLabel := false
+	for _, label := range pr.Labels {
+		if label.Name == cfg.Label {
+			hasLabel = true
+		}
+	}
+	if allApproved && !hasLabel {
+		log.Infof("Adding %s label.", cfg.Label)
+		if err := gc.AddLabel(org, repo, number, cfg.Label); err != nil {
+			return err
+		}
+	} else if !allApproved && hasLabel {
+		log.Infof("Removing %s label.", cfg.Label)
+		if err := gc.RemoveLabel(org, repo, number, cfg.Label); err != nil {
+			return err
+		}
+	}
+	var body string
+	if len(needed) > 0 {
+		body = statusComment(needed, allApproved)
+	}
+	return updateComment(gc, log, org, repo, number, comments, botName, body)
+}
+
+// updateComment edits the bot's status comment to say body, creating it if
+// there isn't one. An empty body deletes the status comment.
+func updateComment(gc githubClient, log *logrus.Entry, org, repo string, number int, comments []github.IssueComment, botName, body string) error {
+	var old []github.IssueComment
+	for _, c := range comments {
+		if c.User.Login == botName && strings.Contains(c.Body, statusMarker) {
+			old = append(old, c)
+		}
+	}
+	if body == "" {
+		for _, c := range old {
+			log.Info("Deleting approval status comment.")
+			if err := gc.DeleteComment(org, repo, c.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(old) == 0 {
+		log.Info("Creating approval status comment.")
+		return gc.CreateComment(org, repo, number, body)
+	}
+	// Keep the first status comment and delete any duplicates.
+	for _, c := range old[1:] {
+		if err := gc.DeleteComment(org, repo, c.ID); err != nil {
+			return err
+		}
+	}
+	if old[0].Body == body {
+		return nil
+	}
+	log.Info("Updating approval status comment.")
+	return gc.EditComment(org, repo, old[0].ID, body)
+}
+
+func statusComment(needed map[string]*ownersStatus, approved bool) string {
+	var paths []string
+	for p := range needed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	if approved {
+		fmt.Fprintln(&b, "This PR is **APPROVED**.")
+	} else {
+		fmt.Fprintln(&b, "This PR is **NOT APPROVED**.")
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "It needs approval from an approver in each of these OWNERS files, or in a parent directory's:")
+	fmt.Fprintln(&b)
+	for _, p := range paths {
+		s := needed[p]
+		if len(s.approvedBy) > 0 {
+			fmt.Fprintf(&b, "- [x] **%s**: approved by %s\n", p, mentions(s.approvedBy))
+		} else if len(s.suggested()) > 0 {
+			fmt.Fprintf(&b, "- [ ] **%s**: suggested approvers %s\n", p, mentions(s.suggested()))
+		} else {
+			fmt.Fprintf(&b, "- [ ] **%s**\n", p)
+		}
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Approvers can approve with `/approve` in a comment, and cancel with `/approve cancel`.")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "<details>\n\n%s\n</details>\n", plugins.AboutThisBot)
+	fmt.Fprint(&b, statusMarker)
+	return b.String()
+}
+
+func mentions(logins []string) string {
+	var ms []string
+	for _, l := range logins {
+		ms = append(ms, "@"+l)
+	}
+	return strings.Join(ms, ", ")
+}
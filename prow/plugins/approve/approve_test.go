@@ -0,0 +1,248 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approve
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const approvedLabel = "approved"
+
+var testConfig = plugins.Approve{Label: approvedLabel}
+
+func TestApprove(t *testing.T) {
+	// "root" approves everything, "pkg" approves pkg/, and nobody owns
+	// docs/ apart from the root.
+	files := map[string]map[string]string{
+		"OWNERS":     {"base": "approvers:\n- root\n"},
+		"pkg/OWNERS": {"base": "approvers:\n- Pkg\nreviewers:\n- rev\n"},
+	}
+	var testcases = []struct {
+		name      string
+		changes   []string
+		comments  []github.IssueComment
+		hasLabel  bool
+		oldStatus bool
+
+		shouldAdd     bool
+		shouldRemove  bool
+		shouldComment bool
+		approved      bool
+	}{
+		{
+			name:          "no approvals",
+			changes:       []string{"pkg/a.go", "docs/b.md"},
+			shouldComment: true,
+		},
+		{
+			name:    "approval for one of two",
+			changes: []string{"pkg/a.go", "docs/b.md"},
+			comments: []github.IssueComment{
+				{Body: "/approve", User: github.User{Login: "pkg"}},
+			},
+			shouldComment: true,
+		},
+		{
+			name:    "parent approver covers both",
+			changes: []string{"pkg/a.go", "docs/b.md"},
+			comments: []github.IssueComment{
+				{Body: "/approve", User: github.User{Login: "root"}},
+			},
+			shouldAdd:     true,
+			shouldComment: true,
+			approved:      true,
+		},
+		{
+			name:    "reviewer can't approve",
+			changes: []string{"pkg/a.go"},
+			comments: []github.IssueComment{
+				{Body: "/approve", User: github.User{Login: "rev"}},
+			},
+			shouldComment: true,
+		},
+		{
+			name:    "approval cancelled",
+			changes: []string{"pkg/a.go"},
+			comments: []github.IssueComment{
+				{Body: "/approve", User: github.User{Login: "pkg"}},
+				{Body: "/approve cancel", User: github.User{Login: "pkg"}},
+			},
+			hasLabel:      true,
+			shouldRemove:  true,
+			shouldComment: true,
+		},
+		{
+			name:    "already approved and labeled",
+			changes: []string{"pkg/a.go"},
+			comments: []github.IssueComment{
+				{Body: "/approve", User: github.User{Login: "pkg"}},
+			},
+			hasLabel:  true,
+			oldStatus: true,
+			approved:  true,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{},
+			PullRequests: map[int]*github.PullRequest{
+				5: {Number: 5, Base: github.PullRequestBranch{SHA: "base"}},
+			},
+			PullRequestChanges: map[int][]github.PullRequestChange{},
+			RemoteFiles:        files,
+			IssueCommentID:     100,
+		}
+		for _, f := range tc.changes {
+			fc.PullRequestChanges[5] = append(fc.PullRequestChanges[5], github.PullRequestChange{Filename: f})
+		}
+		if tc.hasLabel {
+			fc.PullRequests[5].Labels = []github.Label{{Name: approvedLabel}}
+		}
+		fc.IssueComments[5] = tc.comments
+		if tc.oldStatus {
+			// Work out what the status comment would say, then pretend that
+			// the bot already posted it.
+			if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+				t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+			}
+			fc.LabelsAdded = nil
+		}
+		before := len(fc.IssueComments[5])
+		if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldAdd != (len(fc.LabelsAdded) == 1) {
+			t.Errorf("For case %s, expected add %t, got %v.", tc.name, tc.shouldAdd, fc.LabelsAdded)
+		}
+		if tc.shouldRemove != (len(fc.LabelsRemoved) == 1) {
+			t.Errorf("For case %s, expected remove %t, got %v.", tc.name, tc.shouldRemove, fc.LabelsRemoved)
+		}
+		comments := fc.IssueComments[5]
+		if tc.shouldComment != (len(comments) == before+1) {
+			t.Errorf("For case %s, expected comment %t, got %d comments after %d.", tc.name, tc.shouldComment, len(comments), before)
+		}
+		status := comments[len(comments)-1].Body
+		if !strings.Contains(status, statusMarker) {
+			t.Errorf("For case %s, last comment is not the status: %q", tc.name, status)
+		} else if tc.approved != strings.Contains(status, "**APPROVED**") {
+			t.Errorf("For case %s, expected approved %t in status: %q", tc.name, tc.approved, status)
+		}
+	}
+}
+
+func TestStatusCommentReplaced(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{
+			5: {{ID: 1, Body: "old\n" + statusMarker, User: github.User{Login: "k8s-ci-robot"}}},
+		},
+		PullRequests: map[int]*github.PullRequest{
+			5: {Number: 5, Base: github.PullRequestBranch{SHA: "base"}},
+		},
+		PullRequestChanges: map[int][]github.PullRequestChange{
+			5: {{Filename: "a.go"}},
+		},
+		RemoteFiles:    map[string]map[string]string{"OWNERS": {"base": "approvers:\n- root\n"}},
+		IssueCommentID: 100,
+	}
+	if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if cs := fc.IssueComments[5]; len(cs) != 1 || cs[0].ID != 1 || !strings.Contains(cs[0].Body, "**NOT APPROVED**") {
+		t.Errorf("Expected the status comment to be edited in place, got %+v.", cs)
+	}
+}
+
+func TestNoOwners(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{},
+		PullRequests: map[int]*github.PullRequest{
+			5: {Number: 5, Base: github.PullRequestBranch{SHA: "base"}},
+		},
+		PullRequestChanges: map[int][]github.PullRequestChange{
+			5: {{Filename: "a.go"}},
+		},
+	}
+	if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.IssueComments[5]) != 0 || len(fc.LabelsAdded) != 0 {
+		t.Errorf("Expected nothing to happen without OWNERS files, got comments %v and labels %v.", fc.IssueComments[5], fc.LabelsAdded)
+	}
+}
+
+// If a push leaves the PR with no owned files, the old label and status
+// comment go away.
+func TestNoOwnersRemovesStatus(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{
+			5: {{ID: 1, Body: "old\n" + statusMarker, User: github.User{Login: "k8s-ci-robot"}}},
+		},
+		PullRequests: map[int]*github.PullRequest{
+			5: {Number: 5, Base: github.PullRequestBranch{SHA: "base"}, Labels: []github.Label{{Name: approvedLabel}}},
+		},
+		PullRequestChanges: map[int][]github.PullRequestChange{
+			5: {{Filename: "a.go"}},
+		},
+	}
+	if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.IssueComments[5]) != 0 {
+		t.Errorf("Expected the status comment to be deleted, got %v.", fc.IssueComments[5])
+	}
+	if len(fc.LabelsRemoved) != 1 {
+		t.Errorf("Expected the label to be removed, got %v.", fc.LabelsRemoved)
+	}
+}
+
+// Moving a file out of an owned directory needs approval from its owners.
+func TestRenameNeedsOldOwners(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{
+			5: {{Body: "/approve", User: github.User{Login: "docs"}}},
+		},
+		PullRequests: map[int]*github.PullRequest{
+			5: {Number: 5, Base: github.PullRequestBranch{SHA: "base"}},
+		},
+		PullRequestChanges: map[int][]github.PullRequestChange{
+			5: {{Filename: "docs/a.go", PreviousFilename: "pkg/a.go", Status: "renamed"}},
+		},
+		RemoteFiles: map[string]map[string]string{
+			"docs/OWNERS": {"base": "approvers:\n- docs\n"},
+			"pkg/OWNERS":  {"base": "approvers:\n- pkg\n"},
+		},
+		IssueCommentID: 100,
+	}
+	if err := update(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.LabelsAdded) != 0 {
+		t.Errorf("Expected no label without approval for pkg, got %v.", fc.LabelsAdded)
+	}
+	cs := fc.IssueComments[5]
+	if status := cs[len(cs)-1].Body; !strings.Contains(status, "**pkg/OWNERS**: suggested approvers @pkg") {
+		t.Errorf("Expected the status to ask pkg owners, got %q.", status)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package assign implements the assign plugin. It assigns reviewers to new
+// PRs from the OWNERS files of the changed files, preferring whoever has the
+// fewest open PRs assigned. It also handles "/assign" and "/unassign".
+package assign
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/owners"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "assign"
+
+// Looking up a candidate's load costs a search request, so only the first
+// few candidates are considered. Those come from the OWNERS files nearest to
+// the first changed files.
+const maxCandidates = 10
+
+// "/assign" on its own means the commenter.
+var assignRe = regexp.MustCompile(`(?mi)^/(un)?assign((?: +@?[-\w]+)*) *\r?$`)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Assigns reviewers from the OWNERS files to new PRs and handles assigning people by hand.",
+		Commands: []plugins.Command{{
+			Usage:       "/[un]assign [@user ...]",
+			Description: "Assigns or unassigns the users, or yourself if none are given.",
+			Regex:       assignRe.String(),
+			Example:     "/assign @alice",
+			WhoCanUse:   "Anyone",
+		}},
+		Config: map[string]string{
+			"assign.reviewer_count": fmt.Sprintf("%d", *c.Assign.ReviewerCount),
+			"assign.reviewers":      strings.Join(c.Assign.ReviewersFor(org, repo), ", "),
+		},
+	}
+}
+
+type githubClient interface {
+	AddAssignee(owner, repo string, number int, logins []string) error
+	RemoveAssignee(owner, repo string, number int, logins []string) error
+	GetPullRequestChanges(owner, repo string, number int) ([]github.PullRequestChange, error)
+	GetFile(owner, repo, path, commit string) ([]byte, error)
+	CountIssues(query string) (int, error)
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleComment(pc.GitHubClient, pc.Logger, ic)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pc.PluginConfig.Assign, pre)
+}
+
+func handleComment(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+	if ic.Action != "created" {
+		return nil
+	}
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+
+	var add, remove []string
+	for _, m := range assignRe.FindAllStringSubmatch(ic.Comment.Body, -1) {
+		logins := parseLogins(m[2])
+		if len(logins) == 0 {
+			logins = []string{ic.Comment.User.Login}
+		}
+		if m[1] == "" {
+			add = append(add, logins...)
+		} else {
+			remove = append(remove, logins...)
+		}
+	}
+	if len(add) > 0 {
+		log.Infof("Assigning %v.", add)
+		if err := gc.AddAssignee(org, repo, number, add); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		log.Infof("Unassigning %v.", remove)
+		if err := gc.RemoveAssignee(org, repo, number, remove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseLogins(s string) []string {
+	var logins []string
+	for _, f := range strings.Fields(s) {
+		logins = append(logins, strings.TrimPrefix(f, "@"))
+	}
+	return logins
+}
+
+func handlePR(gc githubClient, log *logrus.Entry, cfg plugins.Assign, pre github.PullRequestEvent) error {
+	// Leave PRs alone if the author already picked reviewers.
+	if pre.Action != "opened" || len(pre.PullRequest.Assignees) > 0 || *cfg.ReviewerCount == 0 {
+		return nil
+	}
+	pr := pre.PullRequest
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+
+	candidates, err := ownersReviewers(gc, org, repo, pr)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		candidates = cfg.ReviewersFor(org, repo)
+	}
+	var logins []string
+	for _, c := range candidates {
+		if !strings.EqualFold(c, pr.User.Login) {
+			logins = append(logins, c)
+		}
+	}
+	if len(logins) == 0 {
+		return nil
+	}
+	if len(logins) > maxCandidates {
+		logins = logins[:maxCandidates]
+	}
+
+	reviewers, err := leastLoaded(gc, org, repo, logins, *cfg.ReviewerCount)
+	if err != nil {
+		return err
+	}
+	log.Infof("Assigning reviewers %v.", reviewers)
+	return gc.AddAssignee(org, repo, pr.Number, reviewers)
+}
+
+// ownersReviewers returns the reviewers from the nearest OWNERS file that
+// lists any for each file that the PR changes.
+func ownersReviewers(gc githubClient, org, repo string, pr github.PullRequest) ([]string, error) {
+	changes, err := gc.GetPullRequestChanges(org, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+	l := owners.NewLoader(gc, org, repo, pr.Base.SHA)
+	seen := map[string]bool{}
+	var reviewers []string
+	for _, c := range changes {
+		fs, err := l.For(c.Filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			if len(f.Reviewers) == 0 {
+				continue
+			}
+			for _, r := range f.Reviewers {
+				if !seen[strings.ToLower(r)] {
+					seen[strings.ToLower(r)] = true
+					reviewers = append(reviewers, r)
+				}
+			}
+			break
+		}
+	}
+	return reviewers, nil
+}
+
+// leastLoaded returns up to n of the logins with the fewest open PRs assigned
+// in the repo. Ties go in alphabetical order so that the choice is stable.
+func leastLoaded(gc githubClient, org, repo string, logins []string, n int) ([]string, error) {
+	load := map[string]int{}
+	for _, l := range logins {
+		count, err := gc.CountIssues(url.QueryEscape(fmt.Sprintf("is:pr is:open repo:%s/%s assignee:%s", org, repo, l)))
+		if err != nil {
+			return nil, err
+		}
+		load[l] = count
+	}
+	sorted := byLoad{logins: append([]string{}, logins...), load: load}
+	sort.Sort(sorted)
+	if len(sorted.logins) > n {
+		return sorted.logins[:n], nil
+	}
+	return sorted.logins, nil
+}
+
+type byLoad struct {
+	logins []string
+	load   map[string]int
+}
+
+func (a byLoad) Len() int      { return len(a.logins) }
+func (a byLoad) Swap(i, j int) { a.logins[i], a.logins[j] = a.logins[j], a.logins[i] }
+func (a byLoad) Less(i, j int) bool {
+	if li, lj := a.load[a.logins[i]], a.load[a.logins[j]]; li != lj {
+		return li < lj
+	}
+	return a.logins[i] < a.logins[j]
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assign
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// fakeClient knows how many open PRs each login has assigned.
+type fakeClient struct {
+	*fakegithub.FakeClient
+	load    map[string]int
+	queries int
+}
+
+func (f *fakeClient) CountIssues(query string) (int, error) {
+	f.queries++
+	q, err := url.QueryUnescape(query)
+	if err != nil {
+		return 0, err
+	}
+	i := strings.Index(q, "assignee:")
+	return f.load[q[i+len("assignee:"):]], nil
+}
+
+func TestAssignComment(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		action  string
+		body    string
+		added   []string
+		removed []string
+	}{
+		{
+			name:   "unrelated comment",
+			action: "created",
+			body:   "please assign someone",
+		},
+		{
+			name:   "assign self",
+			action: "created",
+			body:   "/assign",
+			added:  []string{"org/repo#5:commenter"},
+		},
+		{
+			name:   "assign others",
+			action: "created",
+			body:   "/assign @a b",
+			added:  []string{"org/repo#5:a", "org/repo#5:b"},
+		},
+		{
+			name:    "unassign self",
+			action:  "created",
+			body:    "/unassign",
+			removed: []string{"org/repo#5:commenter"},
+		},
+		{
+			name:    "assign and unassign",
+			action:  "created",
+			body:    "/assign @a\n/unassign @b",
+			added:   []string{"org/repo#5:a"},
+			removed: []string{"org/repo#5:b"},
+		},
+		{
+			name:   "edited comment",
+			action: "edited",
+			body:   "/assign",
+		},
+		{
+			name:   "not at the start of a line",
+			action: "created",
+			body:   "try /assign",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		ic := github.IssueCommentEvent{
+			Action: tc.action,
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+			Issue: github.Issue{Number: 5},
+			Comment: github.IssueComment{
+				Body: tc.body,
+				User: github.User{Login: "commenter"},
+			},
+		}
+		if err := handleComment(fc, logrus.WithField("plugin", pluginName), ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.added, fc.AssigneesAdded) {
+			t.Errorf("For case %s, expected %v added, got %v.", tc.name, tc.added, fc.AssigneesAdded)
+		}
+		if !reflect.DeepEqual(tc.removed, fc.AssigneesRemoved) {
+			t.Errorf("For case %s, expected %v removed, got %v.", tc.name, tc.removed, fc.AssigneesRemoved)
+		}
+	}
+}
+
+func TestAssignReviewers(t *testing.T) {
+	files := map[string]map[string]string{
+		"OWNERS":     {"base": "reviewers:\n- root1\n- root2\n"},
+		"pkg/OWNERS": {"base": "approvers:\n- pkgapprover\n"},
+		"cmd/OWNERS": {"base": "reviewers:\n- cmd1\n- cmd2\n- author\n"},
+	}
+	var testcases = []struct {
+		name      string
+		action    string
+		changes   []string
+		assignees []github.User
+		owners    map[string]map[string]string
+		load      map[string]int
+		disabled  bool
+		added     []string
+	}{
+		{
+			name:    "nearest reviewers, least loaded first",
+			action:  "opened",
+			changes: []string{"cmd/main.go"},
+			owners:  files,
+			load:    map[string]int{"cmd1": 5, "cmd2": 1},
+			added:   []string{"org/repo#5:cmd2", "org/repo#5:cmd1"},
+		},
+		{
+			name:    "ties in alphabetical order",
+			action:  "opened",
+			changes: []string{"cmd/main.go", "README.md"},
+			owners:  files,
+			load:    map[string]int{"root2": 1, "cmd2": 1},
+			added:   []string{"org/repo#5:cmd1", "org/repo#5:root1"},
+		},
+		{
+			name:    "skips OWNERS files without reviewers",
+			action:  "opened",
+			changes: []string{"pkg/a.go"},
+			owners:  files,
+			added:   []string{"org/repo#5:root1", "org/repo#5:root2"},
+		},
+		{
+			name:    "falls back on configured reviewers",
+			action:  "opened",
+			changes: []string{"pkg/a.go"},
+			load:    map[string]int{"team1": 3},
+			added:   []string{"org/repo#5:team2", "org/repo#5:team1"},
+		},
+		{
+			name:      "already assigned",
+			action:    "opened",
+			changes:   []string{"cmd/main.go"},
+			assignees: []github.User{{Login: "someone"}},
+			owners:    files,
+		},
+		{
+			name:    "not opened",
+			action:  "synchronize",
+			changes: []string{"cmd/main.go"},
+			owners:  files,
+		},
+		{
+			name:     "turned off",
+			action:   "opened",
+			changes:  []string{"cmd/main.go"},
+			owners:   files,
+			disabled: true,
+		},
+	}
+	for _, tc := range testcases {
+		count := 2
+		if tc.disabled {
+			count = 0
+		}
+		cfg := plugins.Assign{
+			ReviewerCount: &count,
+			Reviewers:     map[string][]string{"org": {"team1", "team2", "author"}},
+		}
+		fc := &fakeClient{
+			FakeClient: &fakegithub.FakeClient{
+				PullRequestChanges: map[int][]github.PullRequestChange{},
+				RemoteFiles:        tc.owners,
+			},
+			load: tc.load,
+		}
+		for _, f := range tc.changes {
+			fc.PullRequestChanges[5] = append(fc.PullRequestChanges[5], github.PullRequestChange{Filename: f})
+		}
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 5,
+			PullRequest: github.PullRequest{
+				Number: 5,
+				User:   github.User{Login: "author"},
+				Base: github.PullRequestBranch{
+					SHA: "base",
+					Repo: github.Repo{
+						Owner: github.User{Login: "org"},
+						Name:  "repo",
+					},
+				},
+				Assignees: tc.assignees,
+			},
+		}
+		if err := handlePR(fc, logrus.WithField("plugin", pluginName), cfg, pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.added, fc.AssigneesAdded) {
+			t.Errorf("For case %s, expected %v added, got %v.", tc.name, tc.added, fc.AssigneesAdded)
+		}
+	}
+}
+
+func TestAssignReviewersCapsCandidates(t *testing.T) {
+	var reviewers []string
+	for i := 0; i < 3*maxCandidates; i++ {
+		reviewers = append(reviewers, fmt.Sprintf("r%02d", i))
+	}
+	fc := &fakeClient{
+		FakeClient: &fakegithub.FakeClient{
+			PullRequestChanges: map[int][]github.PullRequestChange{5: {{Filename: "a.go"}}},
+			RemoteFiles: map[string]map[string]string{
+				"OWNERS": {"base": "reviewers:\n- " + strings.Join(reviewers, "\n- ") + "\n"},
+			},
+		},
+	}
+	count := 2
+	pre := github.PullRequestEvent{
+		Action: "opened",
+		Number: 5,
+		PullRequest: github.PullRequest{
+			Number: 5,
+			User:   github.User{Login: "author"},
+			Base: github.PullRequestBranch{
+				SHA: "base",
+				Repo: github.Repo{
+					Owner: github.User{Login: "org"},
+					Name:  "repo",
+				},
+			},
+		},
+	}
+	if err := handlePR(fc, logrus.WithField("plugin", pluginName), plugins.Assign{ReviewerCount: &count}, pre); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if fc.queries != maxCandidates {
+		t.Errorf("Expected %d load lookups, got %d.", maxCandidates, fc.queries)
+	}
+	if len(fc.AssigneesAdded) != count {
+		t.Errorf("Expected %d reviewers, got %v.", count, fc.AssigneesAdded)
+	}
+}
@@ -25,8 +25,21 @@ import (
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
 )
 
+const (
+	claYesLabel = "cncf-cla: yes"
+	claNoLabel  = "cncf-cla: no"
+)
+
+var testConfig = plugins.CLA{
+	Context:         "cla/linuxfoundation",
+	YesLabel:        claYesLabel,
+	NoLabel:         claNoLabel,
+	NotFoundMessage: "Please sign the CLA.",
+}
+
 func TestCLALabels(t *testing.T) {
 	var testcases = []struct {
 		name          string
@@ -156,7 +169,7 @@ func TestCLALabels(t *testing.T) {
 			SHA:     tc.statusSHA,
 			State:   tc.state,
 		}
-		if err := handle(fc, logrus.WithField("plugin", pluginName), se); err != nil {
+		if err := handle(fc, logrus.WithField("plugin", pluginName), testConfig, se); err != nil {
 			t.Errorf("For case %s, didn't expect error from cla plugin: %v", tc.name, err)
 			continue
 		}
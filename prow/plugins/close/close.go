@@ -17,6 +17,7 @@ limitations under the License.
 package close
 
 import (
+	"fmt"
 	"regexp"
 
 	"github.com/Sirupsen/logrus"
@@ -27,44 +28,125 @@ import (
 
 const pluginName = "close"
 
-var closeRe = regexp.MustCompile(`(?mi)^\/close\r?$`)
+var (
+	closeRe  = regexp.MustCompile(`(?mi)^\/close\r?$`)
+	reopenRe = regexp.MustCompile(`(?mi)^\/reopen\r?$`)
+)
 
 func init() {
-	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Closes and reopens issues and PRs.",
+		Commands: []plugins.Command{
+			{
+				Usage:       "/close",
+				Description: "Closes the issue or PR.",
+				Regex:       closeRe.String(),
+				Example:     "/close",
+				WhoCanUse:   c.WhoCanUse("close", org, repo),
+			},
+			{
+				Usage:       "/reopen",
+				Description: "Reopens the issue or PR, unless its branch was deleted.",
+				Regex:       reopenRe.String(),
+				Example:     "/reopen",
+				WhoCanUse:   c.WhoCanUse("reopen", org, repo),
+			},
+		},
+	}
 }
 
 type githubClient interface {
 	CreateComment(owner, repo string, number int, comment string) error
 	CloseIssue(owner, repo string, number int) error
+	ReopenIssue(owner, repo string, number int) error
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	GetRef(owner, repo, ref string) (string, error)
 }
 
 func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
-	return handle(pc.GitHubClient, pc.Logger, ic)
+	auth := plugins.NewAuthorizer(pc.GitHubClient, pc.PluginConfig, ic.Repo.Owner.Login, ic.Repo.Name)
+	return handle(pc.GitHubClient, pc.Logger, auth, ic)
 }
 
-func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
-	// Only consider open issues and new comments.
-	if ic.Issue.State != "open" || ic.Issue.IsPullRequest() || ic.Action != "created" {
+func handle(gc githubClient, log *logrus.Entry, auth *plugins.Authorizer, ic github.IssueCommentEvent) error {
+	// Only consider new comments.
+	if ic.Action != "created" {
 		return nil
 	}
-
-	if !closeRe.MatchString(ic.Comment.Body) {
+	var reopen bool
+	if ic.Issue.State == "open" && closeRe.MatchString(ic.Comment.Body) {
+		reopen = false
+	} else if ic.Issue.State == "closed" && reopenRe.MatchString(ic.Comment.Body) {
+		reopen = true
+	} else {
 		return nil
 	}
 
 	org := ic.Repo.Owner.Login
 	repo := ic.Repo.Name
 	number := ic.Issue.Number
+	kind := "an issue"
+	if ic.Issue.IsPullRequest() {
+		kind = "a PR"
+	}
+	verb := "close"
+	if reopen {
+		verb = "reopen"
+	}
 
-	commentAuthor := ic.Comment.User.Login
-
-	// Allow assignees and authors to close issues.
-	if !ic.Issue.IsAuthor(commentAuthor) && !ic.Issue.IsAssignee(commentAuthor) {
-		resp := "you can't close an issue unless you authored it or you are assigned to it"
+	allowed, err := auth.Allowed(verb, ic.Comment.User.Login, ic.Issue)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		resp := fmt.Sprintf("you can't %s %s unless you are %s", verb, kind, auth.WhoCanUse(verb))
 		log.Infof("Commenting \"%s\".", resp)
 		return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
 	}
 
-	log.Info("Closing issue.")
-	return gc.CloseIssue(org, repo, number)
+	if !reopen {
+		log.Info("Closing issue.")
+		return gc.CloseIssue(org, repo, number)
+	}
+	if ic.Issue.IsPullRequest() {
+		// GitHub won't reopen some PRs, so explain why.
+		why, err := cantReopen(gc, org, repo, number)
+		if err != nil {
+			return err
+		}
+		if why != "" {
+			resp := "you can't reopen this PR because " + why
+			log.Infof("Commenting \"%s\".", resp)
+			return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
+		}
+	}
+	log.Info("Reopening issue.")
+	return gc.ReopenIssue(org, repo, number)
+}
+
+// cantReopen returns why GitHub won't reopen the PR, or "" if it will. It
+// won't reopen a merged PR, or one whose head branch or fork no longer
+// exists.
+func cantReopen(gc githubClient, org, repo string, number int) (string, error) {
+	pr, err := gc.GetPullRequest(org, repo, number)
+	if err != nil {
+		return "", err
+	}
+	if pr.Merged {
+		return "it was already merged", nil
+	}
+	deleted := "its branch was deleted. Push the branch again and open a new PR"
+	// GitHub leaves out the head repo once the fork is deleted.
+	if pr.Head.Repo.Name == "" {
+		return deleted, nil
+	}
+	_, err = gc.GetRef(pr.Head.Repo.Owner.Login, pr.Head.Repo.Name, "heads/"+pr.Head.Ref)
+	if _, ok := err.(*github.RefNotFound); ok {
+		return deleted, nil
+	}
+	return "", err
 }
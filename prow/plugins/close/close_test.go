@@ -22,11 +22,19 @@ import (
 	"github.com/Sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
 )
 
 type fakeClient struct {
+	*fakegithub.FakeClient
 	commented bool
 	closed    bool
+	reopened  bool
+
+	pr *github.PullRequest
+	// Refs that exist in the head repo.
+	refs []string
 }
 
 func (c *fakeClient) CreateComment(owner, repo string, number int, comment string) error {
@@ -39,6 +47,24 @@ func (c *fakeClient) CloseIssue(owner, repo string, number int) error {
 	return nil
 }
 
+func (c *fakeClient) ReopenIssue(owner, repo string, number int) error {
+	c.reopened = true
+	return nil
+}
+
+func (c *fakeClient) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
+	return c.pr, nil
+}
+
+func (c *fakeClient) GetRef(owner, repo, ref string) (string, error) {
+	for _, r := range c.refs {
+		if r == ref {
+			return "abcde", nil
+		}
+	}
+	return "", &github.RefNotFound{Org: owner, Repo: repo, Ref: ref}
+}
+
 func TestCloseComment(t *testing.T) {
 	// "a" is the author, "r1", and "r2" are reviewers.
 	var testcases = []struct {
@@ -47,6 +73,8 @@ func TestCloseComment(t *testing.T) {
 		state         string
 		body          string
 		commenter     string
+		pr            bool
+		orgMembers    bool
 		shouldClose   bool
 		shouldComment bool
 	}{
@@ -104,9 +132,44 @@ func TestCloseComment(t *testing.T) {
 			shouldClose:   false,
 			shouldComment: true,
 		},
+		{
+			name:          "close PR by author",
+			action:        "created",
+			state:         "open",
+			body:          "/close",
+			commenter:     "a",
+			pr:            true,
+			shouldClose:   true,
+			shouldComment: false,
+		},
+		{
+			name:          "close by org member",
+			action:        "created",
+			state:         "open",
+			body:          "/close",
+			commenter:     "m",
+			orgMembers:    true,
+			shouldClose:   true,
+			shouldComment: false,
+		},
+		{
+			name:          "close by org member when only authors and assignees may",
+			action:        "created",
+			state:         "open",
+			body:          "/close",
+			commenter:     "m",
+			shouldClose:   false,
+			shouldComment: true,
+		},
 	}
 	for _, tc := range testcases {
-		fc := &fakeClient{}
+		fc := &fakeClient{FakeClient: &fakegithub.FakeClient{OrgMembers: []string{"m"}}}
+		cfg := &plugins.Configuration{}
+		if tc.orgMembers {
+			cfg.Authorization = map[string]plugins.Policy{
+				"close": {Roles: []string{plugins.RoleAuthor, plugins.RoleAssignee, plugins.RoleOrgMember}, Orgs: []string{"org"}},
+			}
+		}
 		ice := github.IssueCommentEvent{
 			Action: tc.action,
 			Comment: github.IssueComment{
@@ -120,7 +183,10 @@ func TestCloseComment(t *testing.T) {
 				Assignees: []github.User{{Login: "a"}, {Login: "r1"}, {Login: "r2"}},
 			},
 		}
-		if err := handle(fc, logrus.WithField("plugin", pluginName), ice); err != nil {
+		if tc.pr {
+			ice.Issue.PullRequest = &struct{}{}
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), plugins.NewAuthorizer(fc, cfg, "org", "repo"), ice); err != nil {
 			t.Errorf("For case %s, didn't expect error from handle: %v", tc.name, err)
 			continue
 		}
@@ -136,3 +202,110 @@ func TestCloseComment(t *testing.T) {
 		}
 	}
 }
+
+func TestReopenComment(t *testing.T) {
+	// "a" is the author and "r1" is an assignee.
+	var testcases = []struct {
+		name          string
+		state         string
+		body          string
+		commenter     string
+		pr            bool
+		forkDeleted   bool
+		merged        bool
+		refs          []string
+		shouldReopen  bool
+		shouldComment bool
+	}{
+		{
+			name:         "reopen by author",
+			state:        "closed",
+			body:         "/reopen",
+			commenter:    "a",
+			shouldReopen: true,
+		},
+		{
+			name:      "reopen an open issue",
+			state:     "open",
+			body:      "/reopen",
+			commenter: "a",
+		},
+		{
+			name:          "reopen by other person",
+			state:         "closed",
+			body:          "/reopen",
+			commenter:     "o",
+			shouldComment: true,
+		},
+		{
+			name:         "reopen PR by assignee",
+			state:        "closed",
+			body:         "/reopen",
+			commenter:    "r1",
+			pr:           true,
+			refs:         []string{"heads/feature"},
+			shouldReopen: true,
+		},
+		{
+			name:          "reopen PR with deleted branch",
+			state:         "closed",
+			body:          "/reopen",
+			commenter:     "a",
+			pr:            true,
+			shouldComment: true,
+		},
+		{
+			name:          "reopen PR with deleted fork",
+			state:         "closed",
+			body:          "/reopen",
+			commenter:     "a",
+			pr:            true,
+			forkDeleted:   true,
+			shouldComment: true,
+		},
+		{
+			name:          "reopen merged PR",
+			state:         "closed",
+			body:          "/reopen",
+			commenter:     "a",
+			pr:            true,
+			merged:        true,
+			refs:          []string{"heads/feature"},
+			shouldComment: true,
+		},
+	}
+	for _, tc := range testcases {
+		pr := &github.PullRequest{Merged: tc.merged}
+		pr.Head.Ref = "feature"
+		if !tc.forkDeleted {
+			pr.Head.Repo = github.Repo{Owner: github.User{Login: "a"}, Name: "repo"}
+		}
+		fc := &fakeClient{FakeClient: &fakegithub.FakeClient{}, pr: pr, refs: tc.refs}
+		ice := github.IssueCommentEvent{
+			Action: "created",
+			Comment: github.IssueComment{
+				Body: tc.body,
+				User: github.User{Login: tc.commenter},
+			},
+			Issue: github.Issue{
+				User:      github.User{Login: "a"},
+				Number:    5,
+				State:     tc.state,
+				Assignees: []github.User{{Login: "r1"}},
+			},
+		}
+		if tc.pr {
+			ice.Issue.PullRequest = &struct{}{}
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), plugins.NewAuthorizer(fc, &plugins.Configuration{}, "org", "repo"), ice); err != nil {
+			t.Errorf("For case %s, didn't expect error from handle: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldReopen != fc.reopened {
+			t.Errorf("For case %s, expected reopened to be %t, got %t.", tc.name, tc.shouldReopen, fc.reopened)
+		}
+		if tc.shouldComment != fc.commented {
+			t.Errorf("For case %s, expected commented to be %t, got %t.", tc.name, tc.shouldComment, fc.commented)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"sort"
+)
+
+var helpProviders = map[string]HelpProvider{}
+
+// PluginHelp describes what a plugin does and how to use it.
+type PluginHelp struct {
+	// What the plugin does, in a sentence or two of Markdown.
+	Description string `json:"description"`
+	// Comment commands that the plugin handles.
+	Commands []Command `json:"commands,omitempty"`
+	// Config file options that affect the plugin -> their values.
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// Command is a comment command that a plugin handles.
+type Command struct {
+	// Short form of the command, such as "/hold [cancel]".
+	Usage string `json:"usage"`
+	// What the command does.
+	Description string `json:"description"`
+	// Regexp that comments are matched against.
+	Regex string `json:"regex"`
+	// Example comment.
+	Example string `json:"example"`
+	// Who may use the command, such as "Anyone" or "PR authors".
+	WhoCanUse string `json:"who_can_use"`
+}
+
+// HelpProvider returns the help for a plugin given the configuration. Org
+// and repo are the repo the help is for, and are empty when it is for any
+// repo.
+type HelpProvider func(c *Configuration, org, repo string) PluginHelp
+
+// registerHelp records the help for a plugin. Each plugin passes the same
+// help to every handler that it registers.
+func registerHelp(name string, help HelpProvider) {
+	if help != nil {
+		helpProviders[name] = help
+	}
+}
+
+// Help is the help for the plugins enabled for a repo.
+type Help struct {
+	// Orgs and repos that have plugins.
+	Repos []string `json:"repos"`
+	// Plugin name -> help.
+	Plugins map[string]PluginHelp `json:"plugins"`
+}
+
+// Help returns the help for every plugin enabled for the repo, or for every
+// registered plugin if org and repo are empty. Plugins without help get an
+// empty PluginHelp.
+func (pa *PluginAgent) Help(org, repo string) Help {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	h := Help{Plugins: map[string]PluginHelp{}}
+	for k := range pa.configuration.Plugins {
+		h.Repos = append(h.Repos, k)
+	}
+	sort.Strings(h.Repos)
+
+	var names []string
+	if org == "" && repo == "" {
+		for p := range allPlugins {
+			names = append(names, p)
+		}
+	} else {
+		names = pa.getPlugins(org, repo)
+	}
+	for _, p := range names {
+		var ph PluginHelp
+		if hp, ok := helpProviders[p]; ok {
+			ph = hp(pa.configuration, org, repo)
+		}
+		h.Plugins[p] = ph
+	}
+	return h
+}
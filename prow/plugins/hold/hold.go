@@ -0,0 +1,169 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hold implements the hold plugin, which keeps PRs from merging while
+// someone has asked to hold them with "/hold" or while their titles start
+// with "WIP". Held PRs get a label and a pending status.
+package hold
+
+import (
+	"regexp"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "hold"
+
+var (
+	holdRe       = regexp.MustCompile(`(?mi)^/hold\r?$`)
+	holdCancelRe = regexp.MustCompile(`(?mi)^/hold cancel\r?$`)
+	// Matches "WIP", "WIP:", and "[WIP]", but not "WIPE".
+	wipRe = regexp.MustCompile(`(?i)^\[?WIP\b`)
+)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Keeps PRs from merging while they are held or their titles start with WIP, by setting the " + c.Hold.Context + " status to pending.",
+		Commands: []plugins.Command{{
+			Usage:       "/hold [cancel]",
+			Description: "Adds or removes the " + c.Hold.HoldLabel + " label.",
+			Regex:       holdRe.String(),
+			Example:     "/hold",
+			WhoCanUse:   "Anyone",
+		}},
+		Config: map[string]string{
+			"hold.hold_label": c.Hold.HoldLabel,
+			"hold.wip_label":  c.Hold.WIPLabel,
+			"hold.context":    c.Hold.Context,
+		},
+	}
+}
+
+type githubClient interface {
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	CreateStatus(owner, repo, ref string, s github.Status) error
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleComment(pc.GitHubClient, pc.Logger, pc.PluginConfig.Hold, ic)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pc.PluginConfig.Hold, pre)
+}
+
+func handleComment(gc githubClient, log *logrus.Entry, cfg plugins.Hold, ic github.IssueCommentEvent) error {
+	if !ic.Issue.IsPullRequest() || ic.Issue.State != "open" || ic.Action != "created" {
+		return nil
+	}
+	var wantHold bool
+	if holdRe.MatchString(ic.Comment.Body) {
+		wantHold = true
+	} else if holdCancelRe.MatchString(ic.Comment.Body) {
+		wantHold = false
+	} else {
+		return nil
+	}
+
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	// We need the head SHA for the status.
+	pr, err := gc.GetPullRequest(org, repo, ic.Issue.Number)
+	if err != nil {
+		return err
+	}
+	labels := labelSet(pr.Labels)
+	if err := setLabel(gc, log, org, repo, pr.Number, labels, cfg.HoldLabel, wantHold); err != nil {
+		return err
+	}
+	return setStatus(gc, log, cfg, org, repo, pr.Head.SHA, labels)
+}
+
+func handlePR(gc githubClient, log *logrus.Entry, cfg plugins.Hold, pre github.PullRequestEvent) error {
+	pr := pre.PullRequest
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+	labels := labelSet(pr.Labels)
+	switch pre.Action {
+	case "opened", "reopened", "edited":
+		if err := setLabel(gc, log, org, repo, pr.Number, labels, cfg.WIPLabel, wipRe.MatchString(pr.Title)); err != nil {
+			return err
+		}
+	case "synchronize":
+		// The new commit needs a status too.
+	case "labeled", "unlabeled":
+		// Someone may have added or removed one of our labels by hand.
+		if pre.Label.Name != cfg.HoldLabel && pre.Label.Name != cfg.WIPLabel {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return setStatus(gc, log, cfg, org, repo, pr.Head.SHA, labels)
+}
+
+func labelSet(ls []github.Label) map[string]bool {
+	labels := map[string]bool{}
+	for _, l := range ls {
+		labels[l.Name] = true
+	}
+	return labels
+}
+
+// setLabel adds or removes the label if necessary, and updates labels to
+// match.
+func setLabel(gc githubClient, log *logrus.Entry, org, repo string, number int, labels map[string]bool, label string, want bool) error {
+	if want && !labels[label] {
+		log.Infof("Adding %s label.", label)
+		if err := gc.AddLabel(org, repo, number, label); err != nil {
+			return err
+		}
+	} else if !want && labels[label] {
+		log.Infof("Removing %s label.", label)
+		if err := gc.RemoveLabel(org, repo, number, label); err != nil {
+			return err
+		}
+	}
+	labels[label] = want
+	return nil
+}
+
+func setStatus(gc githubClient, log *logrus.Entry, cfg plugins.Hold, org, repo, sha string, labels map[string]bool) error {
+	s := github.Status{
+		State:       github.StatusSuccess,
+		Description: "Not on hold.",
+		Context:     cfg.Context,
+	}
+	if labels[cfg.HoldLabel] {
+		s.State = github.StatusPending
+		s.Description = "On hold. Comment /hold cancel to release it."
+	} else if labels[cfg.WIPLabel] {
+		s.State = github.StatusPending
+		s.Description = "Work in progress. Remove WIP from the title when it is ready."
+	}
+	log.Infof("Setting %s status to %s.", cfg.Context, s.State)
+	return gc.CreateStatus(org, repo, sha, s)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hold
+
+import (
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	holdLabel   = "do-not-merge/hold"
+	wipLabel    = "do-not-merge/work-in-progress"
+	holdContext = "do-not-merge"
+)
+
+var testConfig = plugins.Hold{
+	HoldLabel: holdLabel,
+	WIPLabel:  wipLabel,
+	Context:   holdContext,
+}
+
+func TestHoldComment(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		body         string
+		labels       []string
+		shouldAdd    bool
+		shouldRemove bool
+		state        string
+	}{
+		{
+			name: "unrelated comment",
+			body: "please hold on",
+		},
+		{
+			name:      "hold",
+			body:      "/hold",
+			shouldAdd: true,
+			state:     github.StatusPending,
+		},
+		{
+			name:   "hold, already held",
+			body:   "/hold",
+			labels: []string{holdLabel},
+			state:  github.StatusPending,
+		},
+		{
+			name:         "cancel hold",
+			body:         "/hold cancel",
+			labels:       []string{holdLabel},
+			shouldRemove: true,
+			state:        github.StatusSuccess,
+		},
+		{
+			name:         "cancel hold, still WIP",
+			body:         "/hold cancel",
+			labels:       []string{holdLabel, wipLabel},
+			shouldRemove: true,
+			state:        github.StatusPending,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			PullRequests: map[int]*github.PullRequest{
+				5: {Number: 5, Head: github.PullRequestBranch{SHA: "head"}},
+			},
+		}
+		for _, l := range tc.labels {
+			fc.PullRequests[5].Labels = append(fc.PullRequests[5].Labels, github.Label{Name: l})
+		}
+		ic := github.IssueCommentEvent{
+			Action: "created",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+			Issue: github.Issue{
+				Number:      5,
+				State:       "open",
+				PullRequest: &struct{}{},
+			},
+			Comment: github.IssueComment{Body: tc.body},
+		}
+		if err := handleComment(fc, logrus.WithField("plugin", pluginName), testConfig, ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldAdd != (len(fc.LabelsAdded) == 1) {
+			t.Errorf("For case %s, expected add %t, got %v.", tc.name, tc.shouldAdd, fc.LabelsAdded)
+		}
+		if tc.shouldRemove != (len(fc.LabelsRemoved) == 1) {
+			t.Errorf("For case %s, expected remove %t, got %v.", tc.name, tc.shouldRemove, fc.LabelsRemoved)
+		}
+		checkStatus(t, tc.name, fc, tc.state)
+	}
+}
+
+func TestHoldPullRequest(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		action       string
+		title        string
+		labels       []string
+		label        string
+		shouldAdd    bool
+		shouldRemove bool
+		state        string
+	}{
+		{
+			name:      "opened as WIP",
+			action:    "opened",
+			title:     "[WIP] Add a thing",
+			shouldAdd: true,
+			state:     github.StatusPending,
+		},
+		{
+			name:      "lower case wip",
+			action:    "opened",
+			title:     "wip: add a thing",
+			shouldAdd: true,
+			state:     github.StatusPending,
+		},
+		{
+			name:   "opened, not WIP",
+			action: "opened",
+			title:  "Wipe the cache",
+			state:  github.StatusSuccess,
+		},
+		{
+			name:         "WIP removed from the title",
+			action:       "edited",
+			title:        "Add a thing",
+			labels:       []string{wipLabel},
+			shouldRemove: true,
+			state:        github.StatusSuccess,
+		},
+		{
+			name:   "new commit on a held PR",
+			action: "synchronize",
+			title:  "Add a thing",
+			labels: []string{holdLabel},
+			state:  github.StatusPending,
+		},
+		{
+			name:   "hold label removed by hand",
+			action: "unlabeled",
+			title:  "Add a thing",
+			label:  holdLabel,
+			state:  github.StatusSuccess,
+		},
+		{
+			name:   "other label",
+			action: "labeled",
+			title:  "Add a thing",
+			label:  "lgtm",
+		},
+		{
+			name:   "closed",
+			action: "closed",
+			title:  "WIP",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 5,
+			PullRequest: github.PullRequest{
+				Number: 5,
+				Title:  tc.title,
+				Base: github.PullRequestBranch{
+					Repo: github.Repo{
+						Owner: github.User{Login: "org"},
+						Name:  "repo",
+					},
+				},
+				Head: github.PullRequestBranch{SHA: "head"},
+			},
+			Label: github.Label{Name: tc.label},
+		}
+		for _, l := range tc.labels {
+			pre.PullRequest.Labels = append(pre.PullRequest.Labels, github.Label{Name: l})
+		}
+		if err := handlePR(fc, logrus.WithField("plugin", pluginName), testConfig, pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldAdd != (len(fc.LabelsAdded) == 1) {
+			t.Errorf("For case %s, expected add %t, got %v.", tc.name, tc.shouldAdd, fc.LabelsAdded)
+		}
+		if tc.shouldRemove != (len(fc.LabelsRemoved) == 1) {
+			t.Errorf("For case %s, expected remove %t, got %v.", tc.name, tc.shouldRemove, fc.LabelsRemoved)
+		}
+		checkStatus(t, tc.name, fc, tc.state)
+	}
+}
+
+// checkStatus checks that the plugin set the status of the head commit to
+// the state, or left it alone if the state is empty.
+func checkStatus(t *testing.T, name string, fc *fakegithub.FakeClient, state string) {
+	ss := fc.CreatedStatuses["head"]
+	if state == "" {
+		if len(ss) != 0 {
+			t.Errorf("For case %s, expected no status, got %v.", name, ss)
+		}
+		return
+	}
+	if len(ss) != 1 {
+		t.Errorf("For case %s, expected one status, got %v.", name, ss)
+	} else if ss[0].State != state || ss[0].Context != holdContext {
+		t.Errorf("For case %s, expected %s %s, got %+v.", name, holdContext, state, ss[0])
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package label implements the label plugin, which adds and removes kind,
+// area, and priority labels when asked to in a comment.
+package label
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "label"
+
+// "/kind bug" adds kind/bug and "/remove-kind bug" removes it. A command may
+// list more than one label.
+var labelRe = regexp.MustCompile(`(?mi)^/(remove-)?(kind|area|priority)((?: +[-\w./]+)+) *\r?$`)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Adds and removes kind, area, and priority labels that already exist in the repo.",
+		Commands: []plugins.Command{{
+			Usage:       "/[remove-](kind|area|priority) <label> ...",
+			Description: "Adds or removes the labels, such as kind/bug for \"/kind bug\".",
+			Regex:       labelRe.String(),
+			Example:     "/kind bug",
+			WhoCanUse:   "Anyone",
+		}},
+		Config: map[string]string{"label.exclusive_prefixes": strings.Join(c.Label.ExclusivePrefixes, ", ")},
+	}
+}
+
+type githubClient interface {
+	CreateComment(owner, repo string, number int, comment string) error
+	ListLabels(owner, repo string) ([]github.Label, error)
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handle(pc.GitHubClient, pc.Logger, pc.PluginConfig.Label, ic)
+}
+
+func handle(gc githubClient, log *logrus.Entry, cfg plugins.Label, ic github.IssueCommentEvent) error {
+	if ic.Action != "created" {
+		return nil
+	}
+	matches := labelRe.FindAllStringSubmatch(ic.Comment.Body, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+
+	repoLabels, err := gc.ListLabels(org, repo)
+	if err != nil {
+		return err
+	}
+	// Label names are case insensitive. Use the repo's spelling.
+	existing := map[string]string{}
+	for _, l := range repoLabels {
+		existing[strings.ToLower(l.Name)] = l.Name
+	}
+	current := map[string]string{}
+	for _, l := range ic.Issue.Labels {
+		current[strings.ToLower(l.Name)] = l.Name
+	}
+
+	var errs []error
+	var missing []string
+	for _, m := range matches {
+		remove := m[1] != ""
+		prefix := strings.ToLower(m[2])
+		for _, arg := range strings.Fields(m[3]) {
+			key := strings.ToLower(prefix + "/" + arg)
+			name, ok := existing[key]
+			if !ok {
+				missing = append(missing, "`"+prefix+"/"+arg+"`")
+				continue
+			}
+			if remove {
+				if _, ok := current[key]; !ok {
+					continue
+				}
+				log.Infof("Removing %s label.", current[key])
+				if err := gc.RemoveLabel(org, repo, number, current[key]); err != nil {
+					errs = append(errs, err)
+				}
+				delete(current, key)
+				continue
+			}
+			if _, ok := current[key]; ok {
+				continue
+			}
+			// Replace any other label with the same exclusive prefix.
+			if isExclusive(cfg, prefix) {
+				for l, n := range current {
+					if strings.HasPrefix(l, prefix+"/") {
+						log.Infof("Removing %s label.", n)
+						if err := gc.RemoveLabel(org, repo, number, n); err != nil {
+							errs = append(errs, err)
+						}
+						delete(current, l)
+					}
+				}
+			}
+			log.Infof("Adding %s label.", name)
+			if err := gc.AddLabel(org, repo, number, name); err != nil {
+				errs = append(errs, err)
+			}
+			current[key] = name
+		}
+	}
+	if len(missing) > 0 {
+		resp := fmt.Sprintf("these labels do not exist in this repository: %s", strings.Join(missing, ", "))
+		log.Infof("Commenting with \"%s\".", resp)
+		if err := gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors setting labels: %v", len(errs), errs)
+	}
+	return nil
+}
+
+func isExclusive(cfg plugins.Label, prefix string) bool {
+	for _, p := range cfg.ExclusivePrefixes {
+		if strings.EqualFold(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package label
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+var testConfig = plugins.Label{ExclusivePrefixes: []string{"priority"}}
+
+func TestLabel(t *testing.T) {
+	existing := []string{"kind/bug", "kind/flake", "area/hook", "priority/P0", "priority/P1", "lgtm"}
+	var testcases = []struct {
+		name          string
+		body          string
+		labels        []string
+		added         []string
+		removed       []string
+		shouldComment bool
+	}{
+		{
+			name: "unrelated comment",
+			body: "this is a kind bug",
+		},
+		{
+			name:  "add kind",
+			body:  "/kind bug",
+			added: []string{"kind/bug"},
+		},
+		{
+			name:  "add several",
+			body:  "/kind bug flake\n/area hook",
+			added: []string{"area/hook", "kind/bug", "kind/flake"},
+		},
+		{
+			name:   "already labeled",
+			body:   "/kind bug",
+			labels: []string{"kind/bug"},
+		},
+		{
+			name:  "case insensitive",
+			body:  "/Kind Bug\n/priority p1",
+			added: []string{"kind/bug", "priority/P1"},
+		},
+		{
+			name:    "remove kind",
+			body:    "/remove-kind bug",
+			labels:  []string{"kind/bug", "kind/flake"},
+			removed: []string{"kind/bug"},
+		},
+		{
+			name: "remove missing label",
+			body: "/remove-kind bug",
+		},
+		{
+			name:    "priority is exclusive",
+			body:    "/priority P1",
+			labels:  []string{"priority/P0", "kind/bug"},
+			added:   []string{"priority/P1"},
+			removed: []string{"priority/P0"},
+		},
+		{
+			name:   "kinds are not exclusive",
+			body:   "/kind flake",
+			labels: []string{"kind/bug"},
+			added:  []string{"kind/flake"},
+		},
+		{
+			name:          "label does not exist",
+			body:          "/area nope\n/kind bug",
+			added:         []string{"kind/bug"},
+			shouldComment: true,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			IssueComments:  map[int][]github.IssueComment{},
+			ExistingLabels: existing,
+		}
+		ic := github.IssueCommentEvent{
+			Action: "created",
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+			Issue: github.Issue{Number: 5},
+			Comment: github.IssueComment{
+				Body: tc.body,
+				User: github.User{Login: "commenter"},
+			},
+		}
+		for _, l := range tc.labels {
+			ic.Issue.Labels = append(ic.Issue.Labels, github.Label{Name: l})
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), testConfig, ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var added, removed []string
+		for _, l := range tc.added {
+			added = append(added, "org/repo#5:"+l)
+		}
+		for _, l := range tc.removed {
+			removed = append(removed, "org/repo#5:"+l)
+		}
+		sort.Strings(fc.LabelsAdded)
+		if !reflect.DeepEqual(added, fc.LabelsAdded) {
+			t.Errorf("For case %s, expected %v added, got %v.", tc.name, added, fc.LabelsAdded)
+		}
+		if !reflect.DeepEqual(removed, fc.LabelsRemoved) {
+			t.Errorf("For case %s, expected %v removed, got %v.", tc.name, removed, fc.LabelsRemoved)
+		}
+		if tc.shouldComment != (len(fc.IssueComments[5]) == 1) {
+			t.Errorf("For case %s, expected comment %t, got %v.", tc.name, tc.shouldComment, fc.IssueComments[5])
+		}
+	}
+}
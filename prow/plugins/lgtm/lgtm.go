@@ -18,6 +18,7 @@ package lgtm
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 
@@ -28,13 +29,28 @@ import (
 const pluginName = "lgtm"
 
 var (
-	lgtmLabel    = "lgtm"
 	lgtmRe       = regexp.MustCompile(`(?mi)^\/lgtm\r?$`)
 	lgtmCancelRe = regexp.MustCompile(`(?mi)^\/lgtm cancel\r?$`)
 )
 
 func init() {
-	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterReviewEventHandler(pluginName, handleReviewEvent, helpProvider)
+	plugins.RegisterReviewCommentEventHandler(pluginName, handleReviewCommentEvent, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Adds the " + c.LGTM.Label + " label when a reviewer approves the PR.",
+		Commands: []plugins.Command{{
+			Usage:       "/lgtm [cancel]",
+			Description: "Adds or removes the " + c.LGTM.Label + " label. Approving a review also adds it.",
+			Regex:       lgtmRe.String(),
+			Example:     "/lgtm",
+			WhoCanUse:   "Anyone but the PR author who is " + c.WhoCanUse("lgtm", org, repo) + ". The author may cancel.",
+		}},
+		Config: map[string]string{"lgtm.label": c.LGTM.Label},
+	}
 }
 
 type githubClient interface {
@@ -43,60 +59,148 @@ type githubClient interface {
 	RemoveLabel(owner, repo string, number int, label string) error
 }
 
+func authorizer(pc plugins.PluginClient, r github.Repo) *plugins.Authorizer {
+	return plugins.NewAuthorizer(pc.GitHubClient, pc.PluginConfig, r.Owner.Login, r.Name)
+}
+
 func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
-	return handle(pc.GitHubClient, pc.Logger, ic)
+	return handle(pc.GitHubClient, pc.Logger, pc.PluginConfig.LGTM, authorizer(pc, ic.Repo), ic)
+}
+
+func handleReviewEvent(pc plugins.PluginClient, re github.ReviewEvent) error {
+	return handleReview(pc.GitHubClient, pc.Logger, pc.PluginConfig.LGTM, authorizer(pc, re.Repo), re)
+}
+
+func handleReviewCommentEvent(pc plugins.PluginClient, rce github.ReviewCommentEvent) error {
+	return handleReviewComment(pc.GitHubClient, pc.Logger, pc.PluginConfig.LGTM, authorizer(pc, rce.Repo), rce)
 }
 
-func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+func handle(gc githubClient, log *logrus.Entry, cfg plugins.LGTM, auth *plugins.Authorizer, ic github.IssueCommentEvent) error {
 	// Only consider open PRs.
 	if !ic.Issue.IsPullRequest() || ic.Issue.State != "open" || ic.Action != "created" {
 		return nil
 	}
 
-	org := ic.Repo.Owner.Login
-	repo := ic.Repo.Name
-	number := ic.Issue.Number
-
 	// If we create an "/lgtm" comment, add lgtm if necessary.
 	// If we create a "/lgtm cancel" comment, remove lgtm if necessary.
-	wantLGTM := false
-	if lgtmRe.MatchString(ic.Comment.Body) {
-		wantLGTM = true
-	} else if lgtmCancelRe.MatchString(ic.Comment.Body) {
-		wantLGTM = false
-	} else {
+	wantLGTM, ok := lgtmCommand(ic.Comment.Body)
+	if !ok {
+		return nil
+	}
+	return setLGTM(gc, log, cfg, auth, ic.Repo, ic.Issue, ic.Comment, wantLGTM)
+}
+
+func handleReview(gc githubClient, log *logrus.Entry, cfg plugins.LGTM, auth *plugins.Authorizer, re github.ReviewEvent) error {
+	if re.PullRequest.State != "open" || re.Action != "submitted" {
 		return nil
 	}
 
-	// Allow authors to cancel LGTM. Do not allow authors to LGTM, and do not
-	// accept commands from any other user.
-	commentAuthor := ic.Comment.User.Login
-	isAssignee := ic.Issue.IsAssignee(commentAuthor)
-	isAuthor := ic.Issue.IsAuthor(commentAuthor)
+	// An explicit command in the review body wins. Otherwise, approving adds
+	// LGTM and requesting changes removes it.
+	issue := pullRequestIssue(re.PullRequest)
+	wantLGTM, ok := lgtmCommand(re.Review.Body)
+	if !ok {
+		switch strings.ToLower(re.Review.State) {
+		case github.ReviewStateApproved:
+			wantLGTM = true
+		case github.ReviewStateChangesRequested:
+			// Don't complain to reviewers who aren't assigned when there is
+			// nothing to remove.
+			if !issue.HasLabel(cfg.Label) {
+				return nil
+			}
+			wantLGTM = false
+		default:
+			return nil
+		}
+	}
+	comment := github.IssueComment{
+		Body:    re.Review.Body,
+		User:    re.Review.User,
+		HTMLURL: re.Review.HTMLURL,
+	}
+	return setLGTM(gc, log, cfg, auth, re.Repo, issue, comment, wantLGTM)
+}
+
+func handleReviewComment(gc githubClient, log *logrus.Entry, cfg plugins.LGTM, auth *plugins.Authorizer, rce github.ReviewCommentEvent) error {
+	if rce.PullRequest.State != "open" || rce.Action != "created" {
+		return nil
+	}
+
+	wantLGTM, ok := lgtmCommand(rce.Comment.Body)
+	if !ok {
+		return nil
+	}
+	comment := github.IssueComment{
+		Body:    rce.Comment.Body,
+		User:    rce.Comment.User,
+		HTMLURL: rce.Comment.HTMLURL,
+	}
+	return setLGTM(gc, log, cfg, auth, rce.Repo, pullRequestIssue(rce.PullRequest), comment, wantLGTM)
+}
+
+// lgtmCommand returns whether the body asks to add or to remove LGTM, and
+// false for its second value if it asks for neither.
+func lgtmCommand(body string) (bool, bool) {
+	if lgtmRe.MatchString(body) {
+		return true, true
+	} else if lgtmCancelRe.MatchString(body) {
+		return false, true
+	}
+	return false, false
+}
+
+// pullRequestIssue returns the issue view of a PR so that review events can
+// share the checks that we do for issue comments.
+func pullRequestIssue(pr github.PullRequest) github.Issue {
+	return github.Issue{
+		User:        pr.User,
+		Number:      pr.Number,
+		State:       pr.State,
+		HTMLURL:     pr.HTMLURL,
+		Labels:      pr.Labels,
+		Assignees:   pr.Assignees,
+		PullRequest: &struct{}{},
+	}
+}
+
+func setLGTM(gc githubClient, log *logrus.Entry, cfg plugins.LGTM, auth *plugins.Authorizer, r github.Repo, issue github.Issue, comment github.IssueComment, wantLGTM bool) error {
+	org := r.Owner.Login
+	repo := r.Name
+	number := issue.Number
+
+	// Allow authors to cancel LGTM. Do not allow authors to LGTM, and only
+	// accept commands from other users that the policy allows.
+	commentAuthor := comment.User.Login
+	isAuthor := issue.IsAuthor(commentAuthor)
 	if isAuthor && wantLGTM {
 		resp := "you can't LGTM your own PR"
 		log.Infof("Commenting with \"%s\".", resp)
-		return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
+		return gc.CreateComment(org, repo, number, plugins.FormatResponse(comment, resp))
 	} else if !isAuthor {
-		if !isAssignee && wantLGTM {
-			resp := "you can't LGTM a PR unless you are assigned as a reviewer"
+		allowed, err := auth.Allowed("lgtm", commentAuthor, issue)
+		if err != nil {
+			return err
+		}
+		if !allowed && wantLGTM {
+			resp := "you can't LGTM a PR unless you are " + auth.WhoCanUse("lgtm")
 			log.Infof("Commenting with \"%s\".", resp)
-			return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
-		} else if !isAssignee && !wantLGTM {
-			resp := "you can't remove LGTM from a PR unless you are assigned as a reviewer"
+			return gc.CreateComment(org, repo, number, plugins.FormatResponse(comment, resp))
+		} else if !allowed && !wantLGTM {
+			resp := "you can't remove LGTM from a PR unless you are " + auth.WhoCanUse("lgtm")
 			log.Infof("Commenting with \"%s\".", resp)
-			return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
+			return gc.CreateComment(org, repo, number, plugins.FormatResponse(comment, resp))
 		}
 	}
 
 	// Only add the label if it doesn't have it, and vice versa.
-	hasLGTM := ic.Issue.HasLabel(lgtmLabel)
+	hasLGTM := issue.HasLabel(cfg.Label)
 	if hasLGTM && !wantLGTM {
 		log.Info("Removing LGTM label.")
-		return gc.RemoveLabel(org, repo, number, lgtmLabel)
+		return gc.RemoveLabel(org, repo, number, cfg.Label)
 	} else if !hasLGTM && wantLGTM {
 		log.Info("Adding LGTM label.")
-		return gc.AddLabel(org, repo, number, lgtmLabel)
+		return gc.AddLabel(org, repo, number, cfg.Label)
 	}
 	return nil
 }
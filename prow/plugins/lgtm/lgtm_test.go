@@ -23,8 +23,13 @@ import (
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
 )
 
+const lgtmLabel = "lgtm"
+
+var testConfig = plugins.LGTM{Label: lgtmLabel}
+
 func TestLGTMComment(t *testing.T) {
 	// "a" is the author, "a", "r1", and "r2" are reviewers.
 	var testcases = []struct {
@@ -141,7 +146,7 @@ func TestLGTMComment(t *testing.T) {
 		if tc.hasLGTM {
 			ice.Issue.Labels = []github.Label{{Name: lgtmLabel}}
 		}
-		if err := handle(fc, logrus.WithField("plugin", pluginName), ice); err != nil {
+		if err := handle(fc, logrus.WithField("plugin", pluginName), testConfig, plugins.NewAuthorizer(fc, &plugins.Configuration{}, "org", "repo"), ice); err != nil {
 			t.Errorf("For case %s, didn't expect error from lgtmComment: %v", tc.name, err)
 			continue
 		}
@@ -169,3 +174,167 @@ func TestLGTMComment(t *testing.T) {
 		}
 	}
 }
+
+func TestLGTMReview(t *testing.T) {
+	// "a" is the author, "a", "r1", and "r2" are reviewers.
+	var testcases = []struct {
+		name          string
+		action        string
+		state         string
+		body          string
+		reviewer      string
+		hasLGTM       bool
+		shouldToggle  bool
+		shouldComment bool
+	}{
+		{
+			name:         "approval by reviewer, no lgtm on pr",
+			action:       "submitted",
+			state:        "approved",
+			reviewer:     "r1",
+			hasLGTM:      false,
+			shouldToggle: true,
+		},
+		{
+			name:         "approval by reviewer from the API, no lgtm on pr",
+			action:       "submitted",
+			state:        "APPROVED",
+			reviewer:     "r1",
+			hasLGTM:      false,
+			shouldToggle: true,
+		},
+		{
+			name:         "approval by reviewer, lgtm on pr",
+			action:       "submitted",
+			state:        "approved",
+			reviewer:     "r1",
+			hasLGTM:      true,
+			shouldToggle: false,
+		},
+		{
+			name:          "approval by non-reviewer",
+			action:        "submitted",
+			state:         "approved",
+			reviewer:      "o",
+			hasLGTM:       false,
+			shouldToggle:  false,
+			shouldComment: true,
+		},
+		{
+			name:         "changes requested by reviewer, lgtm on pr",
+			action:       "submitted",
+			state:        "changes_requested",
+			reviewer:     "r1",
+			hasLGTM:      true,
+			shouldToggle: true,
+		},
+		{
+			name:         "changes requested by non-reviewer, no lgtm on pr",
+			action:       "submitted",
+			state:        "changes_requested",
+			reviewer:     "o",
+			hasLGTM:      false,
+			shouldToggle: false,
+		},
+		{
+			name:         "lgtm in review body",
+			action:       "submitted",
+			state:        "commented",
+			body:         "nice work\n/lgtm",
+			reviewer:     "r2",
+			hasLGTM:      false,
+			shouldToggle: true,
+		},
+		{
+			name:         "lgtm cancel in approving review body",
+			action:       "submitted",
+			state:        "approved",
+			body:         "/lgtm cancel",
+			reviewer:     "r2",
+			hasLGTM:      true,
+			shouldToggle: true,
+		},
+		{
+			name:         "plain review comment",
+			action:       "submitted",
+			state:        "commented",
+			body:         "what about this?",
+			reviewer:     "r1",
+			hasLGTM:      false,
+			shouldToggle: false,
+		},
+		{
+			name:         "dismissed review",
+			action:       "dismissed",
+			state:        "dismissed",
+			reviewer:     "r1",
+			hasLGTM:      true,
+			shouldToggle: false,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			IssueComments: make(map[int][]github.IssueComment),
+		}
+		re := github.ReviewEvent{
+			Action: tc.action,
+			Review: github.Review{
+				Body:  tc.body,
+				State: tc.state,
+				User:  github.User{Login: tc.reviewer},
+			},
+			PullRequest: github.PullRequest{
+				User:      github.User{Login: "a"},
+				Number:    5,
+				State:     "open",
+				Assignees: []github.User{{Login: "a"}, {Login: "r1"}, {Login: "r2"}},
+			},
+		}
+		if tc.hasLGTM {
+			re.PullRequest.Labels = []github.Label{{Name: lgtmLabel}}
+		}
+		if err := handleReview(fc, logrus.WithField("plugin", pluginName), testConfig, plugins.NewAuthorizer(fc, &plugins.Configuration{}, "org", "repo"), re); err != nil {
+			t.Errorf("For case %s, didn't expect error from handleReview: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldToggle {
+			if tc.hasLGTM && len(fc.LabelsRemoved) == 0 {
+				t.Errorf("For case %s, should have removed LGTM.", tc.name)
+			} else if !tc.hasLGTM && len(fc.LabelsAdded) == 0 {
+				t.Errorf("For case %s, should have added LGTM.", tc.name)
+			}
+		} else if len(fc.LabelsRemoved) > 0 || len(fc.LabelsAdded) > 0 {
+			t.Errorf("For case %s, should not have added/removed LGTM.", tc.name)
+		}
+		if tc.shouldComment && len(fc.IssueComments[5]) != 1 {
+			t.Errorf("For case %s, should have commented.", tc.name)
+		} else if !tc.shouldComment && len(fc.IssueComments[5]) != 0 {
+			t.Errorf("For case %s, should not have commented.", tc.name)
+		}
+	}
+}
+
+func TestLGTMReviewComment(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		IssueComments: make(map[int][]github.IssueComment),
+	}
+	rce := github.ReviewCommentEvent{
+		Action: "created",
+		Comment: github.ReviewComment{
+			Body: "/lgtm",
+			User: github.User{Login: "r1"},
+		},
+		PullRequest: github.PullRequest{
+			User:      github.User{Login: "a"},
+			Number:    5,
+			State:     "open",
+			Assignees: []github.User{{Login: "r1"}},
+		},
+	}
+	if err := handleReviewComment(fc, logrus.WithField("plugin", pluginName), testConfig, plugins.NewAuthorizer(fc, &plugins.Configuration{}, "org", "repo"), rce); err != nil {
+		t.Fatalf("Didn't expect error from handleReviewComment: %v", err)
+	}
+	if len(fc.LabelsAdded) != 1 {
+		t.Errorf("Should have added LGTM, added %v.", fc.LabelsAdded)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle implements the lifecycle plugin, which marks open issues
+// and PRs without activity as stale, then rotten, and finally closes them.
+// Comments such as "/lifecycle frozen" set the lifecycle by hand.
+package lifecycle
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "lifecycle"
+
+const (
+	staleLabel  = "lifecycle/stale"
+	rottenLabel = "lifecycle/rotten"
+	frozenLabel = "lifecycle/frozen"
+)
+
+var lifecycleRe = regexp.MustCompile(`(?mi)^/(remove-)?lifecycle +(frozen|stale|rotten) *\r?$`)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterPeriodicHandler(pluginName, handlePeriodic, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: fmt.Sprintf("Marks issues and PRs without activity as %s after %d days, as %s after %d more, and closes them after %d more.",
+			staleLabel, c.Lifecycle.StaleDays, rottenLabel, c.Lifecycle.RottenDays, c.Lifecycle.CloseDays),
+		Commands: []plugins.Command{{
+			Usage:       "/[remove-]lifecycle (frozen|stale|rotten)",
+			Description: "Sets or removes the lifecycle label. Frozen issues and PRs are never closed.",
+			Regex:       lifecycleRe.String(),
+			Example:     "/lifecycle frozen",
+			WhoCanUse:   "Anyone",
+		}},
+		Config: map[string]string{
+			"lifecycle.stale_days":  fmt.Sprintf("%d", c.Lifecycle.StaleDays),
+			"lifecycle.rotten_days": fmt.Sprintf("%d", c.Lifecycle.RottenDays),
+			"lifecycle.close_days":  fmt.Sprintf("%d", c.Lifecycle.CloseDays),
+		},
+	}
+}
+
+type githubClient interface {
+	CreateComment(owner, repo string, number int, comment string) error
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	CloseIssue(owner, repo string, number int) error
+	FindIssues(query string) ([]github.Issue, error)
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleComment(pc.GitHubClient, pc.Logger, ic)
+}
+
+func handlePeriodic(pc plugins.PluginClient, orgOrRepo string) error {
+	return update(pc.GitHubClient, pc.Logger, pc.PluginConfig.Lifecycle, orgOrRepo, time.Now())
+}
+
+// handleComment adds or removes a lifecycle label. An issue has at most one,
+// so adding one removes the others.
+func handleComment(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+	if ic.Issue.State != "open" || ic.Action != "created" {
+		return nil
+	}
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+	for _, match := range lifecycleRe.FindAllStringSubmatch(ic.Comment.Body, -1) {
+		label := "lifecycle/" + strings.ToLower(match[2])
+		if match[1] != "" {
+			if ic.Issue.HasLabel(label) {
+				log.Infof("Removing %s label.", label)
+				if err := gc.RemoveLabel(org, repo, number, label); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for _, l := range []string{staleLabel, rottenLabel, frozenLabel} {
+			if l != label && ic.Issue.HasLabel(l) {
+				log.Infof("Removing %s label.", l)
+				if err := gc.RemoveLabel(org, repo, number, l); err != nil {
+					return err
+				}
+			}
+		}
+		if !ic.Issue.HasLabel(label) {
+			log.Infof("Adding %s label.", label)
+			if err := gc.AddLabel(org, repo, number, label); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// update moves each open issue and PR in the org or repo that has had no
+// activity for long enough along to its next lifecycle stage. Adding a label
+// or a comment is activity, so an issue moves at most one stage per period.
+// Later stages go first so that nothing moves two stages in one run, even if
+// search results lag behind our changes.
+func update(gc githubClient, log *logrus.Entry, cfg plugins.Lifecycle, orgOrRepo string, now time.Time) error {
+	scope := "org:" + orgOrRepo
+	if strings.Contains(orgOrRepo, "/") {
+		scope = "repo:" + orgOrRepo
+	}
+	days := func(n int) time.Time {
+		return now.Add(-time.Duration(n) * 24 * time.Hour)
+	}
+	stages := []struct {
+		name   string
+		query  string
+		before time.Time
+		act    func(githubClient, plugins.Lifecycle, string, string, github.Issue) error
+	}{
+		{
+			name:   "close",
+			query:  "label:" + rottenLabel + " -label:" + frozenLabel,
+			before: days(cfg.CloseDays),
+			act:    closeRotten,
+		},
+		{
+			name:   "rot",
+			query:  "label:" + staleLabel + " -label:" + rottenLabel + " -label:" + frozenLabel,
+			before: days(cfg.RottenDays),
+			act:    markRotten,
+		},
+		{
+			name:   "stale",
+			query:  "-label:" + staleLabel + " -label:" + rottenLabel + " -label:" + frozenLabel,
+			before: days(cfg.StaleDays),
+			act:    markStale,
+		},
+	}
+	var errs []error
+	for _, s := range stages {
+		query := fmt.Sprintf("state:open %s %s updated:<%s", scope, s.query, s.before.UTC().Format("2006-01-02"))
+		issues, err := gc.FindIssues(url.QueryEscape(query))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, issue := range issues {
+			// The search index can lag behind a "/lifecycle frozen".
+			if issue.HasLabel(frozenLabel) {
+				continue
+			}
+			org, repo, err := issue.OrgRepo()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			log.WithField("issue", fmt.Sprintf("%s/%s#%d", org, repo, issue.Number)).Infof("Lifecycle: %s.", s.name)
+			if err := s.act(gc, cfg, org, repo, issue); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors updating lifecycles: %v", len(errs), errs)
+	}
+	return nil
+}
+
+func closeRotten(gc githubClient, cfg plugins.Lifecycle, org, repo string, issue github.Issue) error {
+	if err := gc.CreateComment(org, repo, issue.Number, closeMessage(issue, cfg)); err != nil {
+		return err
+	}
+	return gc.CloseIssue(org, repo, issue.Number)
+}
+
+func markRotten(gc githubClient, cfg plugins.Lifecycle, org, repo string, issue github.Issue) error {
+	if err := gc.AddLabel(org, repo, issue.Number, rottenLabel); err != nil {
+		return err
+	}
+	if err := gc.RemoveLabel(org, repo, issue.Number, staleLabel); err != nil {
+		return err
+	}
+	return gc.CreateComment(org, repo, issue.Number, rottenMessage(issue, cfg))
+}
+
+func markStale(gc githubClient, cfg plugins.Lifecycle, org, repo string, issue github.Issue) error {
+	if err := gc.AddLabel(org, repo, issue.Number, staleLabel); err != nil {
+		return err
+	}
+	return gc.CreateComment(org, repo, issue.Number, staleMessage(issue, cfg))
+}
+
+func kind(issue github.Issue) string {
+	if issue.IsPullRequest() {
+		return "PRs"
+	}
+	return "Issues"
+}
+
+func staleMessage(issue github.Issue, cfg plugins.Lifecycle) string {
+	return message(fmt.Sprintf(`%[1]s go stale after %[2]d days of inactivity.
+Mark this as fresh with `+"`/remove-lifecycle stale`"+`.
+Stale %[3]s rot after an additional %[4]d days of inactivity and eventually close.
+
+Prevent this from closing with `+"`/lifecycle frozen`"+`.`,
+		kind(issue), cfg.StaleDays, strings.ToLower(kind(issue)), cfg.RottenDays))
+}
+
+func rottenMessage(issue github.Issue, cfg plugins.Lifecycle) string {
+	return message(fmt.Sprintf(`Stale %[1]s rot after %[2]d days of inactivity.
+Mark this as fresh with `+"`/remove-lifecycle rotten`"+`.
+Rotten %[1]s close after an additional %[3]d days of inactivity.
+
+Prevent this from closing with `+"`/lifecycle frozen`"+`.`,
+		strings.ToLower(kind(issue)), cfg.RottenDays, cfg.CloseDays))
+}
+
+func closeMessage(issue github.Issue, cfg plugins.Lifecycle) string {
+	return message(fmt.Sprintf(`Rotten %s close after %d days of inactivity.
+Reopen this with `+"`/reopen`"+` and mark it as fresh with `+"`/remove-lifecycle rotten`"+`.`,
+		strings.ToLower(kind(issue)), cfg.CloseDays))
+}
+
+func message(s string) string {
+	return fmt.Sprintf("%s\n\n<details>\n\n%s\n</details>", s, plugins.AboutThisBot)
+}
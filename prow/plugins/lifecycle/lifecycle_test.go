@@ -0,0 +1,190 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// fakeClient answers each search with the issues for the first term of the
+// query that has any.
+type fakeClient struct {
+	*fakegithub.FakeClient
+	results map[string][]github.Issue
+	queries []string
+}
+
+func (f *fakeClient) FindIssues(query string) ([]github.Issue, error) {
+	q, err := url.QueryUnescape(query)
+	if err != nil {
+		return nil, err
+	}
+	f.queries = append(f.queries, q)
+	for _, term := range strings.Fields(q) {
+		if is, ok := f.results[term]; ok {
+			return is, nil
+		}
+	}
+	return nil, nil
+}
+
+func issue(number int, labels ...string) github.Issue {
+	i := github.Issue{
+		Number:  number,
+		HTMLURL: fmt.Sprintf("https://github.com/org/repo/issues/%d", number),
+	}
+	for _, l := range labels {
+		i.Labels = append(i.Labels, github.Label{Name: l})
+	}
+	return i
+}
+
+func TestUpdate(t *testing.T) {
+	fc := &fakeClient{
+		FakeClient: &fakegithub.FakeClient{IssueComments: map[int][]github.IssueComment{}},
+		results: map[string][]github.Issue{
+			"-label:lifecycle/stale": {issue(1), issue(2, frozenLabel)},
+			"label:lifecycle/stale":  {issue(3, staleLabel)},
+			"label:lifecycle/rotten": {issue(4, rottenLabel)},
+		},
+	}
+	cfg := plugins.Lifecycle{StaleDays: 90, RottenDays: 30, CloseDays: 10}
+	now := time.Date(2017, time.March, 31, 12, 0, 0, 0, time.UTC)
+	if err := update(fc, logrus.WithField("plugin", pluginName), cfg, "org/repo", now); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+
+	expectedQueries := []string{
+		"state:open repo:org/repo label:lifecycle/rotten -label:lifecycle/frozen updated:<2017-03-21",
+		"state:open repo:org/repo label:lifecycle/stale -label:lifecycle/rotten -label:lifecycle/frozen updated:<2017-03-01",
+		"state:open repo:org/repo -label:lifecycle/stale -label:lifecycle/rotten -label:lifecycle/frozen updated:<2016-12-31",
+	}
+	if !reflect.DeepEqual(fc.queries, expectedQueries) {
+		t.Errorf("Expected queries %v, got %v.", expectedQueries, fc.queries)
+	}
+	expectedAdded := []string{"org/repo#1:lifecycle/stale", "org/repo#3:lifecycle/rotten"}
+	sort.Strings(fc.LabelsAdded)
+	if !reflect.DeepEqual(fc.LabelsAdded, expectedAdded) {
+		t.Errorf("Expected labels added %v, got %v.", expectedAdded, fc.LabelsAdded)
+	}
+	expectedRemoved := []string{"org/repo#3:lifecycle/stale"}
+	if !reflect.DeepEqual(fc.LabelsRemoved, expectedRemoved) {
+		t.Errorf("Expected labels removed %v, got %v.", expectedRemoved, fc.LabelsRemoved)
+	}
+	expectedClosed := []string{"org/repo#4"}
+	if !reflect.DeepEqual(fc.IssuesClosed, expectedClosed) {
+		t.Errorf("Expected issues closed %v, got %v.", expectedClosed, fc.IssuesClosed)
+	}
+	for _, n := range []int{1, 3, 4} {
+		if len(fc.IssueComments[n]) != 1 {
+			t.Errorf("Expected one comment on #%d, got %d.", n, len(fc.IssueComments[n]))
+		}
+	}
+	if len(fc.IssueComments[2]) != 0 {
+		t.Errorf("Expected no comments on frozen #2, got %v.", fc.IssueComments[2])
+	}
+}
+
+func TestUpdateOrg(t *testing.T) {
+	fc := &fakeClient{FakeClient: &fakegithub.FakeClient{}}
+	if err := update(fc, logrus.WithField("plugin", pluginName), plugins.Lifecycle{}, "org", time.Now()); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	for _, q := range fc.queries {
+		if !strings.Contains(q, " org:org ") {
+			t.Errorf("Expected the query to search the org, got %q.", q)
+		}
+	}
+}
+
+func TestLifecycleComment(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		body    string
+		state   string
+		labels  []string
+		added   []string
+		removed []string
+	}{
+		{
+			name: "unrelated comment",
+			body: "it's been a while",
+		},
+		{
+			name:    "freeze a stale issue",
+			body:    "/lifecycle frozen",
+			labels:  []string{staleLabel},
+			added:   []string{"org/repo#5:lifecycle/frozen"},
+			removed: []string{"org/repo#5:lifecycle/stale"},
+		},
+		{
+			name:    "remove stale",
+			body:    "/remove-lifecycle stale",
+			labels:  []string{staleLabel},
+			removed: []string{"org/repo#5:lifecycle/stale"},
+		},
+		{
+			name: "remove a missing label",
+			body: "/remove-lifecycle rotten",
+		},
+		{
+			name:   "already frozen",
+			body:   "/lifecycle Frozen",
+			labels: []string{frozenLabel},
+		},
+		{
+			name:  "closed issue",
+			body:  "/lifecycle frozen",
+			state: "closed",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		ic := github.IssueCommentEvent{
+			Action:  "created",
+			Repo:    github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			Comment: github.IssueComment{Body: tc.body},
+			Issue:   issue(5, tc.labels...),
+		}
+		ic.Issue.State = "open"
+		if tc.state != "" {
+			ic.Issue.State = tc.state
+		}
+		if err := handleComment(fc, logrus.WithField("plugin", pluginName), ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(fc.LabelsAdded, tc.added) {
+			t.Errorf("For case %s, expected labels added %v, got %v.", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if !reflect.DeepEqual(fc.LabelsRemoved, tc.removed) {
+			t.Errorf("For case %s, expected labels removed %v, got %v.", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package needsrebase implements the needs-rebase plugin, which labels PRs
+// that no longer merge cleanly into their base branch.
+package needsrebase
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "needs-rebase"
+
+const needsRebaseMessage = "This PR has merge conflicts with its base branch. Please rebase it."
+
+// maxChecks caps how many PRs one periodic pass asks GitHub about. Whatever
+// is left over waits for the next pass.
+const maxChecks = 100
+
+// pending is what hook's event handlers have left for the next periodic pass.
+var pending = newTracker()
+
+func init() {
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+	plugins.RegisterPushEventHandler(pluginName, handlePushEvent, helpProvider)
+	plugins.RegisterPeriodicHandler(pluginName, handlePeriodic, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Adds the " + c.NeedsRebase.Label + " label to PRs that conflict with their base branch, and removes it once they don't.",
+		Config:      map[string]string{"needs_rebase.label": c.NeedsRebase.Label},
+	}
+}
+
+type githubClient interface {
+	CreateComment(owner, repo string, number int, comment string) error
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	FindIssues(query string) ([]github.Issue, error)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pc.PluginConfig.NeedsRebase, pending, pre)
+}
+
+func handlePushEvent(pc plugins.PluginClient, pe github.PushEvent) error {
+	handlePush(pending, pe)
+	return nil
+}
+
+func handlePeriodic(pc plugins.PluginClient, orgOrRepo string) error {
+	return checkPending(pc.GitHubClient, pc.Logger, pc.PluginConfig.NeedsRebase, pending, orgOrRepo)
+}
+
+// prRef names a PR to check.
+type prRef struct {
+	org, repo string
+	number    int
+}
+
+// tracker remembers which PRs may have gained or lost conflicts since the
+// last periodic pass, so that the pass need not ask about every open PR.
+type tracker struct {
+	sync.Mutex
+	// Orgs and repos that have had a full pass since hook started. Until
+	// then we know nothing about them.
+	scanned map[string]bool
+	// Repo full name -> branches pushed since the last pass.
+	pushed map[string]map[string]bool
+	// PRs whose mergeability GitHub hadn't worked out when we last asked.
+	unknown map[prRef]bool
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		scanned: map[string]bool{},
+		pushed:  map[string]map[string]bool{},
+		unknown: map[prRef]bool{},
+	}
+}
+
+func (t *tracker) push(repo, branch string) {
+	t.Lock()
+	defer t.Unlock()
+	if t.pushed[repo] == nil {
+		t.pushed[repo] = map[string]bool{}
+	}
+	t.pushed[repo][branch] = true
+}
+
+func (t *tracker) addUnknown(prs ...prRef) {
+	t.Lock()
+	defer t.Unlock()
+	for _, pr := range prs {
+		t.unknown[pr] = true
+	}
+}
+
+// search finds open PRs that may have gained conflicts: every open PR in an
+// org or repo if branch is empty, or else those without the label against a
+// branch of the repo.
+type search struct {
+	orgOrRepo, branch string
+}
+
+func (s search) query(label string) string {
+	if s.branch != "" {
+		// Commits on the base branch may make a PR conflict, but won't make
+		// a conflicting PR merge. That takes a push to the PR itself, which
+		// handlePR sees.
+		return fmt.Sprintf("type:pr state:open repo:%s base:%s -label:%s", s.orgOrRepo, s.branch, label)
+	}
+	if strings.Contains(s.orgOrRepo, "/") {
+		return "type:pr state:open repo:" + s.orgOrRepo
+	}
+	return "type:pr state:open org:" + s.orgOrRepo
+}
+
+// take returns and forgets the searches and PRs that the next pass over the
+// org or repo should check.
+func (t *tracker) take(orgOrRepo string) ([]search, []prRef) {
+	t.Lock()
+	defer t.Unlock()
+	inScope := func(repo string) bool {
+		return repo == orgOrRepo || strings.HasPrefix(repo, orgOrRepo+"/")
+	}
+	var searches []search
+	full := !t.scanned[orgOrRepo]
+	if full {
+		searches = append(searches, search{orgOrRepo: orgOrRepo})
+		t.scanned[orgOrRepo] = true
+	}
+	for repo, branches := range t.pushed {
+		if !inScope(repo) {
+			continue
+		}
+		// A full search already covers pushed branches.
+		if !full {
+			for b := range branches {
+				searches = append(searches, search{orgOrRepo: repo, branch: b})
+			}
+		}
+		delete(t.pushed, repo)
+	}
+	var prs []prRef
+	for pr := range t.unknown {
+		if inScope(pr.org + "/" + pr.repo) {
+			prs = append(prs, pr)
+			delete(t.unknown, pr)
+		}
+	}
+	return searches, prs
+}
+
+// giveBack makes the next pass repeat a search that failed.
+func (t *tracker) giveBack(s search) {
+	if s.branch != "" {
+		t.push(s.orgOrRepo, s.branch)
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	delete(t.scanned, s.orgOrRepo)
+}
+
+func handlePR(gc githubClient, log *logrus.Entry, cfg plugins.NeedsRebase, t *tracker, pre github.PullRequestEvent) error {
+	switch pre.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return nil
+	}
+	ref := prRef{
+		org:    pre.PullRequest.Base.Repo.Owner.Login,
+		repo:   pre.PullRequest.Base.Repo.Name,
+		number: pre.Number,
+	}
+	// The event doesn't say whether the PR is mergeable yet, so ask.
+	pr, err := gc.GetPullRequest(ref.org, ref.repo, ref.number)
+	if err != nil {
+		return err
+	}
+	if pr.Mergeable == nil {
+		// Likely, since the PR was just pushed. Don't hold up the event
+		// worker waiting for GitHub. The next pass will ask again.
+		t.addUnknown(ref)
+		return nil
+	}
+	return check(gc, log, cfg, ref.org, ref.repo, ref.number, pr.Labels, pr.User.Login, pr.Mergeable)
+}
+
+// handlePush notes that open PRs against the pushed branch may now conflict.
+// The next periodic pass checks them, which also gives GitHub time to work
+// out their mergeability.
+func handlePush(t *tracker, pe github.PushEvent) {
+	branch := pe.Branch()
+	if branch == "" || pe.Deleted {
+		return
+	}
+	t.push(pe.Repo.FullName, branch)
+}
+
+// checkPending checks the PRs in the org or repo whose label may be out of
+// date: every open PR on the first pass, and after that those against pushed
+// branches and those that GitHub couldn't tell us about last time.
+func checkPending(gc githubClient, log *logrus.Entry, cfg plugins.NeedsRebase, t *tracker, orgOrRepo string) error {
+	searches, prs := t.take(orgOrRepo)
+	var errs []error
+	seen := map[prRef]bool{}
+	for _, pr := range prs {
+		seen[pr] = true
+	}
+	for _, s := range searches {
+		issues, err := gc.FindIssues(url.QueryEscape(s.query(cfg.Label)))
+		if err != nil {
+			t.giveBack(s)
+			errs = append(errs, err)
+			continue
+		}
+		for _, issue := range issues {
+			org, repo, err := issue.OrgRepo()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			ref := prRef{org: org, repo: repo, number: issue.Number}
+			if !seen[ref] {
+				seen[ref] = true
+				prs = append(prs, ref)
+			}
+		}
+	}
+	if len(prs) > maxChecks {
+		log.Infof("Checking %d of %d PRs, leaving the rest for the next pass.", maxChecks, len(prs))
+		t.addUnknown(prs[maxChecks:]...)
+		prs = prs[:maxChecks]
+	}
+	for _, ref := range prs {
+		pr, err := gc.GetPullRequest(ref.org, ref.repo, ref.number)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pr.Mergeable == nil {
+			t.addUnknown(ref)
+			continue
+		}
+		l := log.WithField("pr", fmt.Sprintf("%s/%s#%d", ref.org, ref.repo, ref.number))
+		if err := check(gc, l, cfg, ref.org, ref.repo, ref.number, pr.Labels, pr.User.Login, pr.Mergeable); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors checking PRs: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// check adds or removes the label to match whether the PR merges. It does
+// nothing if GitHub hasn't worked that out yet.
+func check(gc githubClient, log *logrus.Entry, cfg plugins.NeedsRebase, org, repo string, number int, labels []github.Label, author string, mergeable *bool) error {
+	if mergeable == nil {
+		return nil
+	}
+	hasLabel := false
+	for _, l := range labels {
+		if l.Name == cfg.Label {
+			hasLabel = true
+		}
+	}
+	if *mergeable && hasLabel {
+		log.Infof("Removing %s label.", cfg.Label)
+		return gc.RemoveLabel(org, repo, number, cfg.Label)
+	} else if !*mergeable && !hasLabel {
+		log.Infof("Adding %s label.", cfg.Label)
+		if err := gc.AddLabel(org, repo, number, cfg.Label); err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("@%s: %s\n\n<details>\n\n%s\n</details>", author, needsRebaseMessage, plugins.AboutThisBot)
+		return gc.CreateComment(org, repo, number, msg)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package needsrebase
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const needsRebaseLabel = "needs-rebase"
+
+var testConfig = plugins.NeedsRebase{Label: needsRebaseLabel}
+
+func TestCheck(t *testing.T) {
+	yes, no := true, false
+	var testcases = []struct {
+		name          string
+		mergeable     *bool
+		hasLabel      bool
+		shouldAdd     bool
+		shouldRemove  bool
+		shouldComment bool
+	}{
+		{
+			name: "mergeability unknown",
+		},
+		{
+			name:      "mergeable",
+			mergeable: &yes,
+		},
+		{
+			name:          "conflicts",
+			mergeable:     &no,
+			shouldAdd:     true,
+			shouldComment: true,
+		},
+		{
+			name:      "conflicts, already labeled",
+			mergeable: &no,
+			hasLabel:  true,
+		},
+		{
+			name:         "conflicts resolved",
+			mergeable:    &yes,
+			hasLabel:     true,
+			shouldRemove: true,
+		},
+		{
+			name:     "labeled, mergeability unknown",
+			hasLabel: true,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{},
+		}
+		var labels []github.Label
+		if tc.hasLabel {
+			labels = []github.Label{{Name: needsRebaseLabel}}
+		}
+		if err := check(fc, logrus.WithField("plugin", pluginName), testConfig, "org", "repo", 5, labels, "author", tc.mergeable); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if tc.shouldAdd != (len(fc.LabelsAdded) == 1) {
+			t.Errorf("For case %s, expected add %t, got %v.", tc.name, tc.shouldAdd, fc.LabelsAdded)
+		}
+		if tc.shouldRemove != (len(fc.LabelsRemoved) == 1) {
+			t.Errorf("For case %s, expected remove %t, got %v.", tc.name, tc.shouldRemove, fc.LabelsRemoved)
+		}
+		if tc.shouldComment != (len(fc.IssueComments[5]) == 1) {
+			t.Errorf("For case %s, expected comment %t, got %v.", tc.name, tc.shouldComment, fc.IssueComments[5])
+		}
+	}
+}
+
+func TestHandlePush(t *testing.T) {
+	no := false
+	fc := &fakegithub.FakeClient{
+		IssueComments: map[int][]github.IssueComment{},
+		Issues: []github.Issue{
+			{Number: 1, HTMLURL: "https://github.com/org/repo/pull/1"},
+		},
+		PullRequests: map[int]*github.PullRequest{
+			1: {Number: 1, Mergeable: &no},
+		},
+	}
+	tr := newTracker()
+	tr.scanned["org"] = true
+	pe := github.PushEvent{
+		Ref:  "refs/heads/master",
+		Repo: github.Repo{FullName: "org/repo"},
+	}
+	handlePush(tr, pe)
+	// Tags can't conflict with anything.
+	pe.Ref = "refs/tags/v1.0"
+	handlePush(tr, pe)
+	if len(tr.pushed["org/repo"]) != 1 || !tr.pushed["org/repo"]["master"] {
+		t.Fatalf("Expected only master to be noted, got %v.", tr.pushed)
+	}
+	if err := checkPending(fc, logrus.WithField("plugin", pluginName), testConfig, tr, "org"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.LabelsAdded) != 1 || fc.LabelsAdded[0] != "org/repo#1:"+needsRebaseLabel {
+		t.Errorf("Expected #1 to be labeled, got %v.", fc.LabelsAdded)
+	}
+	if len(tr.pushed) != 0 {
+		t.Errorf("Expected the push to be forgotten, got %v.", tr.pushed)
+	}
+}
+
+// fakeClient reports that GitHub hasn't worked out whether each PR merges
+// for the first few times that it is asked, and counts the searches.
+type fakeClient struct {
+	*fakegithub.FakeClient
+	unknownFor map[int]int
+	queries    []string
+	gets       int
+}
+
+func (f *fakeClient) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
+	f.gets++
+	if f.unknownFor[number] > 0 {
+		f.unknownFor[number]--
+		return &github.PullRequest{Number: number}, nil
+	}
+	return f.FakeClient.GetPullRequest(owner, repo, number)
+}
+
+func (f *fakeClient) FindIssues(query string) ([]github.Issue, error) {
+	f.queries = append(f.queries, query)
+	return f.FakeClient.FindIssues(query)
+}
+
+func TestCheckPending(t *testing.T) {
+	no := false
+	fc := &fakeClient{
+		FakeClient: &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{},
+			Issues: []github.Issue{
+				{Number: 1, HTMLURL: "https://github.com/org/repo/pull/1"},
+				{Number: 2, HTMLURL: "https://github.com/org/repo/pull/2"},
+			},
+			PullRequests: map[int]*github.PullRequest{
+				1: {Number: 1, Mergeable: &no},
+				2: {Number: 2, Mergeable: &no},
+				3: {Number: 3, Mergeable: &no},
+			},
+		},
+		unknownFor: map[int]int{2: 1, 3: 1},
+	}
+	log := logrus.WithField("plugin", pluginName)
+	tr := newTracker()
+
+	// The PR event doesn't wait for GitHub.
+	pre := github.PullRequestEvent{
+		Action: "synchronize",
+		Number: 3,
+		PullRequest: github.PullRequest{
+			Base: github.PullRequestBranch{
+				Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			},
+		},
+	}
+	if err := handlePR(fc, log, testConfig, tr, pre); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.LabelsAdded) != 0 || !tr.unknown[prRef{"org", "repo", 3}] {
+		t.Fatalf("Expected #3 to be left for the next pass, got labels %v and unknown %v.", fc.LabelsAdded, tr.unknown)
+	}
+
+	// The first pass searches everything and checks #3 again.
+	fc.gets = 0
+	if err := checkPending(fc, log, testConfig, tr, "org"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.queries) != 1 || fc.gets != 3 {
+		t.Errorf("Expected one search and three lookups, got searches %v and %d lookups.", fc.queries, fc.gets)
+	}
+	if len(fc.LabelsAdded) != 2 || !tr.unknown[prRef{"org", "repo", 2}] {
+		t.Errorf("Expected #1 and #3 to be labeled and #2 left over, got labels %v and unknown %v.", fc.LabelsAdded, tr.unknown)
+	}
+
+	// Later passes with no pushes only ask about what was left over.
+	fc.queries = nil
+	fc.gets = 0
+	if err := checkPending(fc, log, testConfig, tr, "org"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.queries) != 0 || fc.gets != 1 || len(fc.LabelsAdded) != 3 {
+		t.Errorf("Expected only #2 to be checked, got searches %v, %d lookups and labels %v.", fc.queries, fc.gets, fc.LabelsAdded)
+	}
+	if err := checkPending(fc, log, testConfig, tr, "org"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if fc.gets != 1 {
+		t.Errorf("Expected no more lookups, got %d.", fc.gets-1)
+	}
+}
+
+func TestCheckPendingCap(t *testing.T) {
+	yes := true
+	fc := &fakeClient{
+		FakeClient: &fakegithub.FakeClient{
+			PullRequests: map[int]*github.PullRequest{},
+		},
+	}
+	for i := 1; i <= maxChecks+5; i++ {
+		fc.Issues = append(fc.Issues, github.Issue{Number: i, HTMLURL: "https://github.com/org/repo/pull/" + strconv.Itoa(i)})
+		fc.PullRequests[i] = &github.PullRequest{Number: i, Mergeable: &yes}
+	}
+	tr := newTracker()
+	if err := checkPending(fc, logrus.WithField("plugin", pluginName), testConfig, tr, "org/repo"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if fc.gets != maxChecks || len(tr.unknown) != 5 {
+		t.Errorf("Expected %d lookups and 5 PRs left over, got %d and %v.", maxChecks, fc.gets, tr.unknown)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify implements the notify plugin, which posts messages to
+// incoming webhooks such as Slack's when PRs merge or get labels.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "notify"
+
+func init() {
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Posts to incoming webhooks, such as Slack's, when PRs merge that change certain files or when PRs get certain labels.",
+	}
+}
+
+type githubClient interface {
+	GetPullRequestChanges(owner, repo string, number int) ([]github.PullRequestChange, error)
+}
+
+// message is what the templates see.
+type message struct {
+	Org    string
+	Repo   string
+	Number int
+	Title  string
+	Author string
+	URL    string
+	// The label that was added, if any.
+	Label string
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	org := pre.PullRequest.Base.Repo.Owner.Login
+	repo := pre.PullRequest.Base.Repo.Name
+	var rules []plugins.NotifyRule
+	rules = append(rules, pc.PluginConfig.Notify.Rules[org]...)
+	rules = append(rules, pc.PluginConfig.Notify.Rules[org+"/"+repo]...)
+	return handle(pc.GitHubClient, pc.Logger, rules, pre)
+}
+
+func handle(gc githubClient, log *logrus.Entry, rules []plugins.NotifyRule, pre github.PullRequestEvent) error {
+	pr := pre.PullRequest
+	merged := pre.Action == "closed" && pr.Merged
+	labeled := pre.Action == "labeled"
+	if len(rules) == 0 || (!merged && !labeled) {
+		return nil
+	}
+	m := message{
+		Org:    pr.Base.Repo.Owner.Login,
+		Repo:   pr.Base.Repo.Name,
+		Number: pr.Number,
+		Title:  pr.Title,
+		Author: pr.User.Login,
+		URL:    pr.HTMLURL,
+		Label:  pre.Label.Name,
+	}
+
+	// Only fetch the changes if a rule needs them, and then only once.
+	var changes []string
+	fetched := false
+	var errs []error
+	for _, r := range rules {
+		if labeled {
+			if r.Label != pre.Label.Name {
+				continue
+			}
+		} else if re := r.MergedPathsRegexp(); re == nil {
+			continue
+		} else {
+			if !fetched {
+				prcs, err := gc.GetPullRequestChanges(m.Org, m.Repo, m.Number)
+				if err != nil {
+					return err
+				}
+				for _, prc := range prcs {
+					changes = append(changes, prc.Filename)
+				}
+				fetched = true
+			}
+			if !anyMatch(re.MatchString, changes) {
+				continue
+			}
+		}
+		var b bytes.Buffer
+		if err := r.MessageTemplate().Execute(&b, m); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("Posting %q.", b.String())
+		if err := post(r.WebhookURLFile, b.String()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors posting messages: %v", len(errs), errs)
+	}
+	return nil
+}
+
+func anyMatch(match func(string) bool, ss []string) bool {
+	for _, s := range ss {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// post sends the message to the incoming webhook whose URL is in the file, in
+// the format that Slack and compatible services expect. We read the file each
+// time so that a new URL takes effect without a restart.
+func post(urlFile, text string) error {
+	u, err := ioutil.ReadFile(urlFile)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSpace(string(u))
+	b, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("response not 2XX: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// urlFile writes the URL to a file in dir, as if it were mounted from a
+// secret, and returns the file's path.
+func urlFile(t *testing.T, dir, name, url string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(url+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNotify(t *testing.T) {
+	var posted []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var m map[string]string
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Errorf("Could not unmarshal message: %v", err)
+		}
+		posted = append(posted, r.URL.Path+" "+m["text"])
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pa := &plugins.PluginAgent{}
+	if err := pa.Set(&plugins.Configuration{
+		Notify: plugins.Notify{Rules: map[string][]plugins.NotifyRule{
+			"org/repo": {
+				{WebhookURLFile: urlFile(t, dir, "api", ts.URL+"/api"), MergedPaths: "^pkg/api/"},
+				{WebhookURLFile: urlFile(t, dir, "release", ts.URL+"/release"), Label: "release-note-action-required", Template: "Action required: {{.URL}}"},
+				{WebhookURLFile: urlFile(t, dir, "any", ts.URL+"/any"), MergedPaths: "."},
+			},
+		}},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+	rules := pa.Config().Notify.Rules["org/repo"]
+
+	var testcases = []struct {
+		name    string
+		action  string
+		merged  bool
+		label   string
+		title   string
+		changes []string
+		posted  []string
+	}{
+		{
+			name:    "merged api change",
+			action:  "closed",
+			merged:  true,
+			changes: []string{"pkg/api/types.go", "README.md"},
+			posted: []string{
+				"/api org/repo#5 by author merged: <https://github.com/org/repo/pull/5|Change the API>",
+				"/any org/repo#5 by author merged: <https://github.com/org/repo/pull/5|Change the API>",
+			},
+		},
+		{
+			name:    "merged other change",
+			action:  "closed",
+			merged:  true,
+			changes: []string{"README.md"},
+			posted: []string{
+				"/any org/repo#5 by author merged: <https://github.com/org/repo/pull/5|Change the API>",
+			},
+		},
+		{
+			name:    "title with Slack markup",
+			action:  "closed",
+			merged:  true,
+			title:   "Use <T> & friends",
+			changes: []string{"README.md"},
+			posted: []string{
+				"/any org/repo#5 by author merged: <https://github.com/org/repo/pull/5|Use &lt;T&gt; &amp; friends>",
+			},
+		},
+		{
+			name:    "closed without merging",
+			action:  "closed",
+			changes: []string{"pkg/api/types.go"},
+		},
+		{
+			name:   "label added",
+			action: "labeled",
+			label:  "release-note-action-required",
+			posted: []string{"/release Action required: https://github.com/org/repo/pull/5"},
+		},
+		{
+			name:   "other label added",
+			action: "labeled",
+			label:  "lgtm",
+		},
+		{
+			name:   "label removed",
+			action: "unlabeled",
+			label:  "release-note-action-required",
+		},
+	}
+	for _, tc := range testcases {
+		posted = nil
+		fc := &fakegithub.FakeClient{
+			PullRequestChanges: map[int][]github.PullRequestChange{},
+		}
+		for _, f := range tc.changes {
+			fc.PullRequestChanges[5] = append(fc.PullRequestChanges[5], github.PullRequestChange{Filename: f})
+		}
+		if tc.title == "" {
+			tc.title = "Change the API"
+		}
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 5,
+			PullRequest: github.PullRequest{
+				Number:  5,
+				Title:   tc.title,
+				HTMLURL: "https://github.com/org/repo/pull/5",
+				User:    github.User{Login: "author"},
+				Merged:  tc.merged,
+				Base: github.PullRequestBranch{
+					Repo: github.Repo{
+						Owner: github.User{Login: "org"},
+						Name:  "repo",
+					},
+				},
+			},
+			Label: github.Label{Name: tc.label},
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), rules, pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(posted, tc.posted) {
+			t.Errorf("For case %s, expected %q to be posted, got %q.", tc.name, tc.posted, posted)
+		}
+	}
+}
+
+func TestPostError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusNotFound)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := post(urlFile(t, dir, "hook", ts.URL), "hello"); err == nil {
+		t.Error("Expected an error when the webhook fails.")
+	}
+	if err := post(filepath.Join(dir, "missing"), "hello"); err == nil {
+		t.Error("Expected an error when the URL file is missing.")
+	}
+}
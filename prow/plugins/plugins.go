@@ -17,14 +17,15 @@ limitations under the License.
 package plugins
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
-	"time"
+	"text/template"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/ghodss/yaml"
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/jobs"
@@ -32,23 +33,29 @@ import (
 )
 
 var (
-	allPlugins           = map[string]struct{}{}
-	issueCommentHandlers = map[string]IssueCommentHandler{}
-	pullRequestHandlers  = map[string]PullRequestHandler{}
-	statusEventHandlers  = map[string]StatusEventHandler{}
+	allPlugins            = map[string]struct{}{}
+	issueCommentHandlers  = map[string]IssueCommentHandler{}
+	pullRequestHandlers   = map[string]PullRequestHandler{}
+	statusEventHandlers   = map[string]StatusEventHandler{}
+	reviewEventHandlers   = map[string]ReviewEventHandler{}
+	reviewCommentHandlers = map[string]ReviewCommentEventHandler{}
+	pushEventHandlers     = map[string]PushEventHandler{}
+	periodicHandlers      = map[string]PeriodicHandler{}
 )
 
 type IssueCommentHandler func(PluginClient, github.IssueCommentEvent) error
 
-func RegisterIssueCommentHandler(name string, fn IssueCommentHandler) {
+func RegisterIssueCommentHandler(name string, fn IssueCommentHandler, help HelpProvider) {
 	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
 	issueCommentHandlers[name] = fn
 }
 
 type PullRequestHandler func(PluginClient, github.PullRequestEvent) error
 
-func RegisterPullRequestHandler(name string, fn PullRequestHandler) {
+func RegisterPullRequestHandler(name string, fn PullRequestHandler, help HelpProvider) {
 	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
 	pullRequestHandlers[name] = fn
 }
 
@@ -57,76 +64,508 @@ type PluginClient struct {
 	KubeClient   *kube.Client
 	JobAgent     *jobs.JobAgent
 	Logger       *logrus.Entry
+
+	// PluginConfig is the plugin configuration at the time of the event.
+	PluginConfig *Configuration
 }
 
 type StatusEventHandler func(PluginClient, github.StatusEvent) error
 
-func RegisterStatusEventHandler(name string, fn StatusEventHandler) {
+func RegisterStatusEventHandler(name string, fn StatusEventHandler, help HelpProvider) {
 	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
 	statusEventHandlers[name] = fn
 }
 
+type ReviewEventHandler func(PluginClient, github.ReviewEvent) error
+
+func RegisterReviewEventHandler(name string, fn ReviewEventHandler, help HelpProvider) {
+	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
+	reviewEventHandlers[name] = fn
+}
+
+type ReviewCommentEventHandler func(PluginClient, github.ReviewCommentEvent) error
+
+func RegisterReviewCommentEventHandler(name string, fn ReviewCommentEventHandler, help HelpProvider) {
+	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
+	reviewCommentHandlers[name] = fn
+}
+
+type PushEventHandler func(PluginClient, github.PushEvent) error
+
+func RegisterPushEventHandler(name string, fn PushEventHandler, help HelpProvider) {
+	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
+	pushEventHandlers[name] = fn
+}
+
+// PeriodicHandler is called regularly for each org or repo that enables the
+// plugin, given as "org" or "org/repo".
+type PeriodicHandler func(PluginClient, string) error
+
+func RegisterPeriodicHandler(name string, fn PeriodicHandler, help HelpProvider) {
+	allPlugins[name] = struct{}{}
+	registerHelp(name, help)
+	periodicHandlers[name] = fn
+}
+
+// Configuration is the format of the plugin config file.
+type Configuration struct {
+	// Repo (eg "k/k") or org (eg "k") -> list of handler names.
+	Plugins map[string][]string `json:"plugins"`
+	// Repo or org -> who the bot trusts and who the bot is. Repo settings
+	// take precedence over org settings.
+	Settings map[string]Settings `json:"settings"`
+	// Command -> who may use it. Commands that are left out get the default
+	// policy.
+	Authorization map[string]Policy `json:"authorization"`
+
+	// Optional configuration for individual plugins. Anything left out
+	// gets the Kubernetes defaults.
+	Approve     Approve     `json:"approve"`
+	Assign      Assign      `json:"assign"`
+	CLA         CLA         `json:"cla"`
+	Hold        Hold        `json:"hold"`
+	Label       Label       `json:"label"`
+	LGTM        LGTM        `json:"lgtm"`
+	Lifecycle   Lifecycle   `json:"lifecycle"`
+	NeedsRebase NeedsRebase `json:"needs_rebase"`
+	Notify      Notify      `json:"notify"`
+	ReleaseNote ReleaseNote `json:"release_note"`
+	Size        Size        `json:"size"`
+}
+
+// Approve is the configuration for the approve plugin.
+type Approve struct {
+	// Label for PRs that every OWNERS file they touch has approved.
+	Label string `json:"label"`
+}
+
+// Assign is the configuration for the assign plugin.
+type Assign struct {
+	// How many reviewers to assign to a new PR. Zero turns off assigning
+	// reviewers, and leaving it out means two.
+	ReviewerCount *int `json:"reviewer_count"`
+	// Repo or org -> reviewers to pick from when no OWNERS file lists any
+	// for the changed files. Repo lists take precedence over org lists.
+	Reviewers map[string][]string `json:"reviewers"`
+}
+
+// ReviewersFor returns the configured reviewers for the repo, falling back on
+// those for its org.
+func (a Assign) ReviewersFor(org, repo string) []string {
+	if rs, ok := a.Reviewers[org+"/"+repo]; ok {
+		return rs
+	}
+	return a.Reviewers[org]
+}
+
+// CLA is the configuration for the cla plugin.
+type CLA struct {
+	// Status context set by the CLA bot.
+	Context string `json:"context"`
+	// Labels for PRs whose authors have and have not signed the CLA.
+	YesLabel string `json:"yes_label"`
+	NoLabel  string `json:"no_label"`
+	// Markdown comment telling the author how to sign the CLA.
+	NotFoundMessage string `json:"not_found_message"`
+}
+
+// Hold is the configuration for the hold plugin.
+type Hold struct {
+	// Label added by "/hold".
+	HoldLabel string `json:"hold_label"`
+	// Label for PRs whose titles start with "WIP".
+	WIPLabel string `json:"wip_label"`
+	// Status context that is pending while the PR has either label, and
+	// success otherwise.
+	Context string `json:"context"`
+}
+
+// Label is the configuration for the label plugin.
+type Label struct {
+	// Prefixes such as "priority" of which an issue may only have one label,
+	// such as "priority/P1", at a time.
+	ExclusivePrefixes []string `json:"exclusive_prefixes"`
+}
+
+// LGTM is the configuration for the lgtm plugin.
+type LGTM struct {
+	// Label for PRs that a reviewer has approved.
+	Label string `json:"label"`
+}
+
+// Lifecycle is the configuration for the lifecycle plugin.
+type Lifecycle struct {
+	// Days without activity before an open issue or PR goes stale.
+	StaleDays int `json:"stale_days"`
+	// Further days without activity before a stale one rots.
+	RottenDays int `json:"rotten_days"`
+	// Further days without activity before a rotten one is closed.
+	CloseDays int `json:"close_days"`
+}
+
+// NeedsRebase is the configuration for the needs-rebase plugin.
+type NeedsRebase struct {
+	// Label for PRs that no longer merge cleanly.
+	Label string `json:"label"`
+}
+
+// Notify is the configuration for the notify plugin.
+type Notify struct {
+	// Repo or org -> when to post messages and where.
+	Rules map[string][]NotifyRule `json:"rules"`
+}
+
+// NotifyRule posts a message to an incoming webhook, such as Slack's, when a
+// PR matches. Set exactly one of MergedPaths and Label.
+type NotifyRule struct {
+	// File that holds the URL to post the message to, such as a key of a
+	// mounted secret. Anyone with the URL can post, so it doesn't go in the
+	// config itself.
+	WebhookURLFile string `json:"webhook_url_file"`
+	// Post when a PR merges that changes a file whose path matches this
+	// regexp.
+	MergedPaths string `json:"merged_paths"`
+	// Post when this label is added to a PR.
+	Label string `json:"label"`
+	// Go template for the message. See the notify plugin for the fields.
+	// There is a default for each kind of rule.
+	Template string `json:"template"`
+
+	mergedPaths *regexp.Regexp
+	template    *template.Template
+}
+
+// MergedPathsRegexp returns the compiled MergedPaths, or nil if it isn't set.
+func (r NotifyRule) MergedPathsRegexp() *regexp.Regexp {
+	return r.mergedPaths
+}
+
+// MessageTemplate returns the compiled Template.
+func (r NotifyRule) MessageTemplate() *template.Template {
+	return r.template
+}
+
+const (
+	defaultNotifyMergedTemplate = `{{.Org}}/{{.Repo}}#{{.Number}} by {{.Author}} merged: <{{.URL}}|{{escape .Title}}>`
+	defaultNotifyLabelTemplate  = `{{.Org}}/{{.Repo}}#{{.Number}} by {{.Author}} is now {{escape .Label}}: <{{.URL}}|{{escape .Title}}>`
+)
+
+// slackEscaper escapes the characters that Slack treats as markup in message
+// text.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// notifyFuncs are the functions that notify templates may use.
+var notifyFuncs = template.FuncMap{"escape": slackEscaper.Replace}
+
+// parse compiles the regexp, if there is one, and the template, or else the
+// default template for the kind of rule.
+func (r NotifyRule) parse() (*regexp.Regexp, *template.Template, error) {
+	var re *regexp.Regexp
+	tmpl := defaultNotifyLabelTemplate
+	if r.MergedPaths != "" {
+		var err error
+		if re, err = regexp.Compile(r.MergedPaths); err != nil {
+			return nil, nil, err
+		}
+		tmpl = defaultNotifyMergedTemplate
+	}
+	if r.Template != "" {
+		tmpl = r.Template
+	}
+	t, err := template.New("message").Funcs(notifyFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, nil, err
+	}
+	return re, t, nil
+}
+
+// ReleaseNote is the configuration for the release-note plugin.
+type ReleaseNote struct {
+	// Label added by "/release-note".
+	Label string `json:"label"`
+	// Label added by "/release-note-none".
+	NoneLabel string `json:"none_label"`
+	// Other release note labels that the plugin replaces.
+	ActionRequiredLabel string `json:"action_required_label"`
+	LabelNeededLabel    string `json:"label_needed_label"`
+}
+
+// Labels returns every release note label.
+func (rn ReleaseNote) Labels() []string {
+	return []string{rn.NoneLabel, rn.ActionRequiredLabel, rn.LabelNeededLabel, rn.Label}
+}
+
+// Size is the configuration for the size plugin.
+type Size struct {
+	// Regexps matching the paths of generated files, which don't count
+	// towards the size of a PR.
+	GeneratedFiles []string `json:"generated_files"`
+
+	generatedFiles []*regexp.Regexp
+}
+
+// IsGenerated returns true if the path matches one of GeneratedFiles.
+func (s Size) IsGenerated(path string) bool {
+	for _, re := range s.generatedFiles {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Size) parse() ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, g := range s.GeneratedFiles {
+		re, err := regexp.Compile(g)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+const defaultCLANotFoundMessage = `Thanks for your pull request. Before we can look at your pull request, you'll need to sign a Contributor License Agreement (CLA).
+
+:memo: **Please follow instructions at <https://github.com/kubernetes/kubernetes/wiki/CLA-FAQ> to sign the CLA.**
+
+Once you've signed, please reply here (e.g. "I signed it!") and we'll verify.  Thanks.
+
+---
+
+- If you've already signed a CLA, it's possible we don't have your GitHub username or you're using a different email address.  Check your existing CLA data and verify that your [email is set on your git commits](https://help.github.com/articles/setting-your-email-in-git/).
+- If you signed the CLA as a corporation, please sign in with your organization's credentials at <https://identity.linuxfoundation.org/projects/cncf> to be authorized.`
+
+// setDefaults fills in the plugin configuration that was left out.
+func (c *Configuration) setDefaults() {
+	setDefault(&c.Approve.Label, "approved")
+	if c.Assign.ReviewerCount == nil {
+		n := 2
+		c.Assign.ReviewerCount = &n
+	}
+	setDefault(&c.CLA.Context, "cla/linuxfoundation")
+	setDefault(&c.CLA.YesLabel, "cncf-cla: yes")
+	setDefault(&c.CLA.NoLabel, "cncf-cla: no")
+	setDefault(&c.CLA.NotFoundMessage, defaultCLANotFoundMessage)
+	setDefault(&c.Hold.HoldLabel, "do-not-merge/hold")
+	setDefault(&c.Hold.WIPLabel, "do-not-merge/work-in-progress")
+	setDefault(&c.Hold.Context, "do-not-merge")
+	if c.Label.ExclusivePrefixes == nil {
+		c.Label.ExclusivePrefixes = []string{"priority"}
+	}
+	setDefault(&c.LGTM.Label, "lgtm")
+	if c.Lifecycle.StaleDays == 0 {
+		c.Lifecycle.StaleDays = 90
+	}
+	if c.Lifecycle.RottenDays == 0 {
+		c.Lifecycle.RottenDays = 30
+	}
+	if c.Lifecycle.CloseDays == 0 {
+		c.Lifecycle.CloseDays = 30
+	}
+	setDefault(&c.NeedsRebase.Label, "needs-rebase")
+	setDefault(&c.ReleaseNote.Label, "release-note")
+	setDefault(&c.ReleaseNote.NoneLabel, "release-note-none")
+	setDefault(&c.ReleaseNote.ActionRequiredLabel, "release-note-action-required")
+	setDefault(&c.ReleaseNote.LabelNeededLabel, "release-note-label-needed")
+}
+
+// compile compiles the notify and size regexps and templates for the plugins
+// to use. Validate has already checked them.
+func (c *Configuration) compile() error {
+	for k, rs := range c.Notify.Rules {
+		for i := range rs {
+			re, t, err := rs[i].parse()
+			if err != nil {
+				return fmt.Errorf("notify rule %d for %s is invalid: %v", i, k, err)
+			}
+			rs[i].mergedPaths = re
+			rs[i].template = t
+		}
+	}
+	res, err := c.Size.parse()
+	if err != nil {
+		return fmt.Errorf("size generated_files are invalid: %v", err)
+	}
+	c.Size.generatedFiles = res
+	return nil
+}
+
+func setDefault(s *string, d string) {
+	if *s == "" {
+		*s = d
+	}
+}
+
+// Settings describes who the bot trusts and who the bot is for an org or
+// repo.
+type Settings struct {
+	// Members of these orgs may trigger tests, and their PRs are tested
+	// without an "ok to test".
+	TrustedOrgs []string `json:"trusted_orgs"`
+	// Logins that the bot comments as. Plugins ignore their comments.
+	BotLogins []string `json:"bot_logins"`
+	// Prefix for bot commands, such as "@k8s-bot".
+	CommandPrefix string `json:"command_prefix"`
+}
+
+// IsBot returns true if the login belongs to the bot.
+func (s Settings) IsBot(login string) bool {
+	for _, b := range s.BotLogins {
+		if b == login {
+			return true
+		}
+	}
+	return false
+}
+
+// SettingsFor returns the settings for the repo, falling back on those for
+// its org. Set ensures that every org and repo with plugins has settings.
+// A nil config has empty settings.
+func (c *Configuration) SettingsFor(org, repo string) Settings {
+	if c == nil {
+		return Settings{}
+	}
+	if s, ok := c.Settings[org+"/"+repo]; ok {
+		return s
+	}
+	return c.Settings[org]
+}
+
 type PluginAgent struct {
 	PluginClient
 
-	mut sync.Mutex
-	// Repo (eg "k/k") -> list of handler names.
-	ps map[string][]string
+	mut           sync.Mutex
+	configuration *Configuration
 }
 
-// Load attempts to load config from the path. It returns an error if either
-// the file can't be read or it contains an unknown plugin.
-func (pa *PluginAgent) Load(path string) error {
-	pa.mut.Lock()
-	defer pa.mut.Unlock()
-	b, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
+// Set fills in defaults, compiles the regexps and templates, and then
+// replaces the plugin configuration. It returns every problem with the config,
+// one per line, and changes nothing if the config is invalid.
+func (pa *PluginAgent) Set(c *Configuration) error {
+	if errs := append(c.UnknownPlugins(), c.Validate()...); len(errs) > 0 {
+		var msgs []string
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		sort.Strings(msgs)
+		return errors.New(strings.Join(msgs, "\n"))
 	}
-	np := map[string][]string{}
-	if err := yaml.Unmarshal(b, &np); err != nil {
+	c.setDefaults()
+	if err := c.compile(); err != nil {
 		return err
 	}
-	// Check that there are no plugins that we don't know about.
-	for _, v := range np {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.configuration = c
+	return nil
+}
+
+// UnknownPlugins returns an error for each plugin in the config that hasn't
+// been registered. Only binaries that import the plugins can check this.
+func (c *Configuration) UnknownPlugins() []error {
+	var errs []error
+	for k, v := range c.Plugins {
 		for _, p := range v {
 			if _, ok := allPlugins[p]; !ok {
-				return fmt.Errorf("unknown plugin: %s", p)
+				errs = append(errs, fmt.Errorf("unknown plugin %s for %s", p, k))
 			}
 		}
 	}
+	return errs
+}
+
+// Validate returns every problem with the config other than unknown plugins:
+// plugins enabled for both a repo and its org, and missing or incomplete
+// settings. It doesn't change the config.
+func (c *Configuration) Validate() []error {
+	var errs []error
 	// Check that there are no duplicates.
-	for k, v := range np {
+	for k, v := range c.Plugins {
 		if strings.Contains(k, "/") {
 			org := strings.Split(k, "/")[0]
 			for _, p1 := range v {
-				for _, p2 := range np[org] {
+				for _, p2 := range c.Plugins[org] {
 					if p1 == p2 {
-						return fmt.Errorf("plugin %s is duplicated for %s and %s", p1, k, org)
+						errs = append(errs, fmt.Errorf("plugin %s is duplicated for %s and %s", p1, k, org))
 					}
 				}
 			}
 		}
 	}
-	pa.ps = np
-	return nil
-}
-
-// Start starts polling path for plugin config. If the first attempt fails,
-// then start returns the error. Future errors will halt updates but not stop.
-func (pa *PluginAgent) Start(path string) error {
-	if err := pa.Load(path); err != nil {
-		return err
+	// Check that the settings are complete.
+	for k, s := range c.Settings {
+		if len(s.TrustedOrgs) == 0 {
+			errs = append(errs, fmt.Errorf("settings for %s need at least one trusted org", k))
+		}
+		if len(s.BotLogins) == 0 {
+			errs = append(errs, fmt.Errorf("settings for %s need at least one bot login", k))
+		}
+		if s.CommandPrefix == "" {
+			errs = append(errs, fmt.Errorf("settings for %s need a command prefix", k))
+		}
+		for _, o := range s.TrustedOrgs {
+			if o == "" {
+				errs = append(errs, fmt.Errorf("settings for %s have an empty trusted org", k))
+			}
+		}
+		for _, b := range s.BotLogins {
+			if b == "" {
+				errs = append(errs, fmt.Errorf("settings for %s have an empty bot login", k))
+			}
+		}
+	}
+	errs = append(errs, c.validatePolicies()...)
+	if c.Assign.ReviewerCount != nil && *c.Assign.ReviewerCount < 0 {
+		errs = append(errs, fmt.Errorf("assign reviewer_count %d is negative", *c.Assign.ReviewerCount))
 	}
-	ticker := time.Tick(1 * time.Minute)
-	go func() {
-		for range ticker {
-			if err := pa.Load(path); err != nil {
-				logrus.WithField("path", path).WithError(err).Error("Error loading plugin config.")
+	if c.Lifecycle.StaleDays < 0 || c.Lifecycle.RottenDays < 0 || c.Lifecycle.CloseDays < 0 {
+		errs = append(errs, fmt.Errorf("lifecycle days must not be negative"))
+	}
+	// Check that the notify rules make sense.
+	for k, rs := range c.Notify.Rules {
+		for i, r := range rs {
+			if r.WebhookURLFile == "" {
+				errs = append(errs, fmt.Errorf("notify rule %d for %s needs a webhook_url_file", i, k))
+			}
+			if (r.MergedPaths == "") == (r.Label == "") {
+				errs = append(errs, fmt.Errorf("notify rule %d for %s needs exactly one of merged_paths and label", i, k))
+			}
+			if _, _, err := r.parse(); err != nil {
+				errs = append(errs, fmt.Errorf("notify rule %d for %s is invalid: %v", i, k, err))
 			}
 		}
-	}()
-	return nil
+	}
+	if _, err := c.Size.parse(); err != nil {
+		errs = append(errs, fmt.Errorf("size generated_files are invalid: %v", err))
+	}
+	// Check that the CLA labels can be told apart.
+	if c.CLA.YesLabel != "" && c.CLA.YesLabel == c.CLA.NoLabel {
+		errs = append(errs, fmt.Errorf("cla yes_label and no_label are both %s", c.CLA.YesLabel))
+	}
+	// Check that everything with plugins has settings.
+	for k := range c.Plugins {
+		if _, ok := c.Settings[k]; ok {
+			continue
+		}
+		if _, ok := c.Settings[strings.Split(k, "/")[0]]; !ok {
+			errs = append(errs, fmt.Errorf("no settings for %s", k))
+		}
+	}
+	return errs
+}
+
+// Config returns the current plugin configuration. Callers must not modify
+// it.
+func (pa *PluginAgent) Config() *Configuration {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	return pa.configuration
 }
 
 // IssueCommentHandlers returns a map of plugin names to handlers for the repo.
@@ -174,13 +613,79 @@ func (pa *PluginAgent) StatusEventHandlers(owner, repo string) map[string]Status
 	return hs
 }
 
+// ReviewEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *PluginAgent) ReviewEventHandlers(owner, repo string) map[string]ReviewEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]ReviewEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := reviewEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
+// ReviewCommentEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *PluginAgent) ReviewCommentEventHandlers(owner, repo string) map[string]ReviewCommentEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]ReviewCommentEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := reviewCommentHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
+// PushEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *PluginAgent) PushEventHandlers(owner, repo string) map[string]PushEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]PushEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := pushEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
+// PeriodicHandlers returns a map of orgs and repos to maps of plugin names to
+// periodic handlers.
+func (pa *PluginAgent) PeriodicHandlers() map[string]map[string]PeriodicHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]map[string]PeriodicHandler{}
+	for k, ps := range pa.configuration.Plugins {
+		for _, p := range ps {
+			if h, ok := periodicHandlers[p]; ok {
+				if hs[k] == nil {
+					hs[k] = map[string]PeriodicHandler{}
+				}
+				hs[k][p] = h
+			}
+		}
+	}
+
+	return hs
+}
+
 // getPlugins returns a list of plugins that are enabled on a given (org, repository).
 func (pa *PluginAgent) getPlugins(owner, repo string) []string {
 	var plugins []string
 
 	fullName := fmt.Sprintf("%s/%s", owner, repo)
-	plugins = append(plugins, pa.ps[owner]...)
-	plugins = append(plugins, pa.ps[fullName]...)
+	plugins = append(plugins, pa.configuration.Plugins[owner]...)
+	plugins = append(plugins, pa.configuration.Plugins[fullName]...)
 
 	return plugins
 }
@@ -17,13 +17,14 @@ limitations under the License.
 package plugins
 
 import (
+	"strings"
 	"testing"
 )
 
 func TestGetPlugins(t *testing.T) {
 	var testcases = []struct {
 		name            string
-		pluginMap       map[string][]string // this is read from the plugins.yaml file typically.
+		pluginMap       map[string][]string // this is read from the config.yaml file typically.
 		owner           string
 		repo            string
 		expectedPlugins []string
@@ -70,7 +71,7 @@ func TestGetPlugins(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		pa := PluginAgent{}
-		pa.ps = tc.pluginMap
+		pa.configuration = &Configuration{Plugins: tc.pluginMap}
 
 		plugins := pa.getPlugins(tc.owner, tc.repo)
 		if len(plugins) != len(tc.expectedPlugins) {
@@ -84,3 +85,290 @@ func TestGetPlugins(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate(t *testing.T) {
+	allPlugins["validate-test"] = struct{}{}
+	defer delete(allPlugins, "validate-test")
+	good := Settings{
+		TrustedOrgs:   []string{"org"},
+		BotLogins:     []string{"bot"},
+		CommandPrefix: "@bot",
+	}
+	var testcases = []struct {
+		name   string
+		config Configuration
+		valid  bool
+	}{
+		{
+			name: "org settings cover repos",
+			config: Configuration{
+				Plugins:  map[string][]string{"org/repo": {"validate-test"}},
+				Settings: map[string]Settings{"org": good},
+			},
+			valid: true,
+		},
+		{
+			name: "repo settings",
+			config: Configuration{
+				Plugins:  map[string][]string{"org/repo": {"validate-test"}},
+				Settings: map[string]Settings{"org/repo": good},
+			},
+			valid: true,
+		},
+		{
+			name: "unknown plugin",
+			config: Configuration{
+				Plugins:  map[string][]string{"org": {"not-a-plugin"}},
+				Settings: map[string]Settings{"org": good},
+			},
+		},
+		{
+			name: "duplicated plugin",
+			config: Configuration{
+				Plugins: map[string][]string{
+					"org":      {"validate-test"},
+					"org/repo": {"validate-test"},
+				},
+				Settings: map[string]Settings{"org": good},
+			},
+		},
+		{
+			name: "missing settings",
+			config: Configuration{
+				Plugins:  map[string][]string{"org/repo": {"validate-test"}},
+				Settings: map[string]Settings{"other-org": good},
+			},
+		},
+		{
+			name: "repo settings don't cover the org",
+			config: Configuration{
+				Plugins:  map[string][]string{"org": {"validate-test"}},
+				Settings: map[string]Settings{"org/repo": good},
+			},
+		},
+		{
+			name: "no trusted orgs",
+			config: Configuration{
+				Settings: map[string]Settings{"org": {BotLogins: []string{"bot"}, CommandPrefix: "@bot"}},
+			},
+		},
+		{
+			name: "no bot logins",
+			config: Configuration{
+				Settings: map[string]Settings{"org": {TrustedOrgs: []string{"org"}, CommandPrefix: "@bot"}},
+			},
+		},
+		{
+			name: "no command prefix",
+			config: Configuration{
+				Settings: map[string]Settings{"org": {TrustedOrgs: []string{"org"}, BotLogins: []string{"bot"}}},
+			},
+		},
+		{
+			name: "same cla labels",
+			config: Configuration{
+				CLA: CLA{YesLabel: "cla", NoLabel: "cla"},
+			},
+		},
+		{
+			name: "notify rules",
+			config: Configuration{
+				Notify: Notify{Rules: map[string][]NotifyRule{"org/repo": {
+					{WebhookURLFile: "/etc/notify/hooks", MergedPaths: "^pkg/"},
+					{WebhookURLFile: "/etc/notify/hooks", Label: "lgtm", Template: "{{.URL}}"},
+				}}},
+			},
+			valid: true,
+		},
+		{
+			name: "notify rule without a webhook",
+			config: Configuration{
+				Notify: Notify{Rules: map[string][]NotifyRule{"org": {{Label: "lgtm"}}}},
+			},
+		},
+		{
+			name: "notify rule with both conditions",
+			config: Configuration{
+				Notify: Notify{Rules: map[string][]NotifyRule{"org": {{WebhookURLFile: "/etc/notify/hooks", Label: "lgtm", MergedPaths: "."}}}},
+			},
+		},
+		{
+			name: "notify rule with a bad regexp",
+			config: Configuration{
+				Notify: Notify{Rules: map[string][]NotifyRule{"org": {{WebhookURLFile: "/etc/notify/hooks", MergedPaths: "("}}}},
+			},
+		},
+		{
+			name: "notify rule with a bad template",
+			config: Configuration{
+				Notify: Notify{Rules: map[string][]NotifyRule{"org": {{WebhookURLFile: "/etc/notify/hooks", Label: "lgtm", Template: "{{.URL"}}}},
+			},
+		},
+		{
+			name: "negative lifecycle days",
+			config: Configuration{
+				Lifecycle: Lifecycle{RottenDays: -1},
+			},
+		},
+		{
+			name: "bad generated file regexp",
+			config: Configuration{
+				Size: Size{GeneratedFiles: []string{"("}},
+			},
+		},
+		{
+			name: "authorization",
+			config: Configuration{
+				Authorization: map[string]Policy{
+					"close": {Roles: []string{RoleAuthor, RoleTeamMember}, Teams: []string{"org/team"}},
+					"lgtm":  {Roles: []string{RoleCollaborator}},
+				},
+			},
+			valid: true,
+		},
+		{
+			name: "authorization for an unknown command",
+			config: Configuration{
+				Authorization: map[string]Policy{"merge": {Roles: []string{RoleAuthor}}},
+			},
+		},
+		{
+			name: "authorization without roles",
+			config: Configuration{
+				Authorization: map[string]Policy{"close": {}},
+			},
+		},
+		{
+			name: "authorization with an unknown role",
+			config: Configuration{
+				Authorization: map[string]Policy{"close": {Roles: []string{"owner"}}},
+			},
+		},
+		{
+			name: "team_member role without teams",
+			config: Configuration{
+				Authorization: map[string]Policy{"close": {Roles: []string{RoleTeamMember}}},
+			},
+		},
+		{
+			name: "test authorization for authors",
+			config: Configuration{
+				Authorization: map[string]Policy{"test": {Roles: []string{RoleOrgMember, RoleAuthor}}},
+			},
+		},
+		{
+			name: "test authorization for assignees",
+			config: Configuration{
+				Authorization: map[string]Policy{"test": {Roles: []string{RoleAssignee}}},
+			},
+		},
+		{
+			name: "bad team",
+			config: Configuration{
+				Authorization: map[string]Policy{"close": {Roles: []string{RoleTeamMember}, Teams: []string{"team"}}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		errs := append(tc.config.UnknownPlugins(), tc.config.Validate()...)
+		if tc.valid && len(errs) > 0 {
+			t.Errorf("For case %s, didn't expect errors: %v", tc.name, errs)
+		} else if !tc.valid && len(errs) == 0 {
+			t.Errorf("For case %s, expected errors.", tc.name)
+		}
+	}
+}
+
+func TestSettingsFor(t *testing.T) {
+	c := &Configuration{
+		Settings: map[string]Settings{
+			"org":      {CommandPrefix: "@org-bot"},
+			"org/repo": {CommandPrefix: "@repo-bot"},
+		},
+	}
+	if p := c.SettingsFor("org", "repo").CommandPrefix; p != "@repo-bot" {
+		t.Errorf("Expected repo settings to win, got %s.", p)
+	}
+	if p := c.SettingsFor("org", "other").CommandPrefix; p != "@org-bot" {
+		t.Errorf("Expected org settings, got %s.", p)
+	}
+	var nilConfig *Configuration
+	if p := nilConfig.SettingsFor("org", "repo").CommandPrefix; p != "" {
+		t.Errorf("Expected empty settings for a nil config, got %s.", p)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	pa := &PluginAgent{}
+	if err := pa.Set(&Configuration{LGTM: LGTM{Label: "looks-good"}}); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	c := pa.Config()
+	if c.LGTM.Label != "looks-good" {
+		t.Errorf("Expected to keep the lgtm label, got %s.", c.LGTM.Label)
+	}
+	if c.CLA.Context != "cla/linuxfoundation" || c.CLA.YesLabel != "cncf-cla: yes" || c.CLA.NotFoundMessage == "" {
+		t.Errorf("Expected default cla config, got %+v.", c.CLA)
+	}
+	for _, l := range c.ReleaseNote.Labels() {
+		if l == "" {
+			t.Errorf("Expected default release note labels, got %+v.", c.ReleaseNote)
+		}
+	}
+	if c.Assign.ReviewerCount == nil || *c.Assign.ReviewerCount != 2 {
+		t.Errorf("Expected two reviewers by default, got %v.", c.Assign.ReviewerCount)
+	}
+
+	zero := 0
+	if err := pa.Set(&Configuration{
+		Assign: Assign{ReviewerCount: &zero},
+		Size:   Size{GeneratedFiles: []string{`^zz_generated\.`}},
+	}); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if n := *pa.Config().Assign.ReviewerCount; n != 0 {
+		t.Errorf("Expected zero reviewers to turn off assigning, got %d.", n)
+	}
+	if !pa.Config().Size.IsGenerated("zz_generated.deepcopy.go") {
+		t.Errorf("Expected the size regexps to be compiled by Set.")
+	}
+}
+
+func TestValidateDoesNotCompile(t *testing.T) {
+	c := &Configuration{
+		Notify: Notify{Rules: map[string][]NotifyRule{
+			"org": {{WebhookURLFile: "/etc/notify/org", MergedPaths: `^docs/`}},
+		}},
+		Size: Size{GeneratedFiles: []string{`^zz_generated\.`}},
+	}
+	if errs := c.Validate(); len(errs) > 0 {
+		t.Fatalf("Didn't expect errors: %v", errs)
+	}
+	if r := c.Notify.Rules["org"][0]; r.MergedPathsRegexp() != nil || r.MessageTemplate() != nil || c.Size.IsGenerated("zz_generated.go") {
+		t.Errorf("Expected Validate to leave the config alone, got %+v and %+v.", r, c.Size)
+	}
+	pa := &PluginAgent{}
+	if err := pa.Set(c); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if r := pa.Config().Notify.Rules["org"][0]; r.MergedPathsRegexp() == nil || r.MessageTemplate() == nil {
+		t.Errorf("Expected Set to compile the notify rule, got %+v.", r)
+	}
+}
+
+func TestSetReportsEveryError(t *testing.T) {
+	pa := &PluginAgent{}
+	err := pa.Set(&Configuration{
+		Settings: map[string]Settings{"org": {}},
+		Size:     Size{GeneratedFiles: []string{"("}},
+	})
+	if err == nil {
+		t.Fatal("Expected an error.")
+	}
+	if n := len(strings.Split(err.Error(), "\n")); n != 4 {
+		t.Errorf("Expected four errors, one per line, got %d: %v", n, err)
+	}
+	if pa.Config() != nil {
+		t.Errorf("Expected the config not to change, got %+v.", pa.Config())
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/owners"
+)
+
+// Roles that a policy can allow.
+const (
+	// The author of the issue or PR.
+	RoleAuthor = "author"
+	// An assignee of the issue or PR.
+	RoleAssignee = "assignee"
+	// A member of one of the policy's orgs, or of the repo's trusted orgs if
+	// the policy has none.
+	RoleOrgMember = "org_member"
+	// A member of one of the policy's teams.
+	RoleTeamMember = "team_member"
+	// An approver in the repo's top-level OWNERS file.
+	RoleApprover = "approver"
+	// A collaborator with write or admin access to the repo.
+	RoleCollaborator = "collaborator"
+)
+
+var allRoles = []string{RoleAuthor, RoleAssignee, RoleOrgMember, RoleTeamMember, RoleApprover, RoleCollaborator}
+
+// Policy says who may use a command. Anyone with one of its roles may.
+type Policy struct {
+	Roles []string `json:"roles"`
+	// Orgs for the org_member role. Default is the repo's trusted orgs.
+	Orgs []string `json:"orgs"`
+	// Teams for the team_member role, as "org/team-slug".
+	Teams []string `json:"teams"`
+}
+
+// defaultPolicies are the policies for commands that the config leaves out.
+// They are also the only commands that the config may set policies for.
+var defaultPolicies = map[string]Policy{
+	"close":        {Roles: []string{RoleAuthor, RoleAssignee}},
+	"reopen":       {Roles: []string{RoleAuthor, RoleAssignee}},
+	"lgtm":         {Roles: []string{RoleAssignee}},
+	"release-note": {Roles: []string{RoleAuthor, RoleAssignee}},
+	"test":         {Roles: []string{RoleOrgMember}},
+}
+
+// PolicyFor returns the policy for the command. A nil config uses the
+// default policies.
+func (c *Configuration) PolicyFor(command string) Policy {
+	if c == nil {
+		return defaultPolicies[command]
+	}
+	if p, ok := c.Authorization[command]; ok {
+		return p
+	}
+	return defaultPolicies[command]
+}
+
+// WhoCanUse describes who may use the command in the repo, such as "the
+// author or an assignee".
+func (c *Configuration) WhoCanUse(command, org, repo string) string {
+	p := c.PolicyFor(command)
+	var who []string
+	for _, r := range p.Roles {
+		switch r {
+		case RoleAuthor:
+			who = append(who, "the author")
+		case RoleAssignee:
+			who = append(who, "an assignee")
+		case RoleOrgMember:
+			who = append(who, "a member of "+strings.Join(c.policyOrgs(p, org, repo), " or "))
+		case RoleTeamMember:
+			who = append(who, "a member of "+strings.Join(p.Teams, " or "))
+		case RoleApprover:
+			who = append(who, "an approver in the top-level OWNERS file")
+		case RoleCollaborator:
+			who = append(who, "a collaborator with write access")
+		}
+	}
+	switch len(who) {
+	case 0:
+		return "nobody"
+	case 1:
+		return who[0]
+	case 2:
+		return who[0] + " or " + who[1]
+	}
+	return strings.Join(who[:len(who)-1], ", ") + ", or " + who[len(who)-1]
+}
+
+func (c *Configuration) policyOrgs(p Policy, org, repo string) []string {
+	if len(p.Orgs) > 0 {
+		return p.Orgs
+	}
+	return c.SettingsFor(org, repo).TrustedOrgs
+}
+
+// validatePolicies returns an error for each policy that names an unknown
+// command or role, or that has a role without what it needs.
+func (c *Configuration) validatePolicies() []error {
+	var errs []error
+	for command, p := range c.Authorization {
+		if _, ok := defaultPolicies[command]; !ok {
+			errs = append(errs, fmt.Errorf("authorization for unknown command %s", command))
+		}
+		if len(p.Roles) == 0 {
+			errs = append(errs, fmt.Errorf("authorization for %s needs at least one role", command))
+		}
+		for _, r := range p.Roles {
+			known := false
+			for _, k := range allRoles {
+				if r == k {
+					known = true
+				}
+			}
+			if !known {
+				errs = append(errs, fmt.Errorf("authorization for %s has unknown role %s", command, r))
+			}
+			if r == RoleTeamMember && len(p.Teams) == 0 {
+				errs = append(errs, fmt.Errorf("authorization for %s has the team_member role but no teams", command))
+			}
+			// The test policy also decides whose PRs are tested without an
+			// "ok to test", so it can't trust people for being the author.
+			if command == "test" && (r == RoleAuthor || r == RoleAssignee) {
+				errs = append(errs, fmt.Errorf("authorization for test can't have the %s role", r))
+			}
+		}
+		for _, t := range p.Teams {
+			if parts := strings.Split(t, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				errs = append(errs, fmt.Errorf("authorization for %s has team %q, which isn't of the form org/team", command, t))
+			}
+		}
+	}
+	return errs
+}
+
+// AuthClient is the subset of the GitHub client that an Authorizer uses.
+type AuthClient interface {
+	IsMember(org, user string) (bool, error)
+	ListTeams(org string) ([]github.Team, error)
+	IsTeamMember(id int, user string) (bool, error)
+	CollaboratorPermission(org, repo, user string) (string, error)
+	GetFile(org, repo, path, commit string) ([]byte, error)
+}
+
+// Authorizer checks whether users may use commands in one repo. It remembers
+// the answers to the lookups that it makes, so make a new one for each event.
+type Authorizer struct {
+	gc        AuthClient
+	config    *Configuration
+	org, repo string
+
+	// What was looked up -> the answer.
+	cache map[string]bool
+	// org -> team slug -> team ID
+	teams map[string]map[string]int
+	// The top-level OWNERS file, once loaded. It is nil if there is none.
+	owners       *owners.File
+	ownersLoaded bool
+}
+
+// NewAuthorizer returns an Authorizer for the repo.
+func NewAuthorizer(gc AuthClient, c *Configuration, org, repo string) *Authorizer {
+	return &Authorizer{
+		gc:     gc,
+		config: c,
+		org:    org,
+		repo:   repo,
+		cache:  map[string]bool{},
+		teams:  map[string]map[string]int{},
+	}
+}
+
+// Allowed returns true if the user may use the command on the issue or PR.
+// Lookups stop at the first role that the user has.
+func (a *Authorizer) Allowed(command, user string, issue github.Issue) (bool, error) {
+	p := a.config.PolicyFor(command)
+	for _, r := range p.Roles {
+		var ok bool
+		var err error
+		switch r {
+		case RoleAuthor:
+			ok = issue.IsAuthor(user)
+		case RoleAssignee:
+			ok = issue.IsAssignee(user)
+		case RoleOrgMember:
+			for _, o := range a.config.policyOrgs(p, a.org, a.repo) {
+				if ok, err = a.isMember(o, user); ok || err != nil {
+					break
+				}
+			}
+		case RoleTeamMember:
+			for _, t := range p.Teams {
+				if ok, err = a.isTeamMember(t, user); ok || err != nil {
+					break
+				}
+			}
+		case RoleApprover:
+			ok, err = a.isApprover(user)
+		case RoleCollaborator:
+			ok, err = a.isCollaborator(user)
+		}
+		if err != nil {
+			return false, err
+		} else if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// WhoCanUse describes who may use the command.
+func (a *Authorizer) WhoCanUse(command string) string {
+	return a.config.WhoCanUse(command, a.org, a.repo)
+}
+
+func (a *Authorizer) isMember(org, user string) (bool, error) {
+	key := "member:" + org + ":" + user
+	if m, ok := a.cache[key]; ok {
+		return m, nil
+	}
+	m, err := a.gc.IsMember(org, user)
+	if err != nil {
+		return false, fmt.Errorf("could not check membership in %s: %v", org, err)
+	}
+	a.cache[key] = m
+	return m, nil
+}
+
+func (a *Authorizer) isTeamMember(team, user string) (bool, error) {
+	key := "team:" + team + ":" + user
+	if m, ok := a.cache[key]; ok {
+		return m, nil
+	}
+	parts := strings.SplitN(team, "/", 2)
+	org, slug := parts[0], parts[1]
+	if _, ok := a.teams[org]; !ok {
+		ts, err := a.gc.ListTeams(org)
+		if err != nil {
+			return false, fmt.Errorf("could not list teams in %s: %v", org, err)
+		}
+		a.teams[org] = map[string]int{}
+		for _, t := range ts {
+			a.teams[org][t.Slug] = t.ID
+		}
+	}
+	id, ok := a.teams[org][slug]
+	if !ok {
+		return false, fmt.Errorf("no team %s", team)
+	}
+	m, err := a.gc.IsTeamMember(id, user)
+	if err != nil {
+		return false, fmt.Errorf("could not check membership in %s: %v", team, err)
+	}
+	a.cache[key] = m
+	return m, nil
+}
+
+func (a *Authorizer) isApprover(user string) (bool, error) {
+	if !a.ownersLoaded {
+		// The default branch has the OWNERS file that the repo goes by.
+		fs, err := owners.NewLoader(a.gc, a.org, a.repo, "").For("OWNERS")
+		if err != nil {
+			return false, err
+		}
+		if len(fs) > 0 {
+			a.owners = fs[0]
+		}
+		a.ownersLoaded = true
+	}
+	return a.owners != nil && a.owners.IsApprover(user), nil
+}
+
+func (a *Authorizer) isCollaborator(user string) (bool, error) {
+	key := "collaborator:" + user
+	if c, ok := a.cache[key]; ok {
+		return c, nil
+	}
+	p, err := a.gc.CollaboratorPermission(a.org, a.repo, user)
+	if err != nil {
+		return false, fmt.Errorf("could not check permission on %s/%s: %v", a.org, a.repo, err)
+	}
+	c := p == "admin" || p == "write"
+	a.cache[key] = c
+	return c, nil
+}
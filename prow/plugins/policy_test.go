@@ -0,0 +1,186 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+// countingClient counts the membership lookups that reach GitHub.
+type countingClient struct {
+	*fakegithub.FakeClient
+	isMemberCalls int
+}
+
+func (c *countingClient) IsMember(org, user string) (bool, error) {
+	c.isMemberCalls++
+	return c.FakeClient.IsMember(org, user)
+}
+
+func TestAllowed(t *testing.T) {
+	issue := github.Issue{
+		User:      github.User{Login: "author"},
+		Assignees: []github.User{{Login: "assignee"}},
+	}
+	var testcases = []struct {
+		name    string
+		policy  Policy
+		user    string
+		allowed bool
+	}{
+		{
+			name:    "author",
+			policy:  Policy{Roles: []string{RoleAuthor}},
+			user:    "author",
+			allowed: true,
+		},
+		{
+			name:   "not the author",
+			policy: Policy{Roles: []string{RoleAuthor}},
+			user:   "assignee",
+		},
+		{
+			name:    "assignee",
+			policy:  Policy{Roles: []string{RoleAssignee}},
+			user:    "assignee",
+			allowed: true,
+		},
+		{
+			name:    "member of a trusted org",
+			policy:  Policy{Roles: []string{RoleOrgMember}},
+			user:    "member",
+			allowed: true,
+		},
+		{
+			name:    "member of the policy's org",
+			policy:  Policy{Roles: []string{RoleOrgMember}, Orgs: []string{"other-org"}},
+			user:    "member",
+			allowed: true,
+		},
+		{
+			name:   "not an org member",
+			policy: Policy{Roles: []string{RoleOrgMember}},
+			user:   "author",
+		},
+		{
+			name:    "team member",
+			policy:  Policy{Roles: []string{RoleTeamMember}, Teams: []string{"org/b"}},
+			user:    "b-member",
+			allowed: true,
+		},
+		{
+			name:   "member of another team",
+			policy: Policy{Roles: []string{RoleTeamMember}, Teams: []string{"org/a"}},
+			user:   "b-member",
+		},
+		{
+			name:    "approver",
+			policy:  Policy{Roles: []string{RoleApprover}},
+			user:    "approver",
+			allowed: true,
+		},
+		{
+			name:   "not an approver",
+			policy: Policy{Roles: []string{RoleApprover}},
+			user:   "member",
+		},
+		{
+			name:    "collaborator with write access",
+			policy:  Policy{Roles: []string{RoleCollaborator}},
+			user:    "writer",
+			allowed: true,
+		},
+		{
+			name:   "collaborator with read access",
+			policy: Policy{Roles: []string{RoleCollaborator}},
+			user:   "reader",
+		},
+		{
+			name:    "second role",
+			policy:  Policy{Roles: []string{RoleAuthor, RoleCollaborator}},
+			user:    "writer",
+			allowed: true,
+		},
+	}
+	for _, tc := range testcases {
+		gc := &fakegithub.FakeClient{
+			OrgMembers: []string{"member"},
+			Teams: map[string][]github.Team{
+				"org": {{ID: 1, Slug: "a"}, {ID: 2, Slug: "b"}},
+			},
+			TeamMembers: map[int][]string{2: {"b-member"}},
+			RemoteFiles: map[string]map[string]string{
+				"OWNERS": {"": "approvers:\n- approver\n"},
+			},
+			Collaborators: map[string]string{"writer": "write", "reader": "read"},
+		}
+		c := &Configuration{
+			Settings:      map[string]Settings{"org": {TrustedOrgs: []string{"org"}}},
+			Authorization: map[string]Policy{"close": tc.policy},
+		}
+		allowed, err := NewAuthorizer(gc, c, "org", "repo").Allowed("close", tc.user, issue)
+		if err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+		} else if allowed != tc.allowed {
+			t.Errorf("For case %s, expected allowed %t, got %t.", tc.name, tc.allowed, allowed)
+		}
+	}
+}
+
+func TestAllowedCachesLookups(t *testing.T) {
+	gc := &countingClient{FakeClient: &fakegithub.FakeClient{OrgMembers: []string{"member"}}}
+	c := &Configuration{Settings: map[string]Settings{"org": {TrustedOrgs: []string{"org"}}}}
+	a := NewAuthorizer(gc, c, "org", "repo")
+	for i := 0; i < 3; i++ {
+		if allowed, err := a.Allowed("test", "member", github.Issue{}); err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		} else if !allowed {
+			t.Fatal("Expected member to be allowed.")
+		}
+	}
+	if gc.isMemberCalls != 1 {
+		t.Errorf("Expected one membership lookup, got %d.", gc.isMemberCalls)
+	}
+}
+
+func TestWhoCanUse(t *testing.T) {
+	c := &Configuration{
+		Settings: map[string]Settings{"org": {TrustedOrgs: []string{"org", "other-org"}}},
+		Authorization: map[string]Policy{
+			"lgtm":   {Roles: []string{RoleAssignee, RoleTeamMember, RoleCollaborator}, Teams: []string{"org/reviewers"}},
+			"reopen": {Roles: []string{RoleApprover}},
+		},
+	}
+	var testcases = []struct {
+		command  string
+		expected string
+	}{
+		{"close", "the author or an assignee"},
+		{"test", "a member of org or other-org"},
+		{"lgtm", "an assignee, a member of org/reviewers, or a collaborator with write access"},
+		{"reopen", "an approver in the top-level OWNERS file"},
+		{"unknown", "nobody"},
+	}
+	for _, tc := range testcases {
+		if who := c.WhoCanUse(tc.command, "org", "repo"); who != tc.expected {
+			t.Errorf("For command %s, expected %q, got %q.", tc.command, tc.expected, who)
+		}
+	}
+}
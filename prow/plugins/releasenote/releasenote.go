@@ -28,27 +28,39 @@ import (
 
 const pluginName = "release-note"
 
-const (
-	releaseNoteActionRequired = "release-note-action-required"
-	releaseNoteNone           = "release-note-none"
-	releaseNoteLabelNeeded    = "release-note-label-needed"
-	releaseNote               = "release-note"
-)
-
 var (
-	allRNLabels = []string{
-		releaseNoteNone,
-		releaseNoteActionRequired,
-		releaseNoteLabelNeeded,
-		releaseNote,
-	}
-
 	releaseNoteRe     = regexp.MustCompile(`(?mi)^\/release-note\r?$`)
 	releaseNoteNoneRe = regexp.MustCompile(`(?mi)^\/release-note-none\r?$`)
 )
 
 func init() {
-	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Sets the release note label of a PR.",
+		Commands: []plugins.Command{
+			{
+				Usage:       "/release-note",
+				Description: "Adds the " + c.ReleaseNote.Label + " label.",
+				Regex:       releaseNoteRe.String(),
+				Example:     "/release-note",
+				WhoCanUse:   c.WhoCanUse("release-note", org, repo),
+			},
+			{
+				Usage:       "/release-note-none",
+				Description: "Adds the " + c.ReleaseNote.NoneLabel + " label.",
+				Regex:       releaseNoteNoneRe.String(),
+				Example:     "/release-note-none",
+				WhoCanUse:   c.WhoCanUse("release-note", org, repo),
+			},
+		},
+		Config: map[string]string{
+			"release_note.label":      c.ReleaseNote.Label,
+			"release_note.none_label": c.ReleaseNote.NoneLabel,
+		},
+	}
 }
 
 type githubClient interface {
@@ -58,10 +70,11 @@ type githubClient interface {
 }
 
 func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
-	return handle(pc.GitHubClient, pc.Logger, ic)
+	auth := plugins.NewAuthorizer(pc.GitHubClient, pc.PluginConfig, ic.Repo.Owner.Login, ic.Repo.Name)
+	return handle(pc.GitHubClient, pc.Logger, pc.PluginConfig.ReleaseNote, auth, ic)
 }
 
-func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+func handle(gc githubClient, log *logrus.Entry, cfg plugins.ReleaseNote, auth *plugins.Authorizer, ic github.IssueCommentEvent) error {
 	// Only consider PRs and new comments.
 	if !ic.Issue.IsPullRequest() || ic.Action != "created" {
 		return nil
@@ -74,18 +87,20 @@ func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) err
 	// Which label does the comment want us to add?
 	var nl string
 	if releaseNoteRe.MatchString(ic.Comment.Body) {
-		nl = releaseNote
+		nl = cfg.Label
 	} else if releaseNoteNoneRe.MatchString(ic.Comment.Body) {
-		nl = releaseNoteNone
+		nl = cfg.NoneLabel
 	} else {
 		return nil
 	}
 
-	// Only allow authors and assignees to add labels.
-	isAssignee := ic.Issue.IsAssignee(ic.Comment.User.Login)
-	isAuthor := ic.Issue.IsAuthor(ic.Comment.User.Login)
-	if !isAssignee && !isAuthor {
-		resp := "you can only set release notes if you are the author or an assignee"
+	// Only allow the users that the policy allows to set release notes.
+	allowed, err := auth.Allowed("release-note", ic.Comment.User.Login, ic.Issue)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		resp := "you can only set release notes if you are " + auth.WhoCanUse("release-note")
 		log.Infof("Commenting with \"%s\".", resp)
 		return gc.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
 	}
@@ -99,7 +114,7 @@ func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) err
 		}
 	}
 	// Remove all other release-note-* labels if necessary.
-	for _, l := range allRNLabels {
+	for _, l := range cfg.Labels() {
 		if l != nl && ic.Issue.HasLabel(l) {
 			log.Infof("Removing %s label.", l)
 			if err := gc.RemoveLabel(org, repo, number, l); err != nil {
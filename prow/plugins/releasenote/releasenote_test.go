@@ -23,8 +23,23 @@ import (
 
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
 )
 
+const (
+	releaseNote               = "release-note"
+	releaseNoteNone           = "release-note-none"
+	releaseNoteActionRequired = "release-note-action-required"
+	releaseNoteLabelNeeded    = "release-note-label-needed"
+)
+
+var testConfig = plugins.ReleaseNote{
+	Label:               releaseNote,
+	NoneLabel:           releaseNoteNone,
+	ActionRequiredLabel: releaseNoteActionRequired,
+	LabelNeededLabel:    releaseNoteLabelNeeded,
+}
+
 func TestReleaseNoteComment(t *testing.T) {
 	var testcases = []struct {
 		name          string
@@ -121,7 +136,7 @@ func TestReleaseNoteComment(t *testing.T) {
 		for _, l := range tc.currentLabels {
 			ice.Issue.Labels = append(ice.Issue.Labels, github.Label{Name: l})
 		}
-		if err := handle(fc, logrus.WithField("plugin", pluginName), ice); err != nil {
+		if err := handle(fc, logrus.WithField("plugin", pluginName), testConfig, plugins.NewAuthorizer(fc, &plugins.Configuration{}, "org", "repo"), ice); err != nil {
 			t.Errorf("For case %s, did not expect error: %v", tc.name, err)
 		}
 		if tc.shouldComment && len(fc.IssueComments[5]) == 0 {
@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package size implements the size plugin, which labels PRs by how many lines
+// they change, from size/XS to size/XXL.
+package size
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "size"
+
+// sizes are the labels, smallest first, along with the number of changed
+// lines at which each one starts.
+var sizes = []struct {
+	label string
+	min   int
+}{
+	{"size/XS", 0},
+	{"size/S", 10},
+	{"size/M", 30},
+	{"size/L", 100},
+	{"size/XL", 500},
+	{"size/XXL", 1000},
+}
+
+func init() {
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	return plugins.PluginHelp{
+		Description: "Labels PRs from size/XS to size/XXL by how many lines they add and delete, not counting generated files.",
+		Config:      map[string]string{"size.generated_files": strings.Join(c.Size.GeneratedFiles, ", ")},
+	}
+}
+
+type githubClient interface {
+	GetPullRequestChanges(owner, repo string, number int) ([]github.PullRequestChange, error)
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+}
+
+func handlePullRequest(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handle(pc.GitHubClient, pc.Logger, pc.PluginConfig.Size, pre)
+}
+
+func handle(gc githubClient, log *logrus.Entry, cfg plugins.Size, pre github.PullRequestEvent) error {
+	switch pre.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return nil
+	}
+	pr := pre.PullRequest
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+
+	lines, err := changedLines(gc, cfg, pr)
+	if err != nil {
+		return err
+	}
+	want := sizeLabel(lines)
+
+	var errs []error
+	hasLabel := false
+	for _, l := range pr.Labels {
+		if l.Name == want {
+			hasLabel = true
+		} else if isSizeLabel(l.Name) {
+			log.Infof("Removing %s label.", l.Name)
+			if err := gc.RemoveLabel(org, repo, pr.Number, l.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if !hasLabel {
+		log.Infof("Adding %s label for %d changed lines.", want, lines)
+		if err := gc.AddLabel(org, repo, pr.Number, want); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors setting labels: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// changedLines returns the number of lines that the PR adds and deletes,
+// leaving out generated files. It only lists the files if some could be
+// generated.
+func changedLines(gc githubClient, cfg plugins.Size, pr github.PullRequest) (int, error) {
+	if len(cfg.GeneratedFiles) == 0 {
+		return pr.Additions + pr.Deletions, nil
+	}
+	changes, err := gc.GetPullRequestChanges(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
+	if err != nil {
+		return 0, err
+	}
+	lines := 0
+	for _, c := range changes {
+		if !cfg.IsGenerated(c.Filename) {
+			lines += c.Additions + c.Deletions
+		}
+	}
+	return lines, nil
+}
+
+func sizeLabel(lines int) string {
+	label := sizes[0].label
+	for _, s := range sizes {
+		if lines >= s.min {
+			label = s.label
+		}
+	}
+	return label
+}
+
+func isSizeLabel(label string) bool {
+	for _, s := range sizes {
+		if s.label == label {
+			return true
+		}
+	}
+	return false
+}
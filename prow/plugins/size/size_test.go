@@ -0,0 +1,149 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package size
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestSizeLabel(t *testing.T) {
+	var testcases = []struct {
+		lines int
+		label string
+	}{
+		{0, "size/XS"},
+		{9, "size/XS"},
+		{10, "size/S"},
+		{29, "size/S"},
+		{30, "size/M"},
+		{99, "size/M"},
+		{100, "size/L"},
+		{499, "size/L"},
+		{500, "size/XL"},
+		{999, "size/XL"},
+		{1000, "size/XXL"},
+		{100000, "size/XXL"},
+	}
+	for _, tc := range testcases {
+		if label := sizeLabel(tc.lines); label != tc.label {
+			t.Errorf("For %d lines, expected %s, got %s.", tc.lines, tc.label, label)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	pa := &plugins.PluginAgent{}
+	if err := pa.Set(&plugins.Configuration{
+		Size: plugins.Size{GeneratedFiles: []string{`^vendor/`, `zz_generated`}},
+	}); err != nil {
+		t.Fatalf("Error setting plugin config: %v", err)
+	}
+	withGenerated := pa.Config().Size
+
+	var testcases = []struct {
+		name      string
+		action    string
+		cfg       plugins.Size
+		additions int
+		deletions int
+		changes   []github.PullRequestChange
+		labels    []string
+		added     []string
+		removed   []string
+	}{
+		{
+			name:      "new small PR",
+			action:    "opened",
+			additions: 5,
+			deletions: 3,
+			added:     []string{"org/repo#5:size/XS"},
+		},
+		{
+			name:      "grew",
+			action:    "synchronize",
+			additions: 400,
+			deletions: 200,
+			labels:    []string{"size/M", "lgtm"},
+			added:     []string{"org/repo#5:size/XL"},
+			removed:   []string{"org/repo#5:size/M"},
+		},
+		{
+			name:      "same size",
+			action:    "synchronize",
+			additions: 50,
+			labels:    []string{"size/M"},
+		},
+		{
+			name:      "not a new commit",
+			action:    "labeled",
+			additions: 50,
+		},
+		{
+			name:      "generated files don't count",
+			action:    "opened",
+			cfg:       withGenerated,
+			additions: 5020,
+			deletions: 10,
+			changes: []github.PullRequestChange{
+				{Filename: "vendor/lib/lib.go", Additions: 4000},
+				{Filename: "pkg/api/zz_generated.deepcopy.go", Additions: 1000},
+				{Filename: "pkg/api/types.go", Additions: 20, Deletions: 10},
+			},
+			added: []string{"org/repo#5:size/M"},
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			PullRequestChanges: map[int][]github.PullRequestChange{5: tc.changes},
+		}
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 5,
+			PullRequest: github.PullRequest{
+				Number:    5,
+				Additions: tc.additions,
+				Deletions: tc.deletions,
+				Base: github.PullRequestBranch{
+					Repo: github.Repo{
+						Owner: github.User{Login: "org"},
+						Name:  "repo",
+					},
+				},
+			},
+		}
+		for _, l := range tc.labels {
+			pre.PullRequest.Labels = append(pre.PullRequest.Labels, github.Label{Name: l})
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), tc.cfg, pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.added, fc.LabelsAdded) {
+			t.Errorf("For case %s, expected %v added, got %v.", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if !reflect.DeepEqual(tc.removed, fc.LabelsRemoved) {
+			t.Errorf("For case %s, expected %v removed, got %v.", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+	}
+}
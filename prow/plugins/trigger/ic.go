@@ -21,10 +21,15 @@ import (
 	"regexp"
 
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/jobs"
 	"k8s.io/test-infra/prow/plugins"
 )
 
-var okToTest = regexp.MustCompile(`(?m)^(@k8s-bot )?ok to test\r?$`)
+// retestRe matches "/retest" or the command prefix and "retest" on its own
+// line, which reruns every job that failed on the PR.
+func retestRe(s plugins.Settings) *regexp.Regexp {
+	return regexp.MustCompile(`(?mi)^(/|` + regexp.QuoteMeta(s.CommandPrefix) + ` )retest\r?$`)
+}
 
 func handleIC(c client, ic github.IssueCommentEvent) error {
 	org := ic.Repo.Owner.Login
@@ -43,22 +48,24 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 		return nil
 	}
 	// Skip bot comments.
-	if author == "k8s-bot" || author == "k8s-ci-robot" {
+	if c.Settings.IsBot(author) {
 		return nil
 	}
 
-	// Which jobs does the comment want us to run?
-	requestedJobs := c.JobAgent.MatchingJobs(ic.Repo.FullName, ic.Comment.Body, okToTest)
-	if len(requestedJobs) == 0 {
+	// Does the comment want us to run any jobs at all? We can't tell which
+	// ones until we know the PR's branch and changes.
+	okToTest := okToTestRe(c.Settings)
+	retest := retestRe(c.Settings).MatchString(ic.Comment.Body)
+	if !retest && !requestsJobs(c.JobAgent.AllJobs(ic.Repo.FullName), ic.Comment.Body, okToTest) {
 		return nil
 	}
 
 	// Skip untrusted users.
-	orgMember, err := c.GitHubClient.IsMember(trustedOrg, author)
+	orgMember, err := c.Auth.Allowed("test", author, ic.Issue)
 	if err != nil {
 		return err
 	} else if !orgMember {
-		resp := fmt.Sprintf("you can't request testing unless you are a [%s](https://github.com/%s/people) member", trustedOrg, trustedOrg)
+		resp := fmt.Sprintf("you can't request testing unless you are %s", c.Auth.WhoCanUse("test"))
 		c.Logger.Infof("Commenting \"%s\".", resp)
 		return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
 	}
@@ -68,6 +75,26 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 		return err
 	}
 
+	requestedJobs, err := c.JobAgent.MatchingJobs(ic.Repo.FullName, pr.Base.Ref, ic.Comment.Body, okToTest, changedFiles(c.GitHubClient, *pr))
+	if err != nil {
+		return err
+	}
+	if retest {
+		failed, err := failedJobs(c, ic.Repo.FullName, *pr)
+		if err != nil {
+			return err
+		}
+		requestedJobs = addJobs(requestedJobs, failed)
+		if len(requestedJobs) == 0 {
+			resp := "there are no failed jobs to retest"
+			c.Logger.Infof("Commenting \"%s\".", resp)
+			return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatResponse(ic.Comment, resp))
+		}
+	}
+	if len(requestedJobs) == 0 {
+		return nil
+	}
+
 	ref, err := c.GitHubClient.GetRef(org, repo, "heads/"+pr.Base.Ref)
 	if err != nil {
 		return err
@@ -84,3 +111,52 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 	}
 	return nil
 }
+
+// requestsJobs returns true if the comment might ask for any of the jobs.
+func requestsJobs(js []jobs.JenkinsJob, body string, okToTest *regexp.Regexp) bool {
+	ott := okToTest.MatchString(body)
+	for _, job := range js {
+		if job.TriggerMatches(body) || (ott && (job.AlwaysRun || job.RunIfChanged != "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedJobs returns the jobs whose latest status on the PR's head is failure
+// or error.
+func failedJobs(c client, fullRepoName string, pr github.PullRequest) ([]jobs.JenkinsJob, error) {
+	cs, err := c.GitHubClient.GetCombinedStatus(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Head.SHA)
+	if err != nil {
+		return nil, err
+	}
+	failed := map[string]bool{}
+	for _, s := range cs.Statuses {
+		if s.State == github.StatusFailure || s.State == github.StatusError {
+			failed[s.Context] = true
+		}
+	}
+	var js []jobs.JenkinsJob
+	for _, job := range c.JobAgent.AllJobs(fullRepoName) {
+		if failed[job.Context] && job.RunsAgainstBranch(pr.Base.Ref) {
+			js = append(js, job)
+		}
+	}
+	return js, nil
+}
+
+// addJobs adds the jobs to js unless a job with the same context is already
+// there. Jobs may share a name, but not a context.
+func addJobs(js, more []jobs.JenkinsJob) []jobs.JenkinsJob {
+	contexts := map[string]bool{}
+	for _, job := range js {
+		contexts[job.Context] = true
+	}
+	for _, job := range more {
+		if !contexts[job.Context] {
+			contexts[job.Context] = true
+			js = append(js, job)
+		}
+	}
+	return js
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package trigger
 
 import (
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/Sirupsen/logrus"
@@ -111,6 +113,8 @@ func TestHandleIssueComment(t *testing.T) {
 			GitHubClient: g,
 			JobAgent:     &jobs.JobAgent{},
 			Logger:       logrus.WithField("plugin", pluginName),
+			Settings:     testSettings,
+			Auth:         testAuth(g),
 		}
 		c.JobAgent.SetJobs(map[string][]jobs.JenkinsJob{
 			"org/repo": {
@@ -167,3 +171,156 @@ func TestHandleIssueComment(t *testing.T) {
 		}
 	}
 }
+
+func TestRetest(t *testing.T) {
+	var testcases = []struct {
+		name          string
+		author        string
+		body          string
+		statuses      []github.Status
+		expected      []string
+		shouldComment bool
+	}{
+		{
+			name:   "reruns failures and errors only",
+			author: "t",
+			body:   "/retest",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusFailure},
+				{Context: "e2e", State: github.StatusError},
+				{Context: "verify", State: github.StatusSuccess},
+				{Context: "not a job", State: github.StatusFailure},
+			},
+			expected: []string{"unit", "e2e"},
+		},
+		{
+			name:   "with the command prefix",
+			author: "t",
+			body:   "@k8s-bot retest",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusFailure},
+			},
+			expected: []string{"unit"},
+		},
+		{
+			name:   "skips jobs for other branches",
+			author: "t",
+			body:   "/retest",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusFailure},
+				{Context: "release", State: github.StatusFailure},
+			},
+			expected: []string{"unit"},
+		},
+		{
+			name:   "nothing failed",
+			author: "t",
+			body:   "/retest",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusSuccess},
+				{Context: "e2e", State: github.StatusPending},
+			},
+			shouldComment: true,
+		},
+		{
+			name:   "retest and a trigger for the same job",
+			author: "t",
+			body:   "/retest\n@k8s-bot unit test this",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusFailure},
+				{Context: "e2e", State: github.StatusFailure},
+			},
+			expected: []string{"unit", "e2e"},
+		},
+		{
+			name:   "untrusted user",
+			author: "u",
+			body:   "/retest",
+			statuses: []github.Status{
+				{Context: "unit", State: github.StatusFailure},
+			},
+			shouldComment: true,
+		},
+	}
+	for _, tc := range testcases {
+		g := &fakegithub.FakeClient{
+			IssueComments: map[int][]github.IssueComment{},
+			OrgMembers:    []string{"t"},
+			PullRequests: map[int]*github.PullRequest{
+				5: {
+					Number: 5,
+					Base: github.PullRequestBranch{
+						Ref: "master",
+						Repo: github.Repo{
+							Owner: github.User{Login: "org"},
+							Name:  "repo",
+						},
+					},
+					Head: github.PullRequestBranch{SHA: "head"},
+				},
+			},
+			CombinedStatuses: map[string]*github.CombinedStatus{
+				"head": {SHA: "head", Statuses: tc.statuses},
+			},
+		}
+		c := client{
+			GitHubClient: g,
+			JobAgent:     &jobs.JobAgent{},
+			Logger:       logrus.WithField("plugin", pluginName),
+			Settings:     testSettings,
+			Auth:         testAuth(g),
+		}
+		c.JobAgent.SetJobs(map[string][]jobs.JenkinsJob{
+			"org/repo": {
+				{Name: "unit-job", Context: "unit", Trigger: "@k8s-bot unit test this", AlwaysRun: true},
+				{Name: "e2e-job", Context: "e2e", Trigger: "@k8s-bot e2e test this"},
+				{Name: "verify-job", Context: "verify", Trigger: "@k8s-bot verify test this"},
+				{Name: "release-job", Context: "release", Trigger: "@k8s-bot release test this", Branches: []string{"release-.*"}},
+			},
+		})
+		event := github.IssueCommentEvent{
+			Action: "created",
+			Repo: github.Repo{
+				Owner:    github.User{Login: "org"},
+				Name:     "repo",
+				FullName: "org/repo",
+			},
+			Comment: github.IssueComment{
+				Body: tc.body,
+				User: github.User{Login: tc.author},
+			},
+			Issue: github.Issue{
+				Number:      5,
+				PullRequest: &struct{}{},
+				State:       "open",
+			},
+		}
+
+		oldLineStartPRJob := lineStartPRJob
+		oldLineDeletePRJob := lineDeletePRJob
+		defer func() {
+			lineStartPRJob = oldLineStartPRJob
+			lineDeletePRJob = oldLineDeletePRJob
+		}()
+		var started []string
+		lineStartPRJob = func(k *kube.Client, jobName, context string, pr github.PullRequest, ref string) error {
+			started = append(started, context)
+			return nil
+		}
+		lineDeletePRJob = func(k *kube.Client, jobName string, pr github.PullRequest) error {
+			return nil
+		}
+		if err := handleIC(c, event); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		sort.Strings(started)
+		sort.Strings(tc.expected)
+		if !reflect.DeepEqual(started, tc.expected) {
+			t.Errorf("For case %s, expected %v to start, got %v.", tc.name, tc.expected, started)
+		}
+		if tc.shouldComment != (len(g.IssueComments[5]) == 1) {
+			t.Errorf("For case %s, expected comment %t, got %v.", tc.name, tc.shouldComment, g.IssueComments[5])
+		}
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 
 	"k8s.io/test-infra/prow/github"
-	"k8s.io/test-infra/prow/line"
 	"k8s.io/test-infra/prow/plugins"
 )
 
@@ -30,15 +29,15 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 		// When a PR is opened, if the author is in the org then build it.
 		// Otherwise, ask for "ok to test". There's no need to look for previous
 		// "ok to test" comments since the PR was just opened!
-		member, err := c.GitHubClient.IsMember(trustedOrg, pr.PullRequest.User.Login)
+		member, err := trustedUser(c.Auth, pr.PullRequest.User.Login, pr.PullRequest)
 		if err != nil {
-			return fmt.Errorf("could not check membership: %s", err)
+			return err
 		} else if member {
 			c.Logger.Info("Starting all jobs for new PR.")
 			return buildAll(c, pr.PullRequest)
 		} else {
-			c.Logger.Info("Asking PR author to join the org.")
-			if err := askToJoin(c.GitHubClient, pr.PullRequest); err != nil {
+			c.Logger.Info("Asking for ok to test.")
+			if err := askToJoin(c.GitHubClient, c.Auth, c.Settings, pr.PullRequest); err != nil {
 				return fmt.Errorf("could not ask to join: %s", err)
 			}
 		}
@@ -46,7 +45,7 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 		// When a PR is updated, check that the user is in the org or that an org
 		// member has said "ok to test" before building. There's no need to ask
 		// for "ok to test" because we do that once when the PR is created.
-		trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest)
+		trusted, err := trustedPullRequest(c.GitHubClient, c.Auth, c.Settings, pr.PullRequest)
 		if err != nil {
 			return fmt.Errorf("could not validate PR: %s", err)
 		} else if trusted {
@@ -58,8 +57,8 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 		return deleteAll(c, pr.PullRequest)
 	case "labeled":
 		// When a PR is LGTMd, if it is untrusted then build it once.
-		if pr.Label.Name == lgtmLabel {
-			trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest)
+		if c.LGTMLabel != "" && pr.Label.Name == c.LGTMLabel {
+			trusted, err := trustedPullRequest(c.GitHubClient, c.Auth, c.Settings, pr.PullRequest)
 			if err != nil {
 				return fmt.Errorf("could not validate PR: %s", err)
 			} else if !trusted {
@@ -71,17 +70,17 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 	return nil
 }
 
-func askToJoin(ghc githubClient, pr github.PullRequest) error {
+func askToJoin(ghc githubClient, auth *plugins.Authorizer, s plugins.Settings, pr github.PullRequest) error {
 	commentTemplate := `Hi @%s. Thanks for your PR.
 
-I'm waiting for a [%s](https://github.com/orgs/%s/people) member to verify that this patch is reasonable to test. If it is, they should reply with ` + "`@k8s-bot ok to test`" + ` on its own line. Until that is done, I will not automatically test new commits in this PR, but the usual testing commands by org members will still work. Regular contributors should join the org to skip this step.
+I'm waiting for %s to verify that this patch is reasonable to test. If it is, they should reply with ` + "`%s ok to test`" + ` on its own line. Until that is done, I will not automatically test new commits in this PR, but they can still start tests with the usual commands. Regular contributors should ask to be trusted to skip this step.
 
 <details>
 
 %s
 </details>
 `
-	comment := fmt.Sprintf(commentTemplate, pr.User.Login, trustedOrg, trustedOrg, plugins.AboutThisBot)
+	comment := fmt.Sprintf(commentTemplate, pr.User.Login, auth.WhoCanUse("test"), s.CommandPrefix, plugins.AboutThisBot)
 
 	owner := pr.Base.Repo.Owner.Login
 	name := pr.Base.Repo.Name
@@ -89,18 +88,19 @@ I'm waiting for a [%s](https://github.com/orgs/%s/people) member to verify that
 }
 
 // trustedPullRequest returns whether or not the given PR should be tested.
-// It first checks if the author is in the org, then looks for "ok to test
-// comments by org members.
-func trustedPullRequest(ghc githubClient, pr github.PullRequest) (bool, error) {
+// It first checks if the author is trusted, then looks for "ok to test"
+// comments by trusted users.
+func trustedPullRequest(ghc githubClient, auth *plugins.Authorizer, s plugins.Settings, pr github.PullRequest) (bool, error) {
 	author := pr.User.Login
-	// First check if the author is a member of the org.
-	orgMember, err := ghc.IsMember(trustedOrg, author)
+	// First check if the author is trusted.
+	orgMember, err := trustedUser(auth, author, pr)
 	if err != nil {
 		return false, err
 	} else if orgMember {
 		return true, nil
 	}
 	// Next look for "ok to test" comments on the PR.
+	okToTest := okToTestRe(s)
 	comments, err := ghc.ListIssueComments(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
 	if err != nil {
 		return false, err
@@ -112,15 +112,15 @@ func trustedPullRequest(ghc githubClient, pr github.PullRequest) (bool, error) {
 			continue
 		}
 		// Skip bot comments.
-		if commentAuthor == "k8s-bot" || commentAuthor == "k8s-ci-robot" {
+		if s.IsBot(commentAuthor) {
 			continue
 		}
 		// Look for "ok to test"
 		if !okToTest.MatchString(comment.Body) {
 			continue
 		}
-		// Ensure that the commenter is in the org.
-		commentAuthorMember, err := ghc.IsMember(trustedOrg, commentAuthor)
+		// Ensure that the commenter is trusted.
+		commentAuthorMember, err := trustedUser(auth, commentAuthor, pr)
 		if err != nil {
 			return false, err
 		} else if commentAuthorMember {
@@ -131,11 +131,12 @@ func trustedPullRequest(ghc githubClient, pr github.PullRequest) (bool, error) {
 }
 
 func buildAll(c client, pr github.PullRequest) error {
+	toRun, err := c.JobAgent.TestAllJobs(pr.Base.Repo.FullName, pr.Base.Ref, changedFiles(c.GitHubClient, pr))
+	if err != nil {
+		return err
+	}
 	var ref string
-	for _, job := range c.JobAgent.AllJobs(pr.Base.Repo.FullName) {
-		if !job.AlwaysRun {
-			continue
-		}
+	for _, job := range toRun {
 		// Only get master ref once.
 		if ref == "" {
 			r, err := c.GitHubClient.GetRef(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, "heads/"+pr.Base.Ref)
@@ -144,7 +145,7 @@ func buildAll(c client, pr github.PullRequest) error {
 			}
 			ref = r
 		}
-		if err := line.StartPRJob(c.KubeClient, job.Name, job.Context, pr, ref); err != nil {
+		if err := lineStartPRJob(c.KubeClient, job.Name, job.Context, pr, ref); err != nil {
 			return err
 		}
 	}
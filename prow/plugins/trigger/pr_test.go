@@ -17,10 +17,18 @@ limitations under the License.
 package trigger
 
 import (
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/Sirupsen/logrus"
+
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/plugins"
 )
 
 func TestTrusted(t *testing.T) {
@@ -142,7 +150,7 @@ func TestTrusted(t *testing.T) {
 				0: tc.Comments,
 			},
 		}
-		trusted, err := trustedPullRequest(g, tc.PR)
+		trusted, err := trustedPullRequest(g, testAuth(g), testSettings, tc.PR)
 		if err != nil {
 			t.Fatalf("Didn't expect error: %s", err)
 		}
@@ -151,3 +159,169 @@ func TestTrusted(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildAll(t *testing.T) {
+	var testcases = []struct {
+		Branch       string
+		Changes      []string
+		ExpectedJobs []string
+	}{
+		{
+			Branch:       "master",
+			Changes:      []string{"pkg/foo.go"},
+			ExpectedJobs: []string{"unit"},
+		},
+		{
+			Branch:       "master",
+			Changes:      []string{"pkg/foo.go", "docs/foo.md"},
+			ExpectedJobs: []string{"docs", "unit"},
+		},
+		{
+			Branch:       "release-1.5",
+			Changes:      []string{"pkg/foo.go"},
+			ExpectedJobs: []string{"release", "unit"},
+		},
+	}
+	for _, tc := range testcases {
+		var changes []github.PullRequestChange
+		for _, f := range tc.Changes {
+			changes = append(changes, github.PullRequestChange{Filename: f})
+		}
+		g := &fakegithub.FakeClient{
+			PullRequestChanges: map[int][]github.PullRequestChange{5: changes},
+		}
+		c := client{
+			GitHubClient: g,
+			JobAgent:     &jobs.JobAgent{},
+			Logger:       logrus.WithField("plugin", pluginName),
+		}
+		if err := c.JobAgent.SetJobs(map[string][]jobs.JenkinsJob{
+			"org/repo": {
+				{
+					Name:      "unit",
+					AlwaysRun: true,
+				},
+				{
+					Name:      "release",
+					AlwaysRun: true,
+					Branches:  []string{"release-.*"},
+				},
+				{
+					Name:         "docs",
+					RunIfChanged: "^docs/",
+				},
+				{
+					Name: "manual",
+				},
+			},
+		}); err != nil {
+			t.Fatalf("Could not set jobs: %v", err)
+		}
+
+		oldLineStartPRJob := lineStartPRJob
+		defer func() { lineStartPRJob = oldLineStartPRJob }()
+		var startedJobs []string
+		lineStartPRJob = func(k *kube.Client, jobName, context string, pr github.PullRequest, ref string) error {
+			startedJobs = append(startedJobs, jobName)
+			return nil
+		}
+
+		pr := github.PullRequest{
+			Number: 5,
+			Base: github.PullRequestBranch{
+				Ref: tc.Branch,
+				Repo: github.Repo{
+					Owner:    github.User{Login: "org"},
+					Name:     "repo",
+					FullName: "org/repo",
+				},
+			},
+		}
+		if err := buildAll(c, pr); err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		sort.Strings(startedJobs)
+		if !reflect.DeepEqual(startedJobs, tc.ExpectedJobs) {
+			t.Errorf("For branch %s and changes %v, expected jobs %v, started %v.", tc.Branch, tc.Changes, tc.ExpectedJobs, startedJobs)
+		}
+	}
+}
+
+func TestHandlePRLabeled(t *testing.T) {
+	var testcases = []struct {
+		label string
+		build bool
+	}{
+		{label: "looks-good", build: true},
+		{label: "lgtm", build: false},
+		{label: "size/XS", build: false},
+	}
+	for _, tc := range testcases {
+		g := &fakegithub.FakeClient{OrgMembers: []string{"t1"}}
+		c := client{
+			GitHubClient: g,
+			JobAgent:     &jobs.JobAgent{},
+			Logger:       logrus.WithField("plugin", pluginName),
+			Settings:     testSettings,
+			Auth:         testAuth(g),
+			LGTMLabel:    "looks-good",
+		}
+		if err := c.JobAgent.SetJobs(map[string][]jobs.JenkinsJob{
+			"org/repo": {{Name: "unit", AlwaysRun: true}},
+		}); err != nil {
+			t.Fatalf("Could not set jobs: %v", err)
+		}
+
+		oldLineStartPRJob := lineStartPRJob
+		var startedJobs []string
+		lineStartPRJob = func(k *kube.Client, jobName, context string, pr github.PullRequest, ref string) error {
+			startedJobs = append(startedJobs, jobName)
+			return nil
+		}
+
+		pr := github.PullRequestEvent{
+			Action: "labeled",
+			Label:  github.Label{Name: tc.label},
+			PullRequest: github.PullRequest{
+				User: github.User{Login: "u"},
+				Base: github.PullRequestBranch{
+					Ref: "master",
+					Repo: github.Repo{
+						Owner:    github.User{Login: "org"},
+						Name:     "repo",
+						FullName: "org/repo",
+					},
+				},
+			},
+		}
+		err := handlePR(c, pr)
+		lineStartPRJob = oldLineStartPRJob
+		if err != nil {
+			t.Fatalf("For label %s, didn't expect error: %v", tc.label, err)
+		}
+		if built := len(startedJobs) > 0; built != tc.build {
+			t.Errorf("For label %s, expected build %t, got %t.", tc.label, tc.build, built)
+		}
+	}
+}
+
+func TestAskToJoinNamesPolicy(t *testing.T) {
+	g := &fakegithub.FakeClient{IssueComments: map[int][]github.IssueComment{}}
+	c := &plugins.Configuration{
+		Settings:      map[string]plugins.Settings{"org": testSettings},
+		Authorization: map[string]plugins.Policy{"test": {Roles: []string{plugins.RoleTeamMember}, Teams: []string{"org/testers"}}},
+	}
+	pr := github.PullRequest{
+		Number: 5,
+		User:   github.User{Login: "u"},
+		Base: github.PullRequestBranch{
+			Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+		},
+	}
+	if err := askToJoin(g, plugins.NewAuthorizer(g, c, "org", "repo"), testSettings, pr); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if ics := g.IssueComments[5]; len(ics) != 1 || !strings.Contains(ics[0].Body, "waiting for a member of org/testers to verify") {
+		t.Errorf("Expected the comment to name the test policy, got %+v.", ics)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/line"
+)
+
+func handlePE(c client, pe github.PushEvent) error {
+	// Skip tags and deleted branches.
+	branch := pe.Branch()
+	if branch == "" || pe.Deleted {
+		return nil
+	}
+	// Start the rest of the jobs even if one fails.
+	var errs []error
+	for _, j := range c.JobAgent.MatchingPostsubmits(pe.Repo.FullName, branch) {
+		c.Logger.Infof("Starting %s build.", j.Name)
+		br := line.BuildRequest{
+			Org:     pe.Repo.Owner.Login,
+			Repo:    pe.Repo.Name,
+			BaseRef: branch,
+			BaseSHA: pe.After,
+		}
+		if err := lineStartJob(c.KubeClient, j.Name, "", br); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", j.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors starting postsubmits: %v", len(errs), errs)
+	}
+	return nil
+}
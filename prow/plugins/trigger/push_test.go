@@ -0,0 +1,111 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/jobs"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/line"
+)
+
+func TestHandlePushEvent(t *testing.T) {
+	var testcases = []struct {
+		Ref          string
+		Deleted      bool
+		FailingJob   string
+		ExpectedJobs []string
+	}{
+		{
+			Ref:          "refs/heads/master",
+			ExpectedJobs: []string{"build", "master-only"},
+		},
+		{
+			Ref:          "refs/heads/master",
+			FailingJob:   "build",
+			ExpectedJobs: []string{"build", "master-only"},
+		},
+		{
+			Ref:          "refs/heads/release-1.5",
+			ExpectedJobs: []string{"build"},
+		},
+		{
+			Ref:     "refs/heads/master",
+			Deleted: true,
+		},
+		{
+			Ref: "refs/tags/v1.5.0",
+		},
+	}
+	for _, tc := range testcases {
+		c := client{
+			JobAgent: &jobs.JobAgent{},
+			Logger:   logrus.WithField("plugin", pluginName),
+		}
+		if err := c.JobAgent.SetPostsubmits(map[string][]jobs.Postsubmit{
+			"org/repo": {
+				{
+					Name: "build",
+				},
+				{
+					Name:     "master-only",
+					Branches: []string{"master"},
+				},
+			},
+		}); err != nil {
+			t.Fatalf("Could not set postsubmits: %v", err)
+		}
+		pe := github.PushEvent{
+			Ref:     tc.Ref,
+			After:   "abcdef",
+			Deleted: tc.Deleted,
+			Repo: github.Repo{
+				Owner:    github.User{Login: "org"},
+				Name:     "repo",
+				FullName: "org/repo",
+			},
+		}
+
+		oldLineStartJob := lineStartJob
+		defer func() { lineStartJob = oldLineStartJob }()
+		var startedJobs []string
+		lineStartJob = func(k *kube.Client, jobName, context string, br line.BuildRequest) error {
+			if br.BaseSHA != "abcdef" || len(br.Pulls) != 0 {
+				t.Errorf("Bad build request for %s: %+v", tc.Ref, br)
+			}
+			startedJobs = append(startedJobs, jobName)
+			if jobName == tc.FailingJob {
+				return errors.New("injected failure")
+			}
+			return nil
+		}
+		if err := handlePE(c, pe); err != nil && tc.FailingJob == "" {
+			t.Fatalf("Didn't expect error: %s", err)
+		} else if err == nil && tc.FailingJob != "" {
+			t.Errorf("For %s, expected an error when %s fails.", tc.Ref, tc.FailingJob)
+		}
+		if !reflect.DeepEqual(startedJobs, tc.ExpectedJobs) {
+			t.Errorf("For %s, started %v, expected %v.", tc.Ref, startedJobs, tc.ExpectedJobs)
+		}
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package trigger
 
 import (
+	"regexp"
+
 	"github.com/Sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
@@ -26,21 +28,47 @@ import (
 	"k8s.io/test-infra/prow/plugins"
 )
 
-const (
-	pluginName = "trigger"
-	lgtmLabel  = "lgtm"
-	trustedOrg = "kubernetes"
-)
+const pluginName = "trigger"
 
 func init() {
-	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
-	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest)
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment, helpProvider)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest, helpProvider)
+	plugins.RegisterPushEventHandler(pluginName, handlePush, helpProvider)
+}
+
+func helpProvider(c *plugins.Configuration, org, repo string) plugins.PluginHelp {
+	s := c.SettingsFor(org, repo)
+	prefix := s.CommandPrefix
+	if prefix == "" {
+		prefix = "<command_prefix>"
+	}
+	who := c.WhoCanUse("test", org, repo)
+	return plugins.PluginHelp{
+		Description: "Starts tests when PRs are opened or pushed, and when someone comments a job's trigger.",
+		Commands: []plugins.Command{
+			{
+				Usage:       prefix + " ok to test",
+				Description: "Marks a PR from someone outside the trusted orgs as safe to test.",
+				Regex:       okToTestRe(s).String(),
+				Example:     prefix + " ok to test",
+				WhoCanUse:   who,
+			},
+			{
+				Usage:       "/retest or " + prefix + " retest",
+				Description: "Reruns the jobs that failed on the PR's latest commit.",
+				Regex:       retestRe(s).String(),
+				Example:     "/retest",
+				WhoCanUse:   who,
+			},
+		},
+	}
 }
 
 type githubClient interface {
-	IsMember(org, user string) (bool, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetRef(org, repo, ref string) (string, error)
+	GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error)
 	CreateComment(owner, repo string, number int, comment string) error
 	ListIssueComments(owner, repo string, issue int) ([]github.IssueComment, error)
 }
@@ -50,24 +78,79 @@ type client struct {
 	JobAgent     *jobs.JobAgent
 	KubeClient   *kube.Client
 	Logger       *logrus.Entry
+	Settings     plugins.Settings
+	Auth         *plugins.Authorizer
+	// Untrusted PRs are tested once when they get this label.
+	LGTMLabel string
 }
 
 var lineStartPRJob = line.StartPRJob
+var lineStartJob = line.StartJob
 var lineDeletePRJob = line.DeletePRJob
 
-func getClient(pc plugins.PluginClient) client {
-	return client{
+func getClient(pc plugins.PluginClient, org, repo string) client {
+	c := client{
 		GitHubClient: pc.GitHubClient,
 		JobAgent:     pc.JobAgent,
 		KubeClient:   pc.KubeClient,
 		Logger:       pc.Logger,
+		Settings:     pc.PluginConfig.SettingsFor(org, repo),
+		Auth:         plugins.NewAuthorizer(pc.GitHubClient, pc.PluginConfig, org, repo),
+	}
+	if pc.PluginConfig != nil {
+		c.LGTMLabel = pc.PluginConfig.LGTM.Label
 	}
+	return c
 }
 
 func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) error {
-	return handlePR(getClient(pc), pr)
+	return handlePR(getClient(pc, pr.PullRequest.Base.Repo.Owner.Login, pr.PullRequest.Base.Repo.Name), pr)
 }
 
 func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
-	return handleIC(getClient(pc), ic)
+	return handleIC(getClient(pc, ic.Repo.Owner.Login, ic.Repo.Name), ic)
+}
+
+func handlePush(pc plugins.PluginClient, pe github.PushEvent) error {
+	return handlePE(getClient(pc, pe.Repo.Owner.Login, pe.Repo.Name), pe)
+}
+
+// okToTestRe matches "ok to test" on its own line, with or without the
+// command prefix.
+func okToTestRe(s plugins.Settings) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^(` + regexp.QuoteMeta(s.CommandPrefix) + ` )?ok to test\r?$`)
+}
+
+// trustedUser returns true if the policy for testing allows the user to test
+// the PR. By default, that means that they are a member of one of the trusted
+// orgs.
+func trustedUser(auth *plugins.Authorizer, user string, pr github.PullRequest) (bool, error) {
+	issue := github.Issue{
+		User:        pr.User,
+		Number:      pr.Number,
+		Assignees:   pr.Assignees,
+		PullRequest: &struct{}{},
+	}
+	return auth.Allowed("test", user, issue)
+}
+
+// changedFiles returns a jobs.ChangedFilesProvider that fetches the files
+// changed by the PR at most once.
+func changedFiles(ghc githubClient, pr github.PullRequest) jobs.ChangedFilesProvider {
+	var changes []string
+	fetched := false
+	return func() ([]string, error) {
+		if fetched {
+			return changes, nil
+		}
+		prcs, err := ghc.GetPullRequestChanges(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, prc := range prcs {
+			changes = append(changes, prc.Filename)
+		}
+		fetched = true
+		return changes, nil
+	}
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+var testSettings = plugins.Settings{
+	TrustedOrgs:   []string{"kubernetes"},
+	BotLogins:     []string{"k8s-bot", "k8s-ci-robot"},
+	CommandPrefix: "@k8s-bot",
+}
+
+// testAuth authorizes users in org/repo with the default policies and the
+// test settings.
+func testAuth(g plugins.AuthClient) *plugins.Authorizer {
+	c := &plugins.Configuration{Settings: map[string]plugins.Settings{"org": testSettings}}
+	return plugins.NewAuthorizer(g, c, "org", "repo")
+}
+
+func TestOkToTest(t *testing.T) {
+	var testcases = []struct {
+		prefix string
+		body   string
+		match  bool
+	}{
+		{"@k8s-bot", "ok to test", true},
+		{"@k8s-bot", "@k8s-bot ok to test", true},
+		{"@k8s-bot", "thanks!\r\n@k8s-bot ok to test\r\n", true},
+		{"@k8s-bot", "@other-bot ok to test", false},
+		{"@k8s-bot", "not ok to test", false},
+		{"/bot", "/bot ok to test", true},
+		{"/bot", "@k8s-bot ok to test", false},
+		// The prefix is not a regexp.
+		{"bot.", "botx ok to test", false},
+	}
+	for _, tc := range testcases {
+		re := okToTestRe(plugins.Settings{CommandPrefix: tc.prefix})
+		if match := re.MatchString(tc.body); match != tc.match {
+			t.Errorf("With prefix %q, expected match %t for %q, got %t.", tc.prefix, tc.match, tc.body, match)
+		}
+	}
+}
+
+func TestTrustedUser(t *testing.T) {
+	s := plugins.Settings{TrustedOrgs: []string{"org1", "org2"}}
+	g := &fakegithub.FakeClient{OrgMembers: []string{"t"}}
+	auth := plugins.NewAuthorizer(g, &plugins.Configuration{Settings: map[string]plugins.Settings{"org": s}}, "org", "repo")
+	pr := github.PullRequest{User: github.User{Login: "a"}}
+	if trusted, err := trustedUser(auth, "t", pr); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	} else if !trusted {
+		t.Error("Expected member to be trusted.")
+	}
+	if trusted, err := trustedUser(auth, "u", pr); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	} else if trusted {
+		t.Error("Expected non-member not to be trusted.")
+	}
+}
+
+func TestGetClientWithoutConfig(t *testing.T) {
+	c := getClient(plugins.PluginClient{}, "org", "repo")
+	if c.Settings.CommandPrefix != "" {
+		t.Errorf("Expected empty settings without a config, got %+v.", c.Settings)
+	}
+	if ok, err := trustedUser(c.Auth, "user", github.PullRequest{}); err != nil || ok {
+		t.Errorf("Expected untrusted user without error, got %t and %v.", ok, err)
+	}
+}